@@ -0,0 +1,56 @@
+package kway
+
+// WithAdaptiveLayout configures a Merger to monitor the number of comparisons
+// it performs per merged value, and to rebuild its loser tree when the inputs
+// have a shape that the current layout handles poorly.
+//
+// The Merger counts the values produced by each source over windows of merged
+// values. At the end of each window, if merging cost more than one comparison
+// per value (meaning that the sources did not produce long runs of values, for
+// which the tree needs fewer comparisons), and placing the most productive
+// sources on the shortest paths of the tree reduces the estimated number of
+// comparisons by more than 10%, the tree is rebuilt with this layout. This is
+// the dynamic equivalent of WithSizeHints, for applications which cannot know
+// the sizes of the sources in advance, or when they change during the merge.
+// Like with size hints, the layout only matters when the number of sources is
+// not a power of two.
+//
+// When combined with WithStats, the number of comparisons and of times the tree
+// was rebuilt are reported in the Comparisons and Rebalances fields of Stats.
+func WithAdaptiveLayout() Option {
+	return func(o *options) { o.adaptive = true }
+}
+
+// adaptiveWindow is the number of merged values after which a Merger configured
+// with WithAdaptiveLayout reconsiders the layout of its tree.
+const adaptiveWindow = 16 * bufferSize
+
+// adaptation is the state of the current window of a Merger configured with
+// WithAdaptiveLayout.
+type adaptation struct {
+	wins        []int64
+	values      int
+	comparisons int64
+}
+
+// adapt records that values were produced by the given sources, and rebuilds
+// the tree at the end of the window if its layout is inefficient.
+func (m *Merger[T]) adapt(sources []int) {
+	a := &m.adaptation
+	for _, source := range sources {
+		a.wins[source]++
+	}
+	if a.values += len(sources); a.values < adaptiveWindow {
+		return
+	}
+
+	if m.comparisons-a.comparisons > int64(a.values) && m.tree.rebalance(a.wins, m.treeCmp) {
+		if m.stats != nil {
+			m.stats.Rebalances++
+		}
+	}
+
+	clear(a.wins)
+	a.values = 0
+	a.comparisons = m.comparisons
+}
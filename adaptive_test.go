@@ -0,0 +1,68 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerAdaptiveLayout(t *testing.T) {
+	const n = 100_000
+
+	merge := func(opts ...Option) ([]int, Stats[int]) {
+		// Same shape as TestMergerSizeHints: the source at index 2 produces
+		// most of the values but starts on one of the deepest leaves.
+		seqs := []iter.Seq2[int, error]{
+			sequence(0, n, 12),
+			sequence(3, n, 12),
+			func(yield func(int, error) bool) {
+				for i := range n {
+					if i%3 != 0 && !yield(i, nil) {
+						return
+					}
+				}
+			},
+			sequence(6, n, 12),
+			sequence(9, n, 12),
+		}
+		m := newMerger(t, cmp.Compare[int], seqs, append(opts, WithStats())...)
+		values, err := values(m.All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return values, m.Stats()
+	}
+
+	want, static := merge()
+	got, adaptive := merge(WithAdaptiveLayout())
+
+	if !slices.Equal(got, want) {
+		t.Error("merged values differ with adaptive layout")
+	}
+	if static.Rebalances != 0 {
+		t.Errorf("expected no rebalances without adaptive layout, got %d", static.Rebalances)
+	}
+	if adaptive.Rebalances != 1 {
+		t.Errorf("expected the tree to be rebalanced once, got %d", adaptive.Rebalances)
+	}
+	if adaptive.Comparisons >= static.Comparisons {
+		t.Errorf("expected fewer comparisons with adaptive layout: %d >= %d", adaptive.Comparisons, static.Comparisons)
+	}
+	t.Logf("comparisons: %d static, %d adaptive", static.Comparisons, adaptive.Comparisons)
+}
+
+func TestMergerAdaptiveLayoutBalanced(t *testing.T) {
+	seqs := make([]iter.Seq2[int, error], 5)
+	for i := range seqs {
+		seqs[i] = sequence(i, 100_000, len(seqs))
+	}
+
+	m := newMerger(t, cmp.Compare[int], seqs, WithAdaptiveLayout(), WithStats())
+	if _, err := values(m.All()); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Stats(); s.Rebalances != 0 {
+		t.Errorf("expected no rebalances of balanced inputs, got %d", s.Rebalances)
+	}
+}
@@ -0,0 +1,71 @@
+package kway
+
+import "iter"
+
+// AlignBatches returns a sequence yielding the values of the ordered batches
+// produced by seq, where runs of equal values are never split across batches:
+// a run which reaches the end of a batch is carried over and yielded with the
+// values of the next batch, growing the batch as needed.
+//
+// This allows the consumers of merges grouping values by key to process each
+// batch independently, without stitching groups across batch boundaries:
+//
+//	for batch, err := range kway.AlignBatches(cmp, kway.MergeSliceFunc(cmp, seqs...)) {
+//		...
+//	}
+//
+// Runs are detected by comparing values with their predecessor, which is only
+// needed for the last run of each batch when no run was carried over. Values
+// are only copied when a run is carried over to the next batch, otherwise the
+// batches of seq are yielded as-is. The batches are reused across iterations,
+// the application must not retain them beyond the body of the loop ranging
+// over the sequence.
+//
+// Errors produced by seq are yielded with the batch of values that preceded
+// them, except for the run carried over to the next batch.
+func AlignBatches[T any](cmp func(T, T) int, seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		// Values carried over from the previous batches, the last run starts at
+		// index run.
+		var carry []T
+		var run int
+
+		for values, err := range seq {
+			var out []T
+
+			if len(carry) == 0 {
+				n := len(values)
+				if n > 0 {
+					for n--; n > 0 && cmp(values[n-1], values[len(values)-1]) == 0; n-- {
+					}
+				}
+				out = values[:n]
+				carry = append(carry, values[n:]...)
+			} else {
+				i := len(carry)
+				carry = append(carry, values...)
+				for ; i < len(carry); i++ {
+					if cmp(carry[i-1], carry[i]) != 0 {
+						run = i
+					}
+				}
+				out = carry[:run]
+			}
+
+			if len(out) > 0 || err != nil {
+				if !yield(out, err) {
+					return
+				}
+			}
+
+			if run > 0 {
+				carry = carry[:copy(carry, carry[run:])]
+				run = 0
+			}
+		}
+
+		if len(carry) > 0 {
+			yield(carry, nil)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func batchesOf[T any](batches ...[]T) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for _, batch := range batches {
+			if !yield(batch, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestAlignBatches(t *testing.T) {
+	seq := batchesOf(
+		[]int{0, 1, 1},
+		[]int{1, 1},
+		[]int{1, 2, 3},
+		[]int{},
+		[]int{3},
+		[]int{4, 5},
+		[]int{6, 6},
+	)
+
+	var got [][]int
+	for batch, err := range AlignBatches(cmp.Compare[int], seq) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, slices.Clone(batch))
+	}
+
+	want := [][]int{{0}, {1, 1, 1, 1, 1, 2}, {3, 3, 4}, {5}, {6, 6}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAlignBatchesMerge(t *testing.T) {
+	seqs := []iter.Seq2[[]int, error]{
+		countSlice(1000, 3),
+		countSlice(1000, 3),
+		countSlice(1000, 3),
+	}
+
+	var all []int
+	last, hasLast := 0, false
+	for batch, err := range AlignBatches(cmp.Compare[int], MergeSlice(seqs...)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch) == 0 {
+			t.Fatal("unexpected empty batch")
+		}
+		if hasLast && batch[0] == last {
+			t.Fatalf("run of %d split across batches", last)
+		}
+		last, hasLast = batch[len(batch)-1], true
+		all = append(all, batch...)
+	}
+	if len(all) != 9000 || !slices.IsSorted(all) {
+		t.Errorf("wrong merged values")
+	}
+}
+
+func TestAlignBatchesError(t *testing.T) {
+	errval := errors.New("oops")
+	seq := func(yield func([]int, error) bool) {
+		if yield([]int{1, 2, 2}, nil) && yield(nil, errval) {
+			yield([]int{2, 3}, nil)
+		}
+	}
+
+	var got [][]int
+	var errs []error
+	for batch, err := range AlignBatches(cmp.Compare[int], seq) {
+		got = append(got, slices.Clone(batch))
+		errs = append(errs, err)
+	}
+
+	want := [][]int{{1}, {}, {2, 2, 2}, {3}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !slices.Equal(errs, []error{nil, errval, nil, nil}) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
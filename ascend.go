@@ -0,0 +1,31 @@
+package kway
+
+import "iter"
+
+// Ascend adapts an Ascend-style callback iterator into a sequence that can be
+// passed to the merge functions.
+//
+// Ordered in-memory data structures such as github.com/google/btree expose
+// iteration through methods that invoke a callback for each item in order,
+// until the callback returns false. While the shape of those methods matches
+// range functions, their callback types are usually named types which are not
+// assignable to iter.Seq. Ascend bridges this gap without requiring goroutines
+// or iter.Pull, for example:
+//
+//	merged := kway.Merge(
+//		kway.Ascend(tree0.Ascend),
+//		kway.Ascend(tree1.Ascend),
+//	)
+//
+// Ranges of the trees can be merged by wrapping the method call in a closure:
+//
+//	kway.Ascend(func(f btree.ItemIteratorG[int]) {
+//		tree.AscendRange(10, 20, f)
+//	})
+func Ascend[T any, F ~func(T) bool](ascend func(F)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		ascend(func(item T) bool {
+			return yield(item, nil)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package kway
+
+import (
+	"slices"
+	"testing"
+)
+
+// itemIterator mimics the callback type used by github.com/google/btree.
+type itemIterator func(int) bool
+
+type orderedSet []int
+
+func (s orderedSet) Ascend(f itemIterator) {
+	for _, v := range s {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+func TestAscend(t *testing.T) {
+	s0 := orderedSet{1, 4, 7}
+	s1 := orderedSet{2, 5, 8}
+	s2 := orderedSet{3, 6, 9}
+
+	got, err := values(Merge(Ascend(s0.Ascend), Ascend(s1.Ascend), Ascend(s2.Ascend)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for v := range Ascend(s0.Ascend) {
+		if v != 1 {
+			t.Errorf("expected 1, got %d", v)
+		}
+		break
+	}
+}
@@ -0,0 +1,78 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// MergeBackfill merges a bounded sequence of historical values with an
+// unbounded sequence of live values, which is the common pattern of replaying
+// history before following a live feed (e.g. a database snapshot and its
+// change stream).
+//
+// Values of both sequences are interleaved until the historical sequence is
+// exhausted. From that point, live values are passed through directly, without
+// comparing them. When values compare equal, historical values are yielded
+// first.
+//
+// Since the merge needs the next live value to order historical values, it
+// waits for the live sequence to produce values while backfilling, unless the
+// live sequence ends.
+//
+// See MergeBackfillFunc for a version of this function that allows the caller
+// to pass a custom comparison function.
+func MergeBackfill[T cmp.Ordered](historical, live iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return MergeBackfillFunc(cmp.Compare[T], historical, live)
+}
+
+// MergeBackfillFunc is like MergeBackfill but uses the given comparison
+// function to determine the order of values.
+//
+// See MergeBackfill for more details.
+func MergeBackfillFunc[T any](cmp func(T, T) int, historical, live iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next, stop := iter.Pull2(live)
+		defer stop()
+
+		head, err, ok := next()
+		for ok && err != nil {
+			if !yield(head, err) {
+				return
+			}
+			head, err, ok = next()
+		}
+
+		for v, err := range historical {
+			if err != nil {
+				if !yield(v, err) {
+					return
+				}
+				continue
+			}
+			for ok && cmp(head, v) < 0 {
+				if !yield(head, nil) {
+					return
+				}
+				for head, err, ok = next(); ok && err != nil; head, err, ok = next() {
+					if !yield(head, err) {
+						return
+					}
+				}
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		// The historical sequence is exhausted, switch over to passing live
+		// values through.
+		if ok && !yield(head, nil) {
+			return
+		}
+		for ok {
+			if head, err, ok = next(); ok && !yield(head, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeBackfill(t *testing.T) {
+	comparisons := 0
+	compare := func(a, b int) int {
+		comparisons++
+		return a - b
+	}
+
+	got, err := values(MergeBackfillFunc(compare,
+		seqOf(1, 2, 4, 6),
+		seqOf(3, 5, 7, 8, 9, 10),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	// Values after the crossover are not compared.
+	if comparisons > 6 {
+		t.Errorf("too many comparisons after the historical sequence ended: %d", comparisons)
+	}
+}
+
+func TestMergeBackfillErrors(t *testing.T) {
+	errval := errors.New("")
+
+	live := func(yield func(int, error) bool) {
+		_ = yield(0, errval) && yield(2, nil) && yield(0, errval) && yield(4, nil)
+	}
+
+	var got []int
+	var errs int
+	for v, err := range MergeBackfill(seqOf(1, 3), live) {
+		if err != nil {
+			errs++
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if errs != 2 {
+		t.Errorf("expected 2 errors, got %d", errs)
+	}
+}
@@ -0,0 +1,81 @@
+package kway
+
+import (
+	"context"
+	"iter"
+)
+
+// Batch returns a sequence yielding the values of seq in batches of up to size
+// values, which is the conversion needed to merge sequences of values with
+// MergeSlice.
+//
+// By default, batches are only yielded when they are full or when seq ends, and
+// the same buffer is reused for all batches, so the application must not
+// retain them beyond the body of the loop ranging over the sequence. Batch
+// supports the following options to change this behavior:
+//
+//   - WithFlushInterval yields partial batches when seq did not produce values
+//     for the given duration, seq is then read from a separate goroutine.
+//   - WithContext stops reading from seq when the context is canceled, this
+//     only applies with WithFlushInterval.
+//   - WithBatchPool obtains each batch from a pool, so batches are never
+//     reused and applications may retain them.
+//
+// Other options are ignored.
+func Batch[T any](size int, seq iter.Seq2[T, error], opts ...Option) iter.Seq2[[]T, error] {
+	if size <= 0 {
+		panic("kway: batch size must be positive")
+	}
+	o := makeOptions(opts)
+
+	var batches iter.Seq2[[]T, error]
+	if o.flushInterval > 0 {
+		ctx := o.context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		batches = bufferInterval(ctx, size, o.flushInterval, nil, seq)
+	} else {
+		batches = bufferFunc(size, nil, seq)
+	}
+
+	get, err := typedOption[func(int) []T]("WithBatchPool", o.batchPool)
+	if err != nil {
+		return func(yield func([]T, error) bool) { yield(nil, err) }
+	}
+	if get != nil {
+		batches = pooled(get, batches)
+	}
+	return batches
+}
+
+// Unbatch returns a sequence yielding the values of the batches yielded by seq
+// one at a time. It is the reverse of Batch, and is typically used to consume
+// the result of MergeSlice.
+func Unbatch[T any](seq iter.Seq2[[]T, error]) iter.Seq2[T, error] {
+	return unbuffer(seq)
+}
+
+// WithBatchPool configures Batch to copy each batch into a slice obtained by
+// calling get with the size of the batch, instead of reusing its buffer. The
+// function typically retrieves slices from a pool which the application
+// returns batches to after processing them.
+//
+// If the type of slices returned by get differs from the type of values of the
+// sequence, Batch yields an error wrapping ErrOptionType.
+func WithBatchPool[T any](get func(size int) []T) Option {
+	return func(o *options) { o.batchPool = get }
+}
+
+func pooled[T any](get func(int) []T, seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for values, err := range seq {
+			if len(values) > 0 {
+				values = append(get(len(values))[:0], values...)
+			}
+			if !yield(values, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	var batches [][]int
+	for values, err := range Batch(4, count(10)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, slices.Clone(values))
+	}
+	want := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("expected %v, got %v", want, batches)
+	}
+
+	got, err := values(Unbatch(Batch(4, count(10))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(10)); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBatchPool(t *testing.T) {
+	allocs := 0
+	get := func(size int) []int {
+		allocs++
+		return make([]int, size)
+	}
+
+	var batches [][]int
+	for values, err := range Batch(4, count(10), WithBatchPool(get), WithFlushInterval(time.Hour)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, values)
+	}
+	want := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("expected %v, got %v", want, batches)
+	}
+	if allocs != 3 {
+		t.Errorf("expected 3 batches from the pool, got %d", allocs)
+	}
+}
+
+func TestBatchPoolTypeMismatch(t *testing.T) {
+	get := func(size int) []string { return make([]string, size) }
+	_, err := concatValues(Batch(4, count(10), WithBatchPool(get)))
+	if !errors.Is(err, ErrOptionType) {
+		t.Errorf("expected an option type error, got %v", err)
+	}
+}
+
+func TestUnbatchError(t *testing.T) {
+	errFailed := errors.New("failed")
+	seq := func(yield func([]int, error) bool) {
+		_ = yield([]int{1, 2}, nil) && yield([]int{3, 4}, errFailed) && yield([]int{5}, nil)
+	}
+
+	var got []int
+	for v, err := range Unbatch(iter.Seq2[[]int, error](seq)) {
+		if err != nil {
+			if !errors.Is(err, errFailed) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got = append(got, -1)
+			continue
+		}
+		got = append(got, v)
+	}
+	if want := []int{1, 2, 3, 4, -1, 5}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
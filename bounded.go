@@ -0,0 +1,182 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// ErrOutOfBounds is the error yielded by MergeBounded when a sequence produces
+// a value outside of its declared bounds.
+var ErrOutOfBounds = errors.New("value out of the bounds of its sequence")
+
+// Bounded is a sequence annotated with the range of values that it produces.
+//
+// Bounded sequences are constructed with WithBounds when the minimum and
+// maximum values of the sequence are known ahead of time (e.g. from file
+// metadata), or with Unbounded otherwise.
+type Bounded[T any] struct {
+	Seq iter.Seq2[T, error]
+	min T
+	max T
+	ok  bool
+}
+
+// WithBounds returns a Bounded sequence producing values between min and max,
+// both inclusive.
+func WithBounds[T any](seq iter.Seq2[T, error], min, max T) Bounded[T] {
+	return Bounded[T]{Seq: seq, min: min, max: max, ok: true}
+}
+
+// Unbounded returns a Bounded sequence with unknown bounds.
+func Unbounded[T any](seq iter.Seq2[T, error]) Bounded[T] {
+	return Bounded[T]{Seq: seq}
+}
+
+// Bounds returns the minimum and maximum values of the sequence, and a boolean
+// indicating whether the bounds are known.
+func (b Bounded[T]) Bounds() (min, max T, ok bool) {
+	return b.min, b.max, b.ok
+}
+
+// MergeBounded merges sequences with known bounds. Sequences with ranges that
+// do not overlap with any other are yielded back-to-back without merging their
+// values, and the merge algorithm only runs across groups of sequences with
+// overlapping ranges.
+//
+// Inputs that are partitioned by time or by key ranges are often disjoint or
+// nearly disjoint, in which case MergeBounded is significantly faster than
+// Merge. Groups are made of whole sequences: a sequence with a wide range is
+// merged in full with all the sequences that it overlaps, and sequences with
+// unknown bounds are assumed to overlap with all others, so a single unbounded
+// sequence makes the whole input one merge.
+//
+// The bounds act as a manifest of the sequences, which MergeBounded verifies
+// as the sequences are consumed: the first and last values of each sequence
+// are compared to its bounds, costing two comparisons per sequence since the
+// values in between are ordered. A value found outside of the bounds is not
+// yielded, an error wrapping ErrOutOfBounds is yielded instead.
+//
+// See MergeBoundedFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func MergeBounded[T cmp.Ordered](seqs ...Bounded[T]) iter.Seq2[T, error] {
+	return MergeBoundedFunc(cmp.Compare[T], seqs...)
+}
+
+// MergeBoundedFunc is like MergeBounded but uses the given comparison function
+// to determine the order of values.
+//
+// See MergeBounded for more details.
+func MergeBoundedFunc[T any](cmp func(T, T) int, seqs ...Bounded[T]) iter.Seq2[T, error] {
+	checked := make([]Bounded[T], len(seqs))
+	for i, seq := range seqs {
+		checked[i] = seq
+		if seq.ok {
+			checked[i].Seq = checkBounds(cmp, seq)
+		}
+	}
+	groups := overlappingGroups(cmp, checked)
+	return func(yield func(T, error) bool) {
+		for _, group := range groups {
+			for v, err := range MergeFunc(cmp, group...) {
+				if !yield(v, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// overlappingGroups partitions the sequences into ordered groups of sequences
+// with overlapping ranges.
+func overlappingGroups[T any](cmp func(T, T) int, seqs []Bounded[T]) [][]iter.Seq2[T, error] {
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	for _, seq := range seqs {
+		if !seq.ok {
+			group := make([]iter.Seq2[T, error], len(seqs))
+			for i, seq := range seqs {
+				group[i] = seq.Seq
+			}
+			return [][]iter.Seq2[T, error]{group}
+		}
+	}
+
+	sorted := slices.Clone(seqs)
+	slices.SortStableFunc(sorted, func(a, b Bounded[T]) int {
+		return cmp(a.min, b.min)
+	})
+
+	groups := [][]iter.Seq2[T, error]{{sorted[0].Seq}}
+	groupMax := sorted[0].max
+
+	for _, seq := range sorted[1:] {
+		if cmp(seq.min, groupMax) <= 0 {
+			last := len(groups) - 1
+			groups[last] = append(groups[last], seq.Seq)
+		} else {
+			groups = append(groups, []iter.Seq2[T, error]{seq.Seq})
+		}
+		if cmp(seq.max, groupMax) > 0 {
+			groupMax = seq.max
+		}
+	}
+	return groups
+}
+
+// checkBounds returns a sequence yielding the values of b, verifying that the
+// first and last values are within the bounds.
+//
+// The sequence holds on to the most recent value until it knows whether it is
+// the last one, so out of bounds values are detected before they are yielded.
+func checkBounds[T any](cmp func(T, T) int, b Bounded[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero, last T
+		var hasLast, first = false, true
+
+		outOfBounds := func(v T) bool {
+			return yield(zero, fmt.Errorf("%w: %v not in [%v, %v]", ErrOutOfBounds, v, b.min, b.max))
+		}
+
+		// flush yields the value held by the sequence, it is called when the
+		// value may be the last one, so it is checked against the upper bound.
+		flush := func() bool {
+			if !hasLast {
+				return true
+			}
+			hasLast = false
+			if cmp(last, b.max) > 0 {
+				return outOfBounds(last)
+			}
+			return yield(last, nil)
+		}
+
+		for v, err := range b.Seq {
+			if err != nil {
+				if !flush() || !yield(v, err) {
+					return
+				}
+				continue
+			}
+			if first {
+				if cmp(v, b.min) < 0 {
+					if !outOfBounds(v) {
+						return
+					}
+					continue
+				}
+				first = false
+			}
+			if hasLast && !yield(last, nil) {
+				return
+			}
+			last, hasLast = v, true
+		}
+
+		flush()
+	}
+}
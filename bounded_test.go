@@ -0,0 +1,124 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeBounded(t *testing.T) {
+	comparisons := 0
+	compare := func(a, b int) int {
+		comparisons++
+		return cmp.Compare(a, b)
+	}
+
+	merged := MergeBoundedFunc(compare,
+		WithBounds(seqOf(20, 25, 30), 20, 30),
+		WithBounds(seqOf(0, 5, 10), 0, 10),
+		WithBounds(seqOf(11, 19), 11, 19),
+	)
+	comparisons = 0
+
+	got, err := values(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 5, 10, 11, 19, 20, 25, 30}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	// Each sequence is only verified against its bounds, disjoint sequences
+	// are concatenated without comparing their values.
+	if comparisons != 6 {
+		t.Errorf("expected disjoint sequences to be concatenated, got %d comparisons", comparisons)
+	}
+}
+
+func TestMergeBoundedOutOfBounds(t *testing.T) {
+	var got []int
+	var errs int
+	for v, err := range MergeBounded(
+		WithBounds(seqOf(1, 2, 3), 2, 10),
+		WithBounds(seqOf(11, 12, 21), 11, 20),
+	) {
+		if err != nil {
+			if !errors.Is(err, ErrOutOfBounds) {
+				t.Fatal(err)
+			}
+			errs++
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{2, 3, 11, 12}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if errs != 2 {
+		t.Errorf("expected 2 errors, got %d", errs)
+	}
+}
+
+func TestOverlappingGroups(t *testing.T) {
+	tests := []struct {
+		scenario string
+		seqs     []Bounded[int]
+		sizes    []int
+	}{
+		{
+			scenario: "no sequences",
+		},
+
+		{
+			scenario: "disjoint sequences",
+			seqs: []Bounded[int]{
+				WithBounds(seqOf[int](), 0, 9),
+				WithBounds(seqOf[int](), 10, 19),
+			},
+			sizes: []int{1, 1},
+		},
+
+		{
+			scenario: "sequences sharing a bound",
+			seqs: []Bounded[int]{
+				WithBounds(seqOf[int](), 0, 10),
+				WithBounds(seqOf[int](), 10, 19),
+				WithBounds(seqOf[int](), 20, 29),
+			},
+			sizes: []int{2, 1},
+		},
+
+		{
+			scenario: "sequence overlapping with multiple others",
+			seqs: []Bounded[int]{
+				WithBounds(seqOf[int](), 0, 100),
+				WithBounds(seqOf[int](), 10, 19),
+				WithBounds(seqOf[int](), 50, 59),
+				WithBounds(seqOf[int](), 101, 200),
+			},
+			sizes: []int{3, 1},
+		},
+
+		{
+			scenario: "unbounded sequence",
+			seqs: []Bounded[int]{
+				WithBounds(seqOf[int](), 0, 9),
+				WithBounds(seqOf[int](), 10, 19),
+				Unbounded(seqOf[int]()),
+			},
+			sizes: []int{3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			var sizes []int
+			for _, group := range overlappingGroups(cmp.Compare[int], test.seqs) {
+				sizes = append(sizes, len(group))
+			}
+			if !slices.Equal(sizes, test.sizes) {
+				t.Errorf("expected groups of sizes %v, got %v", test.sizes, sizes)
+			}
+		})
+	}
+}
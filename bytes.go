@@ -0,0 +1,21 @@
+package kway
+
+import (
+	"bytes"
+	"iter"
+)
+
+// MergeBytes merges sequences of byte slices ordered by bytes.Compare.
+//
+// See Merge for more details.
+func MergeBytes(seqs ...iter.Seq2[[]byte, error]) iter.Seq2[[]byte, error] {
+	return MergeFunc(bytes.Compare, seqs...)
+}
+
+// MergeBytesBy is like MergeBytes but orders values of any type by the byte
+// slice keys returned by the key function.
+//
+// See MergeBy for more details.
+func MergeBytesBy[T any](key func(T) []byte, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return MergeByFunc(key, bytes.Compare, seqs...)
+}
@@ -0,0 +1,35 @@
+package kway
+
+import (
+	"bytes"
+	"iter"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMergeBytes(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+
+	var want [][]byte
+	seqs := make([]iter.Seq2[[]byte, error], 4)
+	for i := range seqs {
+		keys := make([][]byte, 100)
+		for j := range keys {
+			keys[j] = make([]byte, prng.Intn(12))
+			prng.Read(keys[j])
+		}
+		slices.SortFunc(keys, bytes.Compare)
+		want = append(want, keys...)
+		seqs[i] = seqOf(keys...)
+	}
+	slices.SortFunc(want, bytes.Compare)
+
+	got, err := values(MergeBytes(seqs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.EqualFunc(got, want, bytes.Equal) {
+		t.Error("merged byte slices are not in order")
+	}
+}
@@ -0,0 +1,194 @@
+package kway
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+)
+
+// ErrCertificateMismatch is the error returned when a file or a sequence of
+// values does not match its certificate.
+var ErrCertificateMismatch = errors.New("values do not match their certificate")
+
+// Certificate is a compact record attesting that a file holds a complete and
+// ordered sequence of values, produced by WriteFileCertified,
+// WriteFilesCertified, or WriteRecordFileCertified while writing the file.
+//
+// A certificate is the manifest entry of the file, extended with a hash of the
+// keys of its values. Certificates are designed to be stored alongside the
+// files (they can be serialized with encoding/json, with the fields of the
+// manifest entry inlined), so downstream systems can trust that a file is
+// ordered and complete without decoding it.
+type Certificate struct {
+	ManifestFile
+	// FNV-1a hash of the keys of all the values in order, each prefixed with
+	// its length encoded as an unsigned varint.
+	KeyHash uint64 `json:"keyHash"`
+}
+
+// Verify checks that the named file matches the size and checksum recorded in
+// the certificate. This only reads the bytes of the file, without decoding its
+// values, and establishes that the file is the one which was certified. The
+// error wraps ErrCertificateMismatch if the file differs.
+func (c Certificate) Verify(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	checksum := crc32.New(castagnoli)
+	size, err := io.Copy(checksum, f)
+	switch {
+	case err != nil:
+		return err
+	case size != c.Size:
+		return fmt.Errorf("%w: %s: size %d, expected %d", ErrCertificateMismatch, name, size, c.Size)
+	case checksum.Sum32() != c.Checksum:
+		return fmt.Errorf("%w: %s: checksum %08x, expected %08x", ErrCertificateMismatch, name, checksum.Sum32(), c.Checksum)
+	}
+	return nil
+}
+
+// WriteFileCertified is like WriteFile, but encodes the values with the codec,
+// validates that the values of seq are ordered according to cmp, and returns a
+// certificate of the file. The name of the certificate is the base name of the
+// file, and its bounds are encoded with the codec, like in a Manifest.
+//
+// The key function appends the key of a value to a buffer and returns it, the
+// keys are used to compute the hash of the certificate. Keys must be
+// deterministic encodings of the values (or of the fields determining their
+// order), so VerifyKeys can recompute the certificate from the values decoded
+// from the file.
+//
+// If a value is ordered before the previous value, the file is not written and
+// the function returns an *OrderError.
+func WriteFileCertified[T any](name string, seq iter.Seq2[T, error], codec Codec[T], cmp func(T, T) int, key func([]byte, T) []byte, perm fs.FileMode) (Certificate, error) {
+	c := newCertifier(cmp, key)
+
+	certified := func(yield func(T, error) bool) {
+		var zero T
+		for v, err := range seq {
+			if err == nil {
+				if err := c.add(v); err != nil {
+					yield(zero, err)
+					return
+				}
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+
+	r := NewReader(certified, codec.Encoder())
+	defer r.Close()
+
+	checksum := crc32.New(castagnoli)
+	w := &countWriter{w: checksum}
+	if err := writeFileAtomic(name, io.TeeReader(r, w), perm); err != nil {
+		return Certificate{}, err
+	}
+
+	cert := Certificate{
+		ManifestFile: ManifestFile{
+			Name:     filepath.Base(name),
+			Values:   c.count,
+			Size:     w.n,
+			Checksum: checksum.Sum32(),
+		},
+		KeyHash: c.sum(),
+	}
+	if c.count > 0 {
+		cert.Min, cert.Max = encodeBound(codec, c.first), encodeBound(codec, c.last)
+	}
+	return cert, nil
+}
+
+// VerifyKeys checks that the values of seq are ordered according to cmp, and
+// that their number, bounds, and key hash match the certificate. This is the
+// thorough verification of a certified file, which decodes all of its values,
+// for example to audit files certified by other systems:
+//
+//	err := kway.VerifyKeys(cert, codec.Decode(f), codec, cmp, key)
+//
+// The bounds of the certificate are decoded with the codec, and compared to the
+// first and last values with cmp.
+//
+// The first error produced by seq is returned. Otherwise, an *OrderError is
+// returned if the values are not ordered, or an error wrapping
+// ErrCertificateMismatch if they differ from the certificate.
+func VerifyKeys[T any](cert Certificate, seq iter.Seq2[T, error], codec Codec[T], cmp func(T, T) int, key func([]byte, T) []byte) error {
+	c := newCertifier(cmp, key)
+	for v, err := range seq {
+		if err != nil {
+			return err
+		}
+		if err := c.add(v); err != nil {
+			return err
+		}
+	}
+
+	if c.count != cert.Values {
+		return fmt.Errorf("%w: %d values, expected %d", ErrCertificateMismatch, c.count, cert.Values)
+	}
+	if c.count > 0 {
+		min, minOK := decodeBound(codec, cert.Min)
+		max, maxOK := decodeBound(codec, cert.Max)
+		if !minOK || !maxOK || cmp(c.first, min) != 0 || cmp(c.last, max) != 0 {
+			return fmt.Errorf("%w: bounds [%q, %q] do not match the values", ErrCertificateMismatch, cert.Min, cert.Max)
+		}
+	}
+	if sum := c.sum(); sum != cert.KeyHash {
+		return fmt.Errorf("%w: key hash %016x, expected %016x", ErrCertificateMismatch, sum, cert.KeyHash)
+	}
+	return nil
+}
+
+// certifier validates the order of a sequence of values and hashes their keys.
+// The order is validated across calls to sum, which only resets the hash, so a
+// certifier can certify the series of files partitioning a sequence.
+type certifier[T any] struct {
+	cmp   func(T, T) int
+	key   func([]byte, T) []byte
+	hash  hash.Hash64
+	count int64
+	first T
+	last  T
+	buf   []byte
+}
+
+func newCertifier[T any](cmp func(T, T) int, key func([]byte, T) []byte) *certifier[T] {
+	return &certifier[T]{cmp: cmp, key: key, hash: fnv.New64a()}
+}
+
+func (c *certifier[T]) add(v T) error {
+	if c.count > 0 && c.cmp(v, c.last) < 0 {
+		return &OrderError{Offset: c.count}
+	}
+	var size [binary.MaxVarintLen64]byte
+	c.buf = c.key(c.buf[:0], v)
+	c.hash.Write(binary.AppendUvarint(size[:0], uint64(len(c.buf))))
+	c.hash.Write(c.buf)
+	if c.count == 0 {
+		c.first = v
+	}
+	c.last = v
+	c.count++
+	return nil
+}
+
+// sum returns the hash of the keys added since the last call, and resets it.
+func (c *certifier[T]) sum() uint64 {
+	sum := c.hash.Sum64()
+	c.hash.Reset()
+	return sum
+}
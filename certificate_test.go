@@ -0,0 +1,233 @@
+package kway
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func appendIntKey(b []byte, v int) []byte { return strconv.AppendInt(b, int64(v), 10) }
+
+func TestWriteFileCertified(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "merged")
+	codec := varintCodec()
+
+	cert, err := WriteFileCertified(name, Merge(seqOf(1, 3, 5), seqOf(2, 4)), codec, cmp.Compare[int], appendIntKey, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Name != "merged" || cert.Values != 5 || cert.Size != 10 {
+		t.Errorf("wrong certificate: %+v", cert)
+	}
+	if min, _ := decodeBound(codec, cert.Min); min != 1 {
+		t.Errorf("wrong lower bound: %d", min)
+	}
+	if max, _ := decodeBound(codec, cert.Max); max != 5 {
+		t.Errorf("wrong upper bound: %d", max)
+	}
+	if err := cert.Verify(name); err != nil {
+		t.Error(err)
+	}
+
+	// Certificates are serializable, and inline the fields of the manifest
+	// entry.
+	b, err := json.Marshal(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Certificate
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, cert) {
+		t.Errorf("certificate mismatch:\nwant: %+v\ngot:  %+v", cert, decoded)
+	}
+	var file ManifestFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(file, cert.ManifestFile) {
+		t.Errorf("manifest entry mismatch:\nwant: %+v\ngot:  %+v", cert.ManifestFile, file)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := VerifyKeys(cert, codec.Decode(f), codec, cmp.Compare[int], appendIntKey); err != nil {
+		t.Error(err)
+	}
+	for _, seq := range [][]int{{1, 2, 3, 4}, {1, 2, 3, 4, 6}, {0, 2, 3, 4, 5}, {1, 2, 2, 4, 5}} {
+		if err := VerifyKeys(cert, seqOf(seq...), codec, cmp.Compare[int], appendIntKey); !errors.Is(err, ErrCertificateMismatch) {
+			t.Errorf("%v: expected a certificate mismatch, got %v", seq, err)
+		}
+	}
+
+	if err := os.WriteFile(name, []byte("1\n2\n3\n4\n6\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Verify(name); !errors.Is(err, ErrCertificateMismatch) {
+		t.Errorf("expected a certificate mismatch, got %v", err)
+	}
+}
+
+func TestWriteFileCertifiedUnordered(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "merged")
+
+	_, err := WriteFileCertified(name, seqOf(1, 3, 2), varintCodec(), cmp.Compare[int], appendIntKey, 0644)
+	var orderErr *OrderError
+	if !errors.As(err, &orderErr) || orderErr.Offset != 2 {
+		t.Fatalf("expected an order error at offset 2, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files were left in the directory: %v", entries)
+	}
+
+	if err := VerifyKeys(Certificate{}, seqOf(2, 1), varintCodec(), cmp.Compare[int], appendIntKey); !errors.As(err, &orderErr) {
+		t.Errorf("expected an order error, got %v", err)
+	}
+}
+
+func TestWriteFileCertifiedEmpty(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "merged")
+
+	cert, err := WriteFileCertified(name, seqOf[int](), varintCodec(), cmp.Compare[int], appendIntKey, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Values != 0 || cert.Min != nil || cert.Max != nil || cert.Size != 0 {
+		t.Errorf("wrong certificate: %+v", cert)
+	}
+	if err := cert.Verify(name); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteFilesCertified(t *testing.T) {
+	dir := t.TempDir()
+	codec := varintCodec()
+	rotation := Rotation{MaxValues: 30, Manifest: "manifest.json"}
+
+	manifest, certs, err := WriteFilesCertified(dir, count(100), codec, rotation, cmp.Compare[int], appendIntKey, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 4 || len(manifest.Files) != 4 {
+		t.Fatalf("expected 4 files, got %d certificates and %d manifest entries", len(certs), len(manifest.Files))
+	}
+	for i, cert := range certs {
+		if !reflect.DeepEqual(cert.ManifestFile, manifest.Files[i]) {
+			t.Errorf("certificate %d does not match its manifest entry:\nwant: %+v\ngot:  %+v", i, manifest.Files[i], cert.ManifestFile)
+		}
+		path := filepath.Join(dir, cert.Name)
+		if err := cert.Verify(path); err != nil {
+			t.Error(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyKeys(cert, codec.Decode(f), codec, cmp.Compare[int], appendIntKey); err != nil {
+			t.Errorf("%s: %v", cert.Name, err)
+		}
+		f.Close()
+	}
+}
+
+func TestWriteFilesCertifiedUnordered(t *testing.T) {
+	dir := t.TempDir()
+
+	// The values are ordered within each file, but not across files.
+	seq := seqOf(0, 1, 2, 3, 4, 3, 4)
+	_, certs, err := WriteFilesCertified(dir, seq, varintCodec(), Rotation{MaxValues: 5, Manifest: "manifest"}, cmp.Compare[int], appendIntKey, 0644)
+	var orderErr *OrderError
+	if !errors.As(err, &orderErr) || orderErr.Offset != 5 {
+		t.Fatalf("expected an order error at offset 5, got %v", err)
+	}
+	if len(certs) != 0 {
+		t.Errorf("unexpected certificates: %+v", certs)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files were left in the directory: %v", entries)
+	}
+}
+
+// reusedRecords returns a sequence of batches of records, each batch copied to
+// the same buffer like the views yielded by MergeRecordFiles.
+func reusedRecords(batches ...[]string) func(func([][]byte, error) bool) {
+	return func(yield func([][]byte, error) bool) {
+		var buf []byte
+		for _, batch := range batches {
+			buf = buf[:0]
+			for _, record := range batch {
+				buf = append(buf, record...)
+			}
+			records := make([][]byte, 0, len(batch))
+			for i, offset := 0, 0; i < len(batch); i++ {
+				records = append(records, buf[offset:offset+len(batch[i])])
+				offset += len(batch[i])
+			}
+			if !yield(records, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestWriteRecordFileCertified(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "merged")
+	format := FixedSizeRecords(2).Key(0, 1)
+
+	cert, err := WriteRecordFileCertified(name, reusedRecords([]string{"a3", "b2"}, []string{"b1", "c0"}), format, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Name != "merged" || cert.Values != 4 || cert.Size != 8 || string(cert.Min) != "a3" || string(cert.Max) != "c0" {
+		t.Errorf("wrong certificate: %+v", cert)
+	}
+	if err := cert.Verify(name); err != nil {
+		t.Error(err)
+	}
+
+	// The keys of the records are hashed, not their payloads.
+	other, err := WriteRecordFileCertified(name, reusedRecords([]string{"a0", "b0", "b0", "c0"}), format, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.KeyHash != cert.KeyHash || other.Checksum == cert.Checksum {
+		t.Errorf("wrong certificate: %+v", other)
+	}
+
+	prefixed, err := WriteRecordFileCertified(name, seqOf([][]byte{[]byte("a"), []byte("bb")}), LengthPrefixedRecords(), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(prefixed.Min, []byte("\x01a")) || !bytes.Equal(prefixed.Max, []byte("\x02bb")) {
+		t.Errorf("wrong bounds: %q, %q", prefixed.Min, prefixed.Max)
+	}
+}
+
+func TestWriteRecordFileCertifiedUnordered(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "merged")
+	format := FixedSizeRecords(2).Key(0, 1)
+
+	// The last record of the first batch is overwritten by the second batch,
+	// it must be retained to detect that the records are not ordered.
+	_, err := WriteRecordFileCertified(name, reusedRecords([]string{"a1", "c2"}, []string{"b3"}), format, 0o644)
+	var orderErr *OrderError
+	if !errors.As(err, &orderErr) || orderErr.Offset != 2 {
+		t.Fatalf("expected an order error at offset 2, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files were left in the directory: %v", entries)
+	}
+}
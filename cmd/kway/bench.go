@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/achille-roussel/kway-go"
+	"github.com/achille-roussel/kway-go/kwaybench"
+)
+
+// strategies are the ways to merge the lines of the files that bench compares,
+// each strategy receives the comparison function counting comparisons, the
+// size of batches, and the lines of each file.
+var strategies = map[string]func(cmp func(string, string) int, batch int, files [][]string) iter.Seq2[string, error]{
+	// Merge values one at a time.
+	"merge": func(cmp func(string, string) int, _ int, files [][]string) iter.Seq2[string, error] {
+		return kway.MergeFunc(cmp, lineSeqs(files)...)
+	},
+	// Merge batches of values, then unbatch the output.
+	"slice": func(cmp func(string, string) int, batch int, files [][]string) iter.Seq2[string, error] {
+		seqs := make([]iter.Seq2[[]string, error], len(files))
+		for i, seq := range lineSeqs(files) {
+			seqs[i] = kway.Batch(batch, seq)
+		}
+		return kway.Unbatch(kway.MergeSliceFunc(cmp, seqs...))
+	},
+	// Merge values with a Merger, which is instrumented.
+	"merger": func(cmp func(string, string) int, _ int, files [][]string) iter.Seq2[string, error] {
+		m, _ := kway.NewMerger(cmp, lineSeqs(files)) // cannot fail without options
+		return m.All()
+	},
+	// Merge values with a Merger adapting the layout of its tree.
+	"adaptive": func(cmp func(string, string) int, _ int, files [][]string) iter.Seq2[string, error] {
+		m, err := kway.NewMerger(cmp, lineSeqs(files), kway.WithAdaptiveLayout())
+		if err != nil {
+			return func(yield func(string, error) bool) { yield("", err) }
+		}
+		return m.All()
+	},
+}
+
+func bench(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	strategyList := flags.String("strategies", "merge,slice,merger,adaptive", "comma-separated list of merge strategies")
+	batchList := flags.String("batch", "16,128,1024", "comma-separated list of batch sizes, for strategies merging batches")
+	count := flags.Int("count", 3, "number of runs of each benchmark, the fastest run is reported")
+	sourceList := flags.String("k", "", "comma-separated list of numbers of files to merge, the first k files are merged (default all)")
+	jsonOutput := flags.Bool("json", false, "report the results as a JSON document")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: kway bench [flags] files...\n\n")
+		fmt.Fprintf(stderr, "Merges the lines of sorted files with each strategy and reports\n")
+		fmt.Fprintf(stderr, "the merge rate, comparisons and allocations per value.\n\n")
+		fmt.Fprintf(stderr, "With -json, the results are written as a JSON document which can be\n")
+		fmt.Fprintf(stderr, "archived to track the performance of merges across runs.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no files to merge")
+	}
+	if *count <= 0 {
+		return errors.New("count must be positive")
+	}
+
+	batches, err := parseInts(*batchList)
+	if err != nil {
+		return fmt.Errorf("invalid batch sizes: %w", err)
+	}
+	sources := []int{flags.NArg()}
+	if *sourceList != "" {
+		if sources, err = parseInts(*sourceList); err != nil {
+			return fmt.Errorf("invalid numbers of files: %w", err)
+		}
+		for _, k := range sources {
+			if k > flags.NArg() {
+				return fmt.Errorf("cannot merge %d of %d files", k, flags.NArg())
+			}
+		}
+	}
+	files := make([][]string, flags.NArg())
+	for i, path := range flags.Args() {
+		if files[i], err = readLines(path); err != nil {
+			return err
+		}
+	}
+
+	var results []kwaybench.Result
+	for _, name := range strings.Split(*strategyList, ",") {
+		strategy, ok := strategies[name]
+		if !ok {
+			return fmt.Errorf("unknown strategy %q", name)
+		}
+		sizes := []int{0}
+		if name == "slice" {
+			sizes = batches
+		}
+		for _, k := range sources {
+			for _, batch := range sizes {
+				var best kwaybench.Result
+				for i := range *count {
+					r, err := kwaybench.Measure(strings.Compare, func(cmp func(string, string) int) iter.Seq2[string, error] {
+						return strategy(cmp, batch, files[:k])
+					})
+					if err != nil {
+						return err
+					}
+					if i == 0 || r.Elapsed < best.Elapsed {
+						best = r
+					}
+				}
+				best.Strategy, best.Batch, best.Sources = name, batch, k
+				results = append(results, best)
+			}
+		}
+	}
+
+	if *jsonOutput {
+		return kwaybench.NewReport(results...).WriteJSON(stdout)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "strategy\tk\tbatch\tvalues\ttime\tmerge/s\tcomp/op\tallocs/op\t")
+	for _, r := range results {
+		batch := "-"
+		if r.Batch > 0 {
+			batch = strconv.Itoa(r.Batch)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%.0f\t%.2f\t%.3f\t\n",
+			r.Strategy,
+			r.Sources,
+			batch,
+			r.Values,
+			r.Elapsed.Round(time.Microsecond),
+			r.MergeRate(),
+			r.ComparisonsPerValue(),
+			r.AllocsPerValue(),
+		)
+	}
+	return w.Flush()
+}
+
+func lineSeqs(files [][]string) []iter.Seq2[string, error] {
+	seqs := make([]iter.Seq2[string, error], len(files))
+	for i, lines := range files {
+		seqs[i] = func(yield func(string, error) bool) {
+			for _, line := range lines {
+				if !yield(line, nil) {
+					return
+				}
+			}
+		}
+	}
+	return seqs
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	for line, err := range (linesCodec{}).Decode(f) {
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func parseInts(list string) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(list, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("%d is not positive", v)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
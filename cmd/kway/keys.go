@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// keyFlags are the flags of sort(1) configuring the order of lines, shared by
+// the sort and merge commands:
+//
+//	-t sep      use sep as field separator instead of transitions to blanks
+//	-k pos1[,pos2][opts]
+//	            compare the fields pos1 through pos2 (the end of the line by
+//	            default), where opts are one or more of n and r
+//	-n          compare according to the numeric value of the key
+//	-r          reverse the result of comparisons
+//	-u          output only the first of lines comparing equal
+//	-s          disable the last-resort comparison of whole lines
+type keyFlags struct {
+	separator string
+	keys      keyList
+	numeric   bool
+	reverse   bool
+	unique    bool
+	stable    bool
+}
+
+func (f *keyFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.separator, "t", "", "use `sep` as field separator")
+	flags.Var(&f.keys, "k", "compare lines by the key at `pos1[,pos2][nr]`, may be repeated")
+	flags.BoolVar(&f.numeric, "n", false, "compare according to the numeric value of keys")
+	flags.BoolVar(&f.reverse, "r", false, "reverse the result of comparisons")
+	flags.BoolVar(&f.unique, "u", false, "output only the first of lines comparing equal")
+	flags.BoolVar(&f.stable, "s", false, "disable the last-resort comparison of whole lines")
+}
+
+// key is a range of fields compared by the -k flag.
+type key struct {
+	start, end int // 1-based, end is zero to compare until the end of lines
+	numeric    bool
+	reverse    bool
+}
+
+type keyList []key
+
+func (l *keyList) String() string { return fmt.Sprint(*l) }
+
+func (l *keyList) Set(s string) error {
+	k, err := parseKey(s)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, k)
+	return nil
+}
+
+func parseKey(s string) (k key, err error) {
+	opts := strings.TrimLeftFunc(s, func(r rune) bool { return r == ',' || unicode.IsDigit(r) })
+	fields := strings.TrimSuffix(s, opts)
+	for _, opt := range opts {
+		switch opt {
+		case 'n':
+			k.numeric = true
+		case 'r':
+			k.reverse = true
+		default:
+			return k, fmt.Errorf("invalid key %q: unsupported option %q", s, opt)
+		}
+	}
+	start, end, hasEnd := strings.Cut(fields, ",")
+	if k.start, err = strconv.Atoi(start); err != nil || k.start <= 0 {
+		return k, fmt.Errorf("invalid key %q: field numbers must be positive integers", s)
+	}
+	if hasEnd {
+		if k.end, err = strconv.Atoi(end); err != nil || k.end < k.start {
+			return k, fmt.Errorf("invalid key %q: end field must be a number after the start field", s)
+		}
+	}
+	return k, nil
+}
+
+// compare returns the comparison function of lines configured by the flags.
+func (f *keyFlags) compare() (func(a, b string) int, error) {
+	if len(f.separator) > 1 {
+		return nil, errors.New("field separator must be a single character")
+	}
+	keys := f.keys
+	if len(keys) == 0 {
+		keys = keyList{{start: 1}}
+	}
+	for i := range keys {
+		// Like sort(1), global options apply to keys without options.
+		if !keys[i].numeric && !keys[i].reverse {
+			keys[i].numeric, keys[i].reverse = f.numeric, f.reverse
+		}
+	}
+	split := f.split
+	wholeLine := len(f.keys) == 0 && !f.numeric
+	lastResort := !f.stable && !f.unique
+
+	return func(a, b string) int {
+		if !wholeLine {
+			fa, fb := split(a), split(b)
+			for _, k := range keys {
+				c := k.compare(fa, fb, f.separator)
+				if c != 0 {
+					return c
+				}
+			}
+			if !lastResort {
+				return 0
+			}
+		}
+		c := strings.Compare(a, b)
+		if f.reverse {
+			c = -c
+		}
+		return c
+	}, nil
+}
+
+// split returns the fields of a line. Without a separator, fields are
+// delimited by transitions from non-blank to blank characters, and include
+// their leading blanks.
+func (f *keyFlags) split(line string) []string {
+	if f.separator != "" {
+		return strings.Split(line, f.separator)
+	}
+	var fields []string
+	for len(line) > 0 {
+		i := strings.IndexFunc(line, func(r rune) bool { return r != ' ' && r != '\t' })
+		if i < 0 {
+			fields = append(fields, line)
+			break
+		}
+		j := strings.IndexAny(line[i:], " \t")
+		if j < 0 {
+			fields = append(fields, line)
+			break
+		}
+		fields = append(fields, line[:i+j])
+		line = line[i+j:]
+	}
+	return fields
+}
+
+func (k *key) compare(a, b []string, sep string) int {
+	ka, kb := k.extract(a, sep), k.extract(b, sep)
+	var c int
+	if k.numeric {
+		c = compareNumbers(ka, kb)
+	} else {
+		c = strings.Compare(ka, kb)
+	}
+	if k.reverse {
+		c = -c
+	}
+	return c
+}
+
+// extract returns the text of the key in the fields of a line, which are joined
+// with the separator they were split on. Without a separator, the fields
+// include their leading blanks, so they are joined as-is.
+func (k *key) extract(fields []string, sep string) string {
+	if k.start > len(fields) {
+		return ""
+	}
+	end := len(fields)
+	if k.end != 0 {
+		end = min(k.end, end)
+	}
+	return strings.Join(fields[k.start-1:end], sep)
+}
+
+// compareNumbers compares the numeric values at the beginning of two strings,
+// strings without numeric prefixes compare as zero.
+func compareNumbers(a, b string) int {
+	x, y := parseNumber(a), parseNumber(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return +1
+	default:
+		return 0
+	}
+}
+
+func parseNumber(s string) float64 {
+	s = strings.TrimLeft(s, " \t")
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	v, _ := strconv.ParseFloat(s[:i], 64)
+	return v
+}
+
+// uniqueLines returns a sequence yielding only the first of consecutive lines
+// of seq which compare equal.
+func uniqueLines(cmp func(string, string) int, seq iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		var last string
+		var hasLast bool
+		for line, err := range seq {
+			if err == nil {
+				if hasLast && cmp(last, line) == 0 {
+					continue
+				}
+				last, hasLast = line, true
+			}
+			if !yield(line, err) {
+				return
+			}
+		}
+	}
+}
+
+// expandKeyFlags rewrites the -k and -t flags with attached values, like -k2n
+// or -t:, into the separate arguments expected by the flag package, so the
+// commands accept the same syntax as sort(1).
+func expandKeyFlags(args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...)
+		}
+		if len(arg) > 2 && (arg[:2] == "-k" || arg[:2] == "-t") && arg[2] != '=' {
+			expanded = append(expanded, arg[:2], arg[2:])
+			continue
+		}
+		expanded = append(expanded, arg)
+	}
+	return expanded
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"iter"
+
+	"github.com/achille-roussel/kway-go"
+)
+
+// maxLineSize is the maximum length of lines read by the commands.
+const maxLineSize = 16 << 20
+
+// linesCodec is a kway.Codec encoding strings as newline-terminated lines,
+// which is the format of the inputs and outputs of the commands.
+type linesCodec struct{}
+
+func (linesCodec) Encoder() kway.Encoder[string] {
+	return kway.EncoderFunc[string](func(dst []byte, lines []string) ([]byte, error) {
+		for _, line := range lines {
+			dst = append(dst, line...)
+			dst = append(dst, '\n')
+		}
+		return dst, nil
+	})
+}
+
+func (linesCodec) Decode(r io.Reader) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, maxLineSize)
+		for s.Scan() {
+			if !yield(s.Text(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// writeLines writes the lines yielded by seq to w.
+func writeLines(w io.Writer, seq iter.Seq2[string, error]) error {
+	r := kway.NewReader(seq, linesCodec{}.Encoder())
+	defer r.Close()
+	_, err := io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,65 @@
+// Command kway exposes the merge algorithms of the kway package to the command
+// line.
+//
+// Usage:
+//
+//	kway <command> [flags] [files...]
+//
+// The commands are:
+//
+//	bench    benchmark merges of sorted files under different strategies
+//	merge    merge sorted files, like sort -m
+//	sort     sort the standard input, spilling sorted runs to disk
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"bench": {"benchmark merges of sorted files under different strategies", bench},
+	"merge": {"merge sorted files, like sort -m", mergeCommand},
+	"sort":  {"sort the standard input, spilling sorted runs to disk", sortCommand},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "kway: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	if err := cmd.run(args[1:], stdin, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "kway %s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: kway <command> [flags] [files...]\n\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the contents to files in a temporary directory, and
+// returns their paths.
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, "input"+string(rune('0'+i)))
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func runCommand(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, stderr, code := runCommand(t, "", "frobnicate")
+	if code != 2 || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("unexpected exit code %d and output %q", code, stderr)
+	}
+}
+
+func TestBench(t *testing.T) {
+	paths := writeFiles(t, "a\nc\ne\n", "b\nd\nf\n")
+
+	stdout, stderr, code := runCommand(t, "", append([]string{"bench", "-count", "1", "-batch", "2,4"}, paths...)...)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	// Header, merge, slice with 2 batch sizes, merger, adaptive.
+	if len(lines) != 6 {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+	for _, line := range lines[1:] {
+		if fields := strings.Fields(line); len(fields) != 8 || fields[3] != "6" {
+			t.Errorf("unexpected result: %q", line)
+		}
+	}
+}
+
+func TestBenchJSON(t *testing.T) {
+	paths := writeFiles(t, "a\nd\n", "b\ne\n", "c\nf\n")
+
+	stdout, stderr, code := runCommand(t, "", append([]string{"bench", "-count", "1", "-strategies", "merge,slice", "-batch", "2", "-k", "2,3", "-json"}, paths...)...)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	var report struct {
+		GoVersion string `json:"goVersion"`
+		Results   []struct {
+			Strategy  string  `json:"strategy"`
+			Batch     int     `json:"batch"`
+			Sources   int     `json:"sources"`
+			Values    int64   `json:"values"`
+			MergeRate float64 `json:"mergesPerSecond"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout)
+	}
+	if report.GoVersion == "" {
+		t.Error("missing Go version")
+	}
+	if len(report.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d:\n%s", len(report.Results), stdout)
+	}
+	for i, r := range report.Results {
+		k := 2 + i%2
+		if r.Sources != k || r.Values != int64(2*k) {
+			t.Errorf("result %d: expected %d values from %d sources, got %d from %d", i, 2*k, k, r.Values, r.Sources)
+		}
+		if (r.Strategy == "slice") != (r.Batch == 2) {
+			t.Errorf("result %d: unexpected batch size %d for strategy %q", i, r.Batch, r.Strategy)
+		}
+	}
+}
+
+func TestBenchTooManySources(t *testing.T) {
+	paths := writeFiles(t, "a\n")
+	_, stderr, code := runCommand(t, "", "bench", "-k", "2", paths[0])
+	if code != 1 || !strings.Contains(stderr, "cannot merge 2 of 1 files") {
+		t.Errorf("unexpected exit code %d and output %q", code, stderr)
+	}
+}
+
+func TestBenchUnknownStrategy(t *testing.T) {
+	paths := writeFiles(t, "a\n")
+	_, stderr, code := runCommand(t, "", "bench", "-strategies", "nope", paths[0])
+	if code != 1 || !strings.Contains(stderr, "unknown strategy") {
+		t.Errorf("unexpected exit code %d and output %q", code, stderr)
+	}
+}
+
+func TestSort(t *testing.T) {
+	input := "pear\napple\nfig\nbanana\napple\ncherry\n"
+
+	for _, test := range []struct {
+		scenario string
+		args     []string
+		output   string
+	}{
+		{
+			scenario: "in memory",
+			output:   "apple\napple\nbanana\ncherry\nfig\npear\n",
+		},
+		{
+			scenario: "spilling runs to disk",
+			args:     []string{"-S", "40"},
+			output:   "apple\napple\nbanana\ncherry\nfig\npear\n",
+		},
+		{
+			scenario: "reverse unique",
+			args:     []string{"-S", "40", "-r", "-u"},
+			output:   "pear\nfig\ncherry\nbanana\napple\n",
+		},
+	} {
+		t.Run(test.scenario, func(t *testing.T) {
+			args := append([]string{"sort", "-T", t.TempDir()}, test.args...)
+			stdout, stderr, code := runCommand(t, input, args...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if stdout != test.output {
+				t.Errorf("unexpected output:\nwant: %q\ngot:  %q", test.output, stdout)
+			}
+		})
+	}
+}
+
+func TestSortKeySpanningFields(t *testing.T) {
+	// The fields of a key are joined with the separator, so keys with the same
+	// characters split differently into fields do not compare equal.
+	stdout, stderr, code := runCommand(t, "ab,c\na,bc\nab,c\n", "sort", "-t", ",", "-k", "1,2", "-u", "-T", t.TempDir())
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := "a,bc\nab,c\n"; stdout != want {
+		t.Errorf("unexpected output:\nwant: %q\ngot:  %q", want, stdout)
+	}
+}
+
+func TestSortLarge(t *testing.T) {
+	var input, output strings.Builder
+	for i := range 1000 {
+		fmt.Fprintf(&input, "%d\n", (i*7919)%1000)
+		fmt.Fprintf(&output, "%d\n", i)
+	}
+	stdout, stderr, code := runCommand(t, input.String(), "sort", "-n", "-S", "1K", "-T", t.TempDir())
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if stdout != output.String() {
+		t.Error("lines were not sorted")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	paths := writeFiles(t,
+		"a:1\nc:10\ne:3\n",
+		"b:2\nd:20\n",
+	)
+
+	for _, test := range []struct {
+		scenario string
+		args     []string
+		stdin    string
+		output   string
+	}{
+		{
+			scenario: "whole lines",
+			args:     paths,
+			output:   "a:1\nb:2\nc:10\nd:20\ne:3\n",
+		},
+		{
+			scenario: "standard input",
+			args:     []string{paths[0], "-"},
+			stdin:    "b:0\n",
+			output:   "a:1\nb:0\nc:10\ne:3\n",
+		},
+		{
+			scenario: "numeric key",
+			args:     append([]string{"-t:", "-k2n"}, writeFiles(t, "a:1\ne:3\nc:10\n", "b:2\nd:20\n")...),
+			output:   "a:1\nb:2\ne:3\nc:10\nd:20\n",
+		},
+		{
+			scenario: "reverse numeric key",
+			args:     append([]string{"-t", ":", "-k", "2,2nr"}, writeFiles(t, "c:10\ne:3\na:1\n", "d:20\nb:2\n")...),
+			output:   "d:20\nc:10\ne:3\nb:2\na:1\n",
+		},
+		{
+			scenario: "unique keys",
+			args:     append([]string{"-t", ":", "-k", "1,1", "-u"}, writeFiles(t, "a:1\nb:1\n", "a:2\nc:2\n")...),
+			output:   "a:1\nb:1\nc:2\n",
+		},
+	} {
+		t.Run(test.scenario, func(t *testing.T) {
+			stdout, stderr, code := runCommand(t, test.stdin, append([]string{"merge"}, test.args...)...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if stdout != test.output {
+				t.Errorf("unexpected output:\nwant: %q\ngot:  %q", test.output, stdout)
+			}
+		})
+	}
+}
+
+func TestMergeOutputFile(t *testing.T) {
+	paths := writeFiles(t, "a\nc\n", "b\n")
+	output := filepath.Join(t.TempDir(), "output")
+	if _, stderr, code := runCommand(t, "", "merge", "-o", output, paths[0], paths[1]); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a\nb\nc\n" {
+		t.Errorf("unexpected output: %q", b)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		key key
+		err bool
+	}{
+		{in: "2", key: key{start: 2}},
+		{in: "2,3", key: key{start: 2, end: 3}},
+		{in: "1,1n", key: key{start: 1, end: 1, numeric: true}},
+		{in: "3nr", key: key{start: 3, numeric: true, reverse: true}},
+		{in: "0", err: true},
+		{in: "3,2", err: true},
+		{in: "1x", err: true},
+	} {
+		k, err := parseKey(test.in)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.in)
+			}
+			continue
+		}
+		if err != nil || k != test.key {
+			t.Errorf("%q: want %+v, got %+v (%v)", test.in, test.key, k, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+
+	"github.com/achille-roussel/kway-go"
+)
+
+func mergeCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var keys keyFlags
+	keys.register(flags)
+	output := flags.String("o", "", "write the output to `file` instead of the standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: kway merge [flags] files...\n\n")
+		fmt.Fprintf(stderr, "Merges the lines of sorted files, like sort -m. The file name - refers\n")
+		fmt.Fprintf(stderr, "to the standard input.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(expandKeyFlags(args)); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no files to merge")
+	}
+	cmp, err := keys.compare()
+	if err != nil {
+		return err
+	}
+
+	seqs := make([]iter.Seq2[string, error], flags.NArg())
+	for i, path := range flags.Args() {
+		if path == "-" {
+			seqs[i] = linesCodec{}.Decode(stdin)
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		seqs[i] = linesCodec{}.Decode(f)
+	}
+
+	seq := kway.MergeFunc(cmp, seqs...)
+	if keys.unique {
+		seq = uniqueLines(cmp, seq)
+	}
+	return writeOutput(*output, stdout, seq)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/achille-roussel/kway-go"
+)
+
+// defaultBufferSize is the default memory budget of the sort command.
+const defaultBufferSize = 64 << 20
+
+func sortCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("sort", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var keys keyFlags
+	keys.register(flags)
+	bufferSize := flags.String("S", strconv.Itoa(defaultBufferSize), "use `size` bytes of memory to sort lines, with an optional K, M, or G suffix")
+	tmpDir := flags.String("T", os.TempDir(), "write sorted runs to temporary files in `dir`")
+	output := flags.String("o", "", "write the output to `file` instead of the standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: kway sort [flags]\n\n")
+		fmt.Fprintf(stderr, "Sorts the lines of the standard input. When the input exceeds the memory\n")
+		fmt.Fprintf(stderr, "budget, sorted runs are written to temporary files and merged.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(expandKeyFlags(args)); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return errors.New("sort reads lines from the standard input")
+	}
+	budget, err := parseSize(*bufferSize)
+	if err != nil {
+		return fmt.Errorf("invalid buffer size: %w", err)
+	}
+	cmp, err := keys.compare()
+	if err != nil {
+		return err
+	}
+
+	spill := kway.Spill[string]{
+		Codec:  linesCodec{},
+		Dir:    *tmpDir,
+		Memory: budget,
+		// Account for the string header in addition to the line contents.
+		SizeOf: func(line string) int { return len(line) + 16 },
+	}
+	seq := kway.SortFunc(cmp, spill, linesCodec{}.Decode(stdin))
+	if keys.unique {
+		seq = uniqueLines(cmp, seq)
+	}
+	return writeOutput(*output, stdout, seq)
+}
+
+// writeOutput writes the lines yielded by seq to the named file, or to stdout
+// if the name is empty.
+func writeOutput(name string, stdout io.Writer, seq iter.Seq2[string, error]) error {
+	if name == "" {
+		return writeLines(stdout, seq)
+	}
+	return kway.WriteFile(name, seq, linesCodec{}.Encoder(), 0o644)
+}
+
+// parseSize parses a size in bytes with an optional K, M, or G suffix.
+func parseSize(s string) (int64, error) {
+	scale := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"), strings.HasSuffix(s, "k"):
+		scale = 1 << 10
+	case strings.HasSuffix(s, "M"), strings.HasSuffix(s, "m"):
+		scale = 1 << 20
+	case strings.HasSuffix(s, "G"), strings.HasSuffix(s, "g"):
+		scale = 1 << 30
+	}
+	if scale != 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%d is not positive", n)
+	}
+	return n * scale, nil
+}
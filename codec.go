@@ -0,0 +1,190 @@
+package kway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// Codec is the interface implemented by file formats that can be merged with
+// MergeFiles and written with WriteFile.
+type Codec[T any] interface {
+	// Encoder returns an encoder for a new stream of values.
+	Encoder() Encoder[T]
+	// Decode returns a sequence of values decoded from r.
+	Decode(r io.Reader) iter.Seq2[T, error]
+}
+
+// GobCodec returns a codec encoding values as a stream of gob messages.
+func GobCodec[T any]() Codec[T] { return gobCodec[T]{} }
+
+type gobCodec[T any] struct{}
+
+func (gobCodec[T]) Encoder() Encoder[T] {
+	w := new(appendWriter)
+	enc := gob.NewEncoder(w)
+	return EncoderFunc[T](func(dst []byte, values []T) ([]byte, error) {
+		w.buf = dst
+		for i := range values {
+			if err := enc.Encode(&values[i]); err != nil {
+				return w.buf, err
+			}
+		}
+		return w.buf, nil
+	})
+}
+
+func (gobCodec[T]) Decode(r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		dec := gob.NewDecoder(bufio.NewReader(r))
+		for {
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				if err != io.EOF {
+					yield(v, err)
+				}
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// JSONLinesCodec returns a codec encoding values as newline-delimited JSON.
+func JSONLinesCodec[T any]() Codec[T] { return jsonLinesCodec[T]{} }
+
+type jsonLinesCodec[T any] struct{}
+
+func (jsonLinesCodec[T]) Encoder() Encoder[T] {
+	return EncoderFunc[T](func(dst []byte, values []T) ([]byte, error) {
+		for _, v := range values {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return dst, err
+			}
+			dst = append(dst, b...)
+			dst = append(dst, '\n')
+		}
+		return dst, nil
+	})
+}
+
+func (jsonLinesCodec[T]) Decode(r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		dec := json.NewDecoder(bufio.NewReader(r))
+		for {
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				if err != io.EOF {
+					yield(v, err)
+				}
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// MaxRecordSize is the maximum size of the binary records encoded and decoded
+// by LengthPrefixedCodec. The length of records is read from the files, so it
+// is bounded to prevent corrupted files from causing arbitrarily large
+// allocations.
+const MaxRecordSize = 64 << 20
+
+// ErrRecordTooLarge is the error returned when a binary record exceeds
+// MaxRecordSize.
+var ErrRecordTooLarge = errors.New("record too large")
+
+// LengthPrefixedCodec returns a codec encoding values as binary records, each
+// prefixed with its length encoded as an unsigned varint. The marshal function
+// appends the binary representation of a value to a buffer, and unmarshal
+// decodes a value from its binary representation. The buffer passed to
+// unmarshal is not reused, so the decoded value may retain it.
+//
+// Records are limited to MaxRecordSize bytes, the encoder and the decoder
+// return an error wrapping ErrRecordTooLarge for larger records.
+func LengthPrefixedCodec[T any](marshal func([]byte, T) ([]byte, error), unmarshal func([]byte) (T, error)) Codec[T] {
+	return lengthPrefixedCodec[T]{marshal: marshal, unmarshal: unmarshal}
+}
+
+type lengthPrefixedCodec[T any] struct {
+	marshal   func([]byte, T) ([]byte, error)
+	unmarshal func([]byte) (T, error)
+}
+
+func (c lengthPrefixedCodec[T]) Encoder() Encoder[T] {
+	var record []byte
+	return EncoderFunc[T](func(dst []byte, values []T) ([]byte, error) {
+		for _, v := range values {
+			var err error
+			if record, err = c.marshal(record[:0], v); err != nil {
+				return dst, err
+			}
+			if len(record) > MaxRecordSize {
+				return dst, recordTooLarge(uint64(len(record)))
+			}
+			dst = binary.AppendUvarint(dst, uint64(len(record)))
+			dst = append(dst, record...)
+		}
+		return dst, nil
+	})
+}
+
+func (c lengthPrefixedCodec[T]) Decode(r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		br := bufio.NewReader(r)
+		for {
+			n, err := binary.ReadUvarint(br)
+			if err != nil {
+				if err != io.EOF {
+					yield(zero, unexpectedEOF(err))
+				}
+				return
+			}
+			if n > MaxRecordSize {
+				yield(zero, recordTooLarge(n))
+				return
+			}
+			// Each record is read into a new buffer, so values returned by
+			// unmarshal may retain it.
+			record := make([]byte, n)
+			if _, err := io.ReadFull(br, record); err != nil {
+				yield(zero, unexpectedEOF(err))
+				return
+			}
+			v, err := c.unmarshal(record)
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+func recordTooLarge(size uint64) error {
+	return fmt.Errorf("%w: %d bytes exceeds the limit of %d bytes", ErrRecordTooLarge, size, MaxRecordSize)
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// appendWriter is an io.Writer appending to a byte slice.
+type appendWriter struct{ buf []byte }
+
+func (w *appendWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	return len(b), nil
+}
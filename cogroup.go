@@ -0,0 +1,74 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Group is the set of values associated with a key in each of the sequences
+// passed to CoGroup. Values[i] holds the values of the sequence at index i, in
+// the order they were produced, and is empty if the key was absent from it.
+type Group[K, V any] struct {
+	Key    K
+	Values [][]V
+}
+
+// CoGroup merges sequences of key/value pairs ordered by key, and yields one
+// group for each unique key, which contains the values of the key in each of
+// the sequences.
+//
+// CoGroup is the streaming primitive to reconcile multiple sorted datasets, and
+// generalizes joins: an inner join of two sequences keeps the groups where both
+// sequences have values, an outer join keeps all groups, etc...
+//
+//	for g, err := range kway.CoGroup(left, right) {
+//		if len(g.Values[0]) > 0 && len(g.Values[1]) > 0 {
+//			...
+//		}
+//	}
+//
+// The slices of values in the groups are not reused by the function, the
+// caller may retain them after the iteration moved to the next group.
+//
+// See CoGroupFunc for a version of this function that allows the caller to pass
+// a custom comparison function for keys.
+func CoGroup[K cmp.Ordered, V any](seqs ...iter.Seq2[KeyValue[K, V], error]) iter.Seq2[Group[K, V], error] {
+	return CoGroupFunc(cmp.Compare[K], seqs...)
+}
+
+// CoGroupFunc is like CoGroup but uses the given comparison function to
+// determine the order and equality of keys.
+//
+// See CoGroup for more details.
+func CoGroupFunc[K, V any](cmp func(K, K) int, seqs ...iter.Seq2[KeyValue[K, V], error]) iter.Seq2[Group[K, V], error] {
+	compare := func(a, b KeyValue[K, V]) int { return cmp(a.Key, b.Key) }
+
+	return func(yield func(Group[K, V], error) bool) {
+		var current Group[K, V]
+
+		for kv, err := range MergeIndexedFunc(compare, seqs...) {
+			if err != nil {
+				if !yield(Group[K, V]{}, err) {
+					return
+				}
+				continue
+			}
+
+			if current.Values != nil && cmp(current.Key, kv.Value.Key) != 0 {
+				if !yield(current, nil) {
+					return
+				}
+				current = Group[K, V]{}
+			}
+			if current.Values == nil {
+				current.Key = kv.Value.Key
+				current.Values = make([][]V, len(seqs))
+			}
+			current.Values[kv.Source] = append(current.Values[kv.Source], kv.Value.Value)
+		}
+
+		if current.Values != nil {
+			yield(current, nil)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package kway
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCoGroup(t *testing.T) {
+	type kv = KeyValue[string, int]
+
+	got, err := values(CoGroup(
+		seqOf(kv{"a", 1}, kv{"b", 2}, kv{"b", 3}),
+		seqOf(kv{"b", 4}, kv{"c", 5}),
+		seqOf(kv{"a", 6}, kv{"c", 7}, kv{"c", 8}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Group[string, int]{
+		{Key: "a", Values: [][]int{{1}, nil, {6}}},
+		{Key: "b", Values: [][]int{{2, 3}, {4}, nil}},
+		{Key: "c", Values: [][]int{nil, {5}, {7, 8}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCoGroupError(t *testing.T) {
+	type kv = KeyValue[int, int]
+	errval := errors.New("")
+
+	failing := func(yield func(kv, error) bool) {
+		_ = yield(kv{1, 1}, nil) && yield(kv{}, errval) && yield(kv{2, 2}, nil)
+	}
+
+	var keys []int
+	var errs []error
+	for g, err := range CoGroup(failing, seqOf(kv{2, 3})) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			keys = append(keys, g.Key)
+		}
+	}
+	if !reflect.DeepEqual(keys, []int{1, 2}) {
+		t.Errorf("expected keys [1 2], got %v", keys)
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
@@ -0,0 +1,45 @@
+package kway
+
+import (
+	"bytes"
+	"iter"
+)
+
+// Collator is the interface implemented by types that compare strings using
+// language-specific rules.
+//
+// The interface is satisfied by *collate.Collator from golang.org/x/text.
+type Collator interface {
+	CompareString(a, b string) int
+}
+
+// MergeCollated merges sequences of strings ordered by the given collator.
+//
+// Collation is often expensive, and the merge may compare each string
+// O(log k) times. Applications that can produce collation sort keys should
+// prefer MergeCollatedKeys, which computes the key of each string only once.
+//
+// See Merge for more details.
+func MergeCollated(c Collator, seqs ...iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return MergeFunc(c.CompareString, seqs...)
+}
+
+// MergeCollatedKeys merges sequences of strings ordered by the collation sort
+// keys returned by the key function. The key of each string is computed once,
+// then cached by the merge for all comparisons involving the string.
+//
+// With golang.org/x/text/collate, the key function can be written as:
+//
+//	func(s string) []byte {
+//		var buf collate.Buffer
+//		return c.KeyFromString(&buf, s)
+//	}
+//
+// The keys are retained by the merge until the strings are yielded, the key
+// function must not return slices that get overwritten by subsequent calls,
+// which happens when reusing a collate.Buffer across calls.
+//
+// See Merge for more details.
+func MergeCollatedKeys(key func(string) []byte, seqs ...iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return MergeByFunc(key, bytes.Compare, seqs...)
+}
@@ -0,0 +1,56 @@
+package kway
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// foldCollator is a simplistic collator which ignores case.
+type foldCollator struct{ calls *int }
+
+func (c foldCollator) CompareString(a, b string) int {
+	*c.calls++
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func TestMergeCollated(t *testing.T) {
+	calls := 0
+	got, err := values(MergeCollated(foldCollator{&calls},
+		seqOf("a", "C", "e"),
+		seqOf("B", "d"),
+		seqOf("A"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "A", "B", "C", "d", "e"}; !slices.EqualFunc(got, want, strings.EqualFold) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if calls == 0 {
+		t.Error("expected the collator to be used")
+	}
+}
+
+func TestMergeCollatedKeys(t *testing.T) {
+	keys := 0
+	key := func(s string) []byte {
+		keys++
+		return []byte(strings.ToLower(s))
+	}
+
+	got, err := values(MergeCollatedKeys(key,
+		seqOf("a", "C", "e"),
+		seqOf("B", "d"),
+		seqOf("A"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "A", "B", "C", "d", "e"}; !slices.EqualFunc(got, want, strings.EqualFold) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if keys != len(got) {
+		t.Errorf("expected %d keys to be computed, got %d", len(got), keys)
+	}
+}
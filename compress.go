@@ -0,0 +1,162 @@
+package kway
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Decompressor describes a compression format of files that MergeFiles
+// decompresses transparently.
+type Decompressor struct {
+	// Name of the compression format, for example "gzip".
+	Name string
+	// File name extensions of the format, including the leading dot.
+	Extensions []string
+	// Magic bytes found at the beginning of files of the format, or empty if
+	// the format can only be detected by extension. The magic bytes are only
+	// used by MergeFiles when configured with WithCompressionSniffing.
+	Magic []byte
+	// NewReader returns a reader decompressing r.
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var decompressors struct {
+	sync.RWMutex
+	list []Decompressor
+}
+
+func init() {
+	RegisterDecompressor(Decompressor{
+		Name:       "gzip",
+		Extensions: []string{".gz", ".gzip"},
+		Magic:      []byte{0x1f, 0x8b, 0x08},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	})
+	RegisterDecompressor(Decompressor{
+		Name:       "bzip2",
+		Extensions: []string{".bz2"},
+		Magic:      []byte("BZh"),
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
+	})
+}
+
+// RegisterDecompressor registers a compression format detected by MergeFiles,
+// from the extension of file names, or from the magic bytes of their contents
+// with WithCompressionSniffing.
+//
+// The gzip and bzip2 formats are registered by default, other formats like
+// zstd or snappy can be registered by applications using the packages which
+// implement them:
+//
+//	kway.RegisterDecompressor(kway.Decompressor{
+//		Name:       "zstd",
+//		Extensions: []string{".zst"},
+//		Magic:      []byte{0x28, 0xb5, 0x2f, 0xfd},
+//		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+//			d, err := zstd.NewReader(r)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return d.IOReadCloser(), nil
+//		},
+//	})
+//
+// Registering a format with the same name as a registered format replaces it.
+func RegisterDecompressor(d Decompressor) {
+	if d.NewReader == nil {
+		panic("kway: decompressor " + d.Name + " has no NewReader function")
+	}
+	decompressors.Lock()
+	defer decompressors.Unlock()
+	decompressors.list = slices.DeleteFunc(decompressors.list, func(r Decompressor) bool {
+		return r.Name == d.Name
+	})
+	decompressors.list = append(decompressors.list, d)
+}
+
+// WithCompressionSniffing configures MergeFiles to detect the compression
+// format of files whose name has no registered extension by the magic bytes at
+// the beginning of their contents.
+//
+// Sniffing is disabled by default because the contents of uncompressed files
+// may start with the magic bytes of a format (e.g. text starting with "BZh"),
+// which would then be decoded as compressed data. It should only be enabled
+// when the files are known to be either compressed or encoded in a format that
+// cannot be mistaken for compressed data.
+func WithCompressionSniffing() Option {
+	return func(o *options) { o.sniffCompress = true }
+}
+
+// detectDecompressor returns the decompressor of the file, detected by the
+// extension of its name first, then by its magic bytes if sniff is true.
+func detectDecompressor(name string, r *bufio.Reader, sniff bool) (Decompressor, bool) {
+	decompressors.RLock()
+	defer decompressors.RUnlock()
+
+	ext := filepath.Ext(name)
+	for _, d := range decompressors.list {
+		if slices.Contains(d.Extensions, ext) {
+			return d, true
+		}
+	}
+	if !sniff {
+		return Decompressor{}, false
+	}
+	for _, d := range decompressors.list {
+		if len(d.Magic) == 0 {
+			continue
+		}
+		if magic, _ := r.Peek(len(d.Magic)); bytes.Equal(magic, d.Magic) {
+			return d, true
+		}
+	}
+	return Decompressor{}, false
+}
+
+// openFile opens the named file, decompressing its contents if it uses one of
+// the registered compression formats.
+func openFile(name string, sniff bool) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	d, ok := detectDecompressor(name, r, sniff)
+	if !ok {
+		return &compressedFile{Reader: r, file: f}, nil
+	}
+	z, err := d.NewReader(r)
+	if err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "decompress " + d.Name, Path: name, Err: err}
+	}
+	return &compressedFile{Reader: z, file: f, decompressor: z}, nil
+}
+
+// compressedFile is the reader returned by openFile, which closes both the
+// decompressor and the file.
+type compressedFile struct {
+	io.Reader
+	file         *os.File
+	decompressor io.Closer
+}
+
+func (f *compressedFile) Close() error {
+	var err error
+	if f.decompressor != nil {
+		err = f.decompressor.Close()
+	}
+	return errors.Join(err, f.file.Close())
+}
@@ -0,0 +1,140 @@
+package kway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeGzip(t *testing.T, name string, data []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	z := gzip.NewWriter(&buf)
+	z.Write(data)
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeFilesCompressed(t *testing.T) {
+	dir := t.TempDir()
+	codec := varintCodec()
+	encode := func(values ...int) []byte {
+		b, err := codec.Encoder().Encode(nil, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	paths := []string{
+		filepath.Join(dir, "plain"),
+		filepath.Join(dir, "by-extension.gz"),
+		filepath.Join(dir, "by-magic"),
+	}
+	if err := os.WriteFile(paths[0], encode(1, 4, 7), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeGzip(t, paths[1], encode(2, 5, 8))
+	writeGzip(t, paths[2], encode(3, 6, 9))
+
+	got, err := values(MergeFiles(paths, codec, WithCompressionSniffing()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Without sniffing, the file with no extension is decoded as is.
+	if _, err := values(MergeFiles(paths, codec)); err == nil {
+		t.Error("expected an error decoding the compressed file without sniffing")
+	}
+}
+
+func TestMergeFilesMagicWithoutCompression(t *testing.T) {
+	codec := LengthPrefixedCodec(
+		func(b []byte, v string) ([]byte, error) { return append(b, v...), nil },
+		func(b []byte) (string, error) { return string(b), nil },
+	)
+	// The length prefix of the 66 bytes record is 'B', so the uncompressed file
+	// starts with the magic bytes of bzip2.
+	record := "Zh" + strings.Repeat(".", 64)
+	b, err := codec.Encoder().Encode(nil, []string{record})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte("BZh")) {
+		t.Fatalf("unexpected encoding: %q", b)
+	}
+	path := filepath.Join(t.TempDir(), "records")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := values(MergeFiles([]string{path}, codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{record}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	// A toy format storing bytes with their bits inverted.
+	invert := func(r io.Reader) (io.ReadCloser, error) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for i := range b {
+			b[i] = ^b[i]
+		}
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	RegisterDecompressor(Decompressor{Name: "invert", Extensions: []string{".inv"}, NewReader: invert})
+	defer func() {
+		decompressors.Lock()
+		decompressors.list = decompressors.list[:len(decompressors.list)-1]
+		decompressors.Unlock()
+	}()
+
+	b, err := varintCodec().Encoder().Encode(nil, []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	path := filepath.Join(t.TempDir(), "values.inv")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := values(MergeFiles([]string{path}, varintCodec()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMergeFilesCorruptedCompression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrupted.gz")
+	if err := os.WriteFile(path, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := values(MergeFiles([]string{path}, varintCodec())); err == nil {
+		t.Error("expected an error decompressing the file")
+	}
+}
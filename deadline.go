@@ -0,0 +1,91 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDeadlineExceeded is the error wrapped by the *DeadlineError yielded by a
+// Merger configured with WithDeadline when its deadline expired.
+var ErrDeadlineExceeded = errors.New("merge deadline exceeded")
+
+// DeadlineError is the error yielded by a Merger configured with WithDeadline
+// when its deadline expired before the end of the merge.
+type DeadlineError struct {
+	Deadline time.Time
+	// Progress of the merge when the deadline expired, which reports how many
+	// values were yielded from each source, and how many were read from the
+	// sources but discarded.
+	Progress Progress
+}
+
+// Error satisfies the error interface.
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("%s after yielding %d values", ErrDeadlineExceeded, e.Progress.Total)
+}
+
+// Unwrap returns ErrDeadlineExceeded.
+func (e *DeadlineError) Unwrap() error {
+	return ErrDeadlineExceeded
+}
+
+// WithDeadline configures a Merger to stop at the given time, which gives
+// interactive queries over many sources "best results within a time budget"
+// semantics: the values merged before the deadline are a valid prefix of the
+// merge, and are yielded to the application.
+//
+// When the deadline expires, the Merger yields the values that it already
+// merged, including the pending value of a Duplicates policy, and the values
+// buffered from the sources that are known to be ordered before the next value
+// of every source, without reading from the sources anymore. It then yields a
+// *DeadlineError reporting the progress of the merge in each source, and stops
+// its sources. Checkpoints configured with WithCheckpoint are committed before
+// the error is yielded, so a merge can be resumed from where it stopped.
+//
+// Values are only yielded when they are known to be ordered: the values read
+// from some sources are discarded when other sources did not produce values
+// before the deadline, the Progress of the error reports them as read but not
+// yielded.
+//
+// The deadline also applies to the contexts of the sources (see
+// WithSourceContexts): the Merger checks them between values, and when sources
+// are read concurrently (see WithFlushInterval), it does not wait for sources
+// blocked on reads beyond the deadline.
+func WithDeadline(t time.Time) Option {
+	return func(o *options) { o.deadline = t }
+}
+
+// deadlineContext returns ctx bounded by the deadline of the Merger, if any.
+func (m *Merger[T]) deadlineContext(ctx context.Context) context.Context {
+	if m.opts.deadline.IsZero() {
+		return ctx
+	}
+	ctx, cancel := context.WithDeadline(ctx, m.opts.deadline)
+	m.cancels = append(m.cancels, cancel)
+	return ctx
+}
+
+// expired reports whether the deadline of the Merger expired.
+func (m *Merger[T]) expired() bool {
+	return !m.opts.deadline.IsZero() && !time.Now().Before(m.opts.deadline)
+}
+
+// expire ends a merge whose deadline expired, emitting the value held by the
+// duplicate policy and committing the last checkpoint before failing with a
+// *DeadlineError. It always returns false.
+func (m *Merger[T]) expire(emit func([]T, []int) bool, fail func(error) bool) bool {
+	if m.duplicates != nil {
+		values, sources := m.duplicates.end(m.creditDuplicates)
+		if len(values) > 0 && !emit(values, sources) {
+			return false
+		}
+	}
+	if m.sinceCheckpoint > 0 && !m.commit(fail) {
+		return false
+	}
+	m.publishProgress()
+	fail(&DeadlineError{Deadline: m.opts.deadline, Progress: m.Progress()})
+	return false
+}
@@ -0,0 +1,175 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+// slowSeq yields the given values, sleeping for the delay before each value,
+// and records whether it was stopped by its consumer.
+func slowSeq(delay time.Duration, stopped *bool, values ...int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		defer func() { *stopped = true }()
+		for _, v := range values {
+			time.Sleep(delay)
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	var stopped bool
+	m := newMerger(t, func(a, b int) int { return a - b },
+		[]iter.Seq2[int, error]{
+			sequence(1, 100, 2),
+			slowSeq(5*time.Millisecond, &stopped, 0, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24, 26, 28, 30, 32, 34, 36, 38, 40),
+		},
+		WithDeadline(time.Now().Add(30*time.Millisecond)),
+	)
+
+	var got []int
+	var err error
+	for v, e := range m.All() {
+		if e != nil {
+			err = e
+			break
+		}
+		got = append(got, v)
+	}
+
+	var deadlineErr *DeadlineError
+	if !errors.As(err, &deadlineErr) || !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("expected *DeadlineError, got %v", err)
+	}
+	if len(got) == 0 || len(got) >= 70 {
+		t.Errorf("expected a partial result, got %d values", len(got))
+	}
+	if !slices.Equal(got, sequenceSlice(0, len(got))) {
+		t.Errorf("values are not a prefix of the merge: %v", got)
+	}
+	if p := deadlineErr.Progress; p.Total != int64(len(got)) || p.Yielded[0]+p.Yielded[1] != p.Total {
+		t.Errorf("unexpected progress for %d values: %+v", len(got), p)
+	}
+	if !stopped {
+		t.Error("the source was not stopped")
+	}
+}
+
+func TestWithDeadlineExpired(t *testing.T) {
+	m := newMerger(t, func(a, b int) int { return a - b },
+		[]iter.Seq2[int, error]{sequence(0, 10, 1)},
+		WithDeadline(time.Now().Add(-time.Second)),
+		WithSingleSource(SingleSourcePassthrough),
+	)
+	var got []int
+	var err error
+	for v, e := range m.All() {
+		if e != nil {
+			err = e
+			break
+		}
+		got = append(got, v)
+	}
+	if !errors.Is(err, ErrDeadlineExceeded) || len(got) != 0 {
+		t.Errorf("expected no values and a deadline error, got %v and %v", got, err)
+	}
+}
+
+func TestWithDeadlineFlushesDuplicates(t *testing.T) {
+	var stopped bool
+	blocked := func(yield func(int, error) bool) {
+		defer func() { stopped = true }()
+		if !yield(1, nil) || !yield(1, nil) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		yield(2, nil)
+	}
+
+	m := newMerger(t, func(a, b int) int { return a - b },
+		[]iter.Seq2[int, error]{seqOf(1, 3), blocked},
+		Duplicates(KeepLast),
+		WithDeadline(time.Now().Add(20*time.Millisecond)),
+	)
+
+	var got []int
+	var err error
+	for v, e := range m.All() {
+		if e != nil {
+			err = e
+			break
+		}
+		got = append(got, v)
+	}
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if !slices.Equal(got, []int{1}) {
+		t.Errorf("expected the pending run to be flushed, got %v", got)
+	}
+	if !stopped {
+		t.Error("the source was not stopped")
+	}
+}
+
+func TestWithDeadlineDrainsBuffers(t *testing.T) {
+	deadline := time.Now().Add(20 * time.Millisecond)
+	m := newMerger(t, func(a, b int) int { return a - b },
+		[]iter.Seq2[int, error]{sequence(0, 1000, 2), sequence(1, 1000, 2)},
+		WithDeadline(deadline),
+	)
+
+	var got []int
+	var err error
+	for v, e := range m.All() {
+		if e != nil {
+			err = e
+			break
+		}
+		if len(got) == 0 {
+			// The deadline expires while the first batch of values is being
+			// consumed, the batches of the sources are already buffered.
+			time.Sleep(time.Until(deadline) + 10*time.Millisecond)
+		}
+		got = append(got, v)
+	}
+
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if len(got) <= bufferSize || len(got) > 2*bufferSize {
+		t.Errorf("expected the buffered values to be yielded, got %d values", len(got))
+	}
+	if !slices.Equal(got, sequenceSlice(0, len(got))) {
+		t.Errorf("values are not a prefix of the merge: %v", got)
+	}
+}
+
+func TestWithDeadlineReset(t *testing.T) {
+	m := newMerger(t, func(a, b int) int { return a - b },
+		[]iter.Seq2[int, error]{seqOf(1, 3), seqOf(2, 4)},
+		WithDeadline(time.Now().Add(time.Hour)),
+	)
+	for range 3 {
+		if _, err := values(m.All()); err != nil {
+			t.Fatal(err)
+		}
+		m.Reset(seqOf(1, 3), seqOf(2, 4))
+	}
+	if len(m.cancels) != 0 {
+		t.Errorf("the contexts of previous merges were retained: %d", len(m.cancels))
+	}
+}
+
+func sequenceSlice(min, max int) []int {
+	values := make([]int, 0, max-min)
+	for i := min; i < max; i++ {
+		values = append(values, i)
+	}
+	return values
+}
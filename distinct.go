@@ -0,0 +1,114 @@
+package kway
+
+import (
+	"math"
+	"math/bits"
+)
+
+// WithDistinct configures a Merger to call fn once for each distinct value that
+// it yields, when the value is yielded for the first time.
+//
+// Since the merge produces ordered values, values comparing equal are adjacent
+// in the output, and the Merger detects distinct values by comparing each value
+// with the previous one, without retaining any other state. The number of
+// calls to fn is the exact number of distinct values of the union of the
+// sources, and fn is a convenient place to feed a DistinctEstimator counting
+// distinct values of attributes which are not ordered by the merge (e.g. the
+// number of users in events merged by time).
+func WithDistinct[T any](fn func(value T)) Option {
+	return func(o *options) { o.distinct = fn }
+}
+
+// observeDistinct calls the distinct function with the values that differ from
+// the value preceding them.
+func (m *Merger[T]) observeDistinct(values []T) {
+	for _, v := range values {
+		if m.hasLastDistinct && m.cmp(m.lastDistinct, v) == 0 {
+			continue
+		}
+		m.lastDistinct, m.hasLastDistinct = v, true
+		m.distinct(v)
+	}
+}
+
+// DistinctEstimator estimates the number of distinct values it observed using
+// the HyperLogLog algorithm, in constant memory.
+//
+// The estimator retains 2^precision bytes, and its estimates have a standard
+// error of about 1.04/sqrt(2^precision): 1.6% with a precision of 12, 0.4% with
+// a precision of 16.
+//
+// A DistinctEstimator is not safe to use from multiple goroutines concurrently.
+type DistinctEstimator[T any] struct {
+	hash      func(T) uint64
+	precision uint8
+	registers []uint8
+}
+
+// NewDistinctEstimator constructs an estimator of the given precision, which
+// must be between 4 and 18. The hash function must distribute the hashes of
+// values uniformly over the 64 bits range, for example using hash/maphash.
+func NewDistinctEstimator[T any](precision int, hash func(T) uint64) *DistinctEstimator[T] {
+	if precision < 4 || precision > 18 {
+		panic("kway: distinct estimator precision must be between 4 and 18")
+	}
+	return &DistinctEstimator[T]{
+		hash:      hash,
+		precision: uint8(precision),
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+// Observe adds the value to the set of values observed by the estimator. It has
+// the signature expected by WithDistinct.
+func (e *DistinctEstimator[T]) Observe(value T) {
+	h := e.hash(value)
+	i := h >> (64 - e.precision)
+	// The sentinel bit bounds the rank when the remaining bits are all zero.
+	w := h<<e.precision | 1<<(e.precision-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+	if rank > e.registers[i] {
+		e.registers[i] = rank
+	}
+}
+
+// Merge adds the values observed by other to the estimator, which is how
+// estimates of distributed merges are combined. Both estimators must have the
+// same precision and hash function.
+func (e *DistinctEstimator[T]) Merge(other *DistinctEstimator[T]) {
+	if e.precision != other.precision {
+		panic("kway: cannot merge distinct estimators of different precisions")
+	}
+	for i, r := range other.registers {
+		e.registers[i] = max(e.registers[i], r)
+	}
+}
+
+// Estimate returns the estimated number of distinct values observed.
+func (e *DistinctEstimator[T]) Estimate() uint64 {
+	m := float64(len(e.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range e.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	switch len(e.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	}
+
+	estimate := alpha * m * m / sum
+	// Linear counting is more accurate for small cardinalities.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
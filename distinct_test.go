@@ -0,0 +1,71 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestMergerDistinct(t *testing.T) {
+	var distinct []int
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			seqOf(0, 1, 1, 3),
+			seqOf(1, 2, 3, 3),
+			seqOf(3, 4),
+		},
+		WithDistinct(func(v int) { distinct = append(distinct, v) }),
+	)
+
+	if _, err := values(m.All()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(distinct, want) {
+		t.Errorf("expected %v, got %v", want, distinct)
+	}
+}
+
+// mix64 is the finalizer of splitmix64, which distributes integers uniformly
+// over the 64 bits range.
+func mix64(v int) uint64 {
+	x := uint64(v)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func TestDistinctEstimator(t *testing.T) {
+	for _, n := range []int{10, 1000, 100_000} {
+		e := NewDistinctEstimator(12, mix64)
+		for i := range n {
+			// Observing values multiple times does not change the estimate.
+			e.Observe(i)
+			e.Observe(i)
+		}
+		if err := relativeError(e.Estimate(), n); err > 0.05 {
+			t.Errorf("n=%d: estimate %d is off by %.1f%%", n, e.Estimate(), 100*err)
+		}
+	}
+}
+
+func TestDistinctEstimatorMerge(t *testing.T) {
+	e1 := NewDistinctEstimator(14, mix64)
+	e2 := NewDistinctEstimator(14, mix64)
+	for i := range 50_000 {
+		e1.Observe(i)
+		e2.Observe(i + 25_000)
+	}
+	e1.Merge(e2)
+	if err := relativeError(e1.Estimate(), 75_000); err > 0.03 {
+		t.Errorf("estimate %d is off by %.1f%%", e1.Estimate(), 100*err)
+	}
+}
+
+func relativeError(estimate uint64, n int) float64 {
+	return math.Abs(float64(estimate)-float64(n)) / float64(n)
+}
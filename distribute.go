@@ -0,0 +1,119 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"sync"
+)
+
+// DistributeHash distributes the values of seq to the sinks by hash, the value
+// v is sent to sinks[hash(v) % len(sinks)].
+//
+// Each sink is called in its own goroutine with a sequence yielding the values
+// assigned to it, in the order they were produced by seq: when seq is the
+// output of a merge, each sink receives an ordered subsequence of the merged
+// values, and all values with the same hash are sent to the same sink. This is
+// used to parallelize the writes of large merges, for example to distribute
+// keys to shards.
+//
+// Values are buffered in batches for each sink, a slow sink eventually blocks
+// the distribution of values to all the other sinks. Sinks may stop ranging
+// over their sequence early, the values assigned to them are then discarded.
+// Errors produced by seq are yielded to all the sinks, after the values that
+// preceded them.
+//
+// The function returns when all the sinks returned, with the errors they
+// returned joined together.
+func DistributeHash[T any](seq iter.Seq2[T, error], hash func(T) uint64, sinks ...func(iter.Seq2[T, error]) error) error {
+	n := uint64(len(sinks))
+	return distribute(seq, func(v T) int { return int(hash(v) % n) }, sinks)
+}
+
+// DistributeRoundRobin distributes the values of seq to the sinks in a
+// round-robin fashion, which balances the load of the sinks regardless of the
+// values.
+//
+// Like with DistributeHash, each sink receives the values assigned to it in the
+// order they were produced by seq, but values that compare equal may be sent to
+// different sinks.
+//
+// See DistributeHash for more details.
+func DistributeRoundRobin[T any](seq iter.Seq2[T, error], sinks ...func(iter.Seq2[T, error]) error) error {
+	next := 0
+	return distribute(seq, func(T) int {
+		i := next
+		if next++; next == len(sinks) {
+			next = 0
+		}
+		return i
+	}, sinks)
+}
+
+func distribute[T any](seq iter.Seq2[T, error], route func(T) int, sinks []func(iter.Seq2[T, error]) error) error {
+	if len(sinks) == 0 {
+		panic("kway: no sinks to distribute values to")
+	}
+
+	outputs := make([]partitionOutput[T], len(sinks))
+	errs := make([]error, len(sinks))
+	wg := sync.WaitGroup{}
+
+	for i, sink := range sinks {
+		out := &outputs[i]
+		out.batches = make(chan partitionBatch[T], 1)
+		out.done = make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer out.stop()
+			errs[i] = sink(out.values)
+		}()
+	}
+
+	bufs := make([][]T, len(sinks))
+	for i := range bufs {
+		bufs[i] = make([]T, 0, bufferSize)
+	}
+	// send delivers the buffered values and the error to the sink at index i,
+	// discarding them if it stopped. It returns false if all the sinks
+	// stopped.
+	send := func(i int, err error) bool {
+		out := &outputs[i]
+		select {
+		case out.batches <- partitionBatch[T]{values: bufs[i], err: err}:
+			bufs[i] = make([]T, 0, bufferSize)
+		case <-out.done:
+			bufs[i] = bufs[i][:0]
+			return !allStopped(outputs)
+		}
+		return true
+	}
+
+	func() {
+		for value, err := range seq {
+			if err != nil {
+				for i := range outputs {
+					if !send(i, err) {
+						return
+					}
+				}
+				continue
+			}
+			i := route(value)
+			if bufs[i] = append(bufs[i], value); len(bufs[i]) == cap(bufs[i]) && !send(i, nil) {
+				return
+			}
+		}
+		for i := range outputs {
+			if len(bufs[i]) > 0 && !send(i, nil) {
+				return
+			}
+		}
+	}()
+
+	for i := range outputs {
+		close(outputs[i].batches)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,86 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+// collectSinks returns n sinks appending the values they receive to the
+// returned slices.
+func collectSinks(n int) ([]func(iter.Seq2[int, error]) error, [][]int) {
+	results := make([][]int, n)
+	sinks := make([]func(iter.Seq2[int, error]) error, n)
+	for i := range sinks {
+		sinks[i] = func(seq iter.Seq2[int, error]) error {
+			for v, err := range seq {
+				if err != nil {
+					return err
+				}
+				results[i] = append(results[i], v)
+			}
+			return nil
+		}
+	}
+	return sinks, results
+}
+
+func TestDistributeHash(t *testing.T) {
+	sinks, results := collectSinks(3)
+	hash := func(v int) uint64 { return uint64(v / 10) }
+
+	if err := DistributeHash(count(1000), hash, sinks...); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, got := range results {
+		if !slices.IsSorted(got) {
+			t.Errorf("sink %d received unordered values", i)
+		}
+		for _, v := range got {
+			if int(hash(v)%3) != i {
+				t.Fatalf("sink %d received value %d", i, v)
+			}
+		}
+	}
+	got := slices.Concat(results...)
+	slices.Sort(got)
+	if want, _ := values(count(1000)); !slices.Equal(got, want) {
+		t.Errorf("sinks do not cover the sequence: %d values", len(got))
+	}
+}
+
+func TestDistributeRoundRobin(t *testing.T) {
+	sinks, results := collectSinks(4)
+
+	if err := DistributeRoundRobin(count(1000), sinks...); err != nil {
+		t.Fatal(err)
+	}
+	for i, got := range results {
+		want, _ := values(sequence(i, 1000, 4))
+		if !slices.Equal(got, want) {
+			t.Errorf("sink %d: unexpected values", i)
+		}
+	}
+}
+
+func TestDistributeErrors(t *testing.T) {
+	errval := errors.New("")
+	seq := func(yield func(int, error) bool) {
+		_ = yield(0, nil) && yield(1, nil) && yield(0, errval)
+	}
+
+	stopped := func(seq iter.Seq2[int, error]) error {
+		for range seq {
+			break
+		}
+		return nil
+	}
+	sinks, _ := collectSinks(1)
+
+	err := DistributeRoundRobin(seq, stopped, sinks[0])
+	if !errors.Is(err, errval) {
+		t.Errorf("expected %v, got %v", errval, err)
+	}
+}
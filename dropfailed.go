@@ -0,0 +1,104 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ErrTooManyFailedSources is the error yielded by a Merger configured with
+// WithDropFailedSources when more sources failed than the configured maximum.
+var ErrTooManyFailedSources = errors.New("too many failed sources")
+
+// WithDropFailedSources configures a Merger to isolate the failures of its
+// sources: a source which produces an error is stopped and removed from the
+// merge, which carries on with the remaining sources. This is the behavior
+// expected from best effort merges federating many shards, where the loss of
+// a few shards is preferable to failing the whole merge.
+//
+// Only the errors produced by the sources themselves cause them to be dropped.
+// Context errors, and errors detected by the merge like values out of order
+// (see WithUnorderedPolicy), are yielded to the application like without the
+// option, so a canceled merge does not end as if it had succeeded.
+//
+// The errors of dropped sources are not yielded to the application, they are
+// reported by Merger.FailedSources, and by the Err field of the statistics of
+// the sources when the Merger was configured with WithStats.
+//
+// When more than maxFailures sources failed, the Merger yields an error
+// wrapping ErrTooManyFailedSources and the errors of the failed sources, and
+// stops. A maximum of zero aborts the merge on the first failure.
+func WithDropFailedSources(maxFailures int) Option {
+	if maxFailures < 0 {
+		panic("kway: maximum number of failed sources must not be negative")
+	}
+	return func(o *options) {
+		o.dropFailed = true
+		o.maxFailures = maxFailures
+	}
+}
+
+// FailedSources returns the errors of the sources that were removed from the
+// merge by a Merger configured with WithDropFailedSources, in the order that
+// they failed.
+func (m *Merger[T]) FailedSources() []*SourceError {
+	return append([]*SourceError(nil), m.failed...)
+}
+
+// dropSource records the failure of a source, and returns false if the merge
+// must be aborted because too many sources failed.
+func (m *Merger[T]) dropSource(source int, err error, fail func(error) bool) bool {
+	m.failed = append(m.failed, &SourceError{Index: source, Label: m.label(source), Err: err})
+	if m.stats != nil {
+		m.stats.Sources[source].Err = err
+	}
+	if len(m.failed) > m.opts.maxFailures {
+		errs := make([]error, len(m.failed))
+		for i, err := range m.failed {
+			errs[i] = err
+		}
+		fail(fmt.Errorf("%w: %d of %d sources: %w", ErrTooManyFailedSources, len(m.failed), len(m.seqs), errors.Join(errs...)))
+		return false
+	}
+	return true
+}
+
+// sourceFailure marks the errors produced by a source itself, as opposed to the
+// errors produced by the merge while reading from the source (cancellation of
+// contexts, values out of order, etc...), which are not failures of the source
+// and must not cause it to be dropped.
+type sourceFailure struct{ err error }
+
+func (f *sourceFailure) Error() string { return f.err.Error() }
+
+func (f *sourceFailure) Unwrap() error { return f.err }
+
+// markFailures returns a sequence wrapping the errors of seq in *sourceFailure.
+// Context errors are not marked, they report that the merge was canceled, even
+// when they are produced by the source observing the context.
+func markFailures[T any](seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for v, err := range seq {
+			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				err = &sourceFailure{err}
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// stopOnFailure returns a sequence which ends after yielding the first failure
+// of the source, which stops the iteration of seq.
+func stopOnFailure[T any](seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		var failure *sourceFailure
+		for values, err := range seq {
+			if !yield(values, err) || errors.As(err, &failure) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+// failingSeq yields the given values, then an error, and records whether it was
+// stopped by its consumer.
+func failingSeq(err error, stopped *bool, values ...int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		defer func() { *stopped = true }()
+		for _, v := range values {
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if !yield(0, err) {
+			return
+		}
+		for v := 100; ; v++ {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestWithDropFailedSources(t *testing.T) {
+	errval := errors.New("shard unavailable")
+	var stopped bool
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			seqOf(1, 4, 7),
+			failingSeq(errval, &stopped, 2, 5),
+			seqOf(3, 6, 9),
+		},
+		WithDropFailedSources(1),
+		WithLabels("a", "b", "c"),
+		WithStats(),
+	)
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 9}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !stopped {
+		t.Error("the failed source was not stopped")
+	}
+
+	failed := m.FailedSources()
+	if len(failed) != 1 || failed[0].Index != 1 || failed[0].Label != "b" || failed[0].Err != errval {
+		t.Errorf("wrong failed sources: %v", failed)
+	}
+	if stats := m.Stats(); stats.Sources[1].Err != errval || stats.Sources[0].Err != nil {
+		t.Errorf("wrong source errors in stats: %v, %v", stats.Sources[0].Err, stats.Sources[1].Err)
+	}
+}
+
+func TestWithDropFailedSourcesAbort(t *testing.T) {
+	errval := errors.New("shard unavailable")
+	var stopped0, stopped1 bool
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			failingSeq(errval, &stopped0, 1),
+			failingSeq(errval, &stopped1, 2, 3),
+			seqOf(1, 2, 3, 4, 5),
+		},
+		WithDropFailedSources(1),
+	)
+
+	var got []int
+	var errs []error
+	for v, err := range m.All() {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTooManyFailedSources) || !errors.Is(errs[0], errval) {
+		t.Fatalf("expected the merge to abort, got %v", errs)
+	}
+	if len(m.FailedSources()) != 2 {
+		t.Errorf("expected 2 failed sources, got %v", m.FailedSources())
+	}
+	if slices.Contains(got, 5) {
+		t.Errorf("the merge continued after aborting: %v", got)
+	}
+}
+
+func TestWithDropFailedSourcesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(1, 3), seqOf(2, 4)},
+		WithDropFailedSources(2),
+		WithSourceContexts(ctx, ctx),
+	)
+
+	var errs []error
+	for _, err := range m.All() {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected the cancellation to be reported, got %v", errs)
+	}
+	if failed := m.FailedSources(); len(failed) != 0 {
+		t.Errorf("canceled sources were dropped: %v", failed)
+	}
+}
+
+func TestWithDropFailedSourcesUnordered(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(1, 3, 2), seqOf(2, 4)},
+		WithDropFailedSources(2),
+		WithUnorderedPolicy(UnorderedError),
+	)
+
+	var errs []error
+	for _, err := range m.All() {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrUnordered) {
+		t.Errorf("expected the order error to be reported, got %v", errs)
+	}
+	if failed := m.FailedSources(); len(failed) != 0 {
+		t.Errorf("unordered source was dropped: %v", failed)
+	}
+}
+
+func TestWithDropFailedSourcesReset(t *testing.T) {
+	errval := errors.New("shard unavailable")
+	var stopped bool
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(1, 3), failingSeq(errval, &stopped, 2)},
+		WithDropFailedSources(1),
+		WithStats(),
+	)
+	for range m.All() {
+	}
+	if len(m.FailedSources()) != 1 {
+		t.Fatalf("expected 1 failed source, got %v", m.FailedSources())
+	}
+
+	m.Reset(seqOf(1, 3), seqOf(2, 4))
+	if failed := m.FailedSources(); len(failed) != 0 {
+		t.Errorf("failed sources were not cleared: %v", failed)
+	}
+	if err := m.Stats().Sources[1].Err; err != nil {
+		t.Errorf("source error was not cleared: %v", err)
+	}
+	got, err := values(m.All())
+	if err != nil || !slices.Equal(got, []int{1, 2, 3, 4}) {
+		t.Errorf("unexpected values after reset: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,329 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// DuplicatePolicy defines how a Merger handles values which compare equal,
+// see Duplicates.
+//
+// The policies are KeepAll, KeepFirst and KeepLast, and the policies returned
+// by Combine.
+type DuplicatePolicy struct {
+	mode duplicateMode
+	fold any // func(T, T) T
+}
+
+type duplicateMode int
+
+const (
+	keepAll duplicateMode = iota
+	keepFirst
+	keepLast
+	combine
+)
+
+var (
+	// KeepAll yields all the values, including duplicates. This is the
+	// behavior of the Merge functions.
+	KeepAll = DuplicatePolicy{mode: keepAll}
+	// KeepFirst yields the first of each run of equal values, in the order of
+	// the merge.
+	KeepFirst = DuplicatePolicy{mode: keepFirst}
+	// KeepLast yields the last of each run of equal values, in the order of
+	// the merge.
+	KeepLast = DuplicatePolicy{mode: keepLast}
+)
+
+// Combine returns a policy yielding a single value for each run of equal
+// values, obtained by folding the values in the order of the merge: the first
+// value of the run is the initial accumulator, and fold is called with the
+// accumulator and each of the following values.
+//
+// The combined value must compare equal to the values it was folded from, so
+// the merged sequence remains ordered.
+func Combine[T any](fold func(acc, v T) T) DuplicatePolicy {
+	if fold == nil {
+		panic("kway: Combine requires a fold function")
+	}
+	return DuplicatePolicy{mode: combine, fold: fold}
+}
+
+// Duplicates configures how a Merger handles runs of values which compare
+// equal, regardless of whether they come from the same or different sources.
+// Equal values are adjacent in the merged sequence, so the policies do not
+// retain more than one value, but KeepLast and Combine delay yielding a value
+// until the next distinct value was read from the sources.
+//
+// The policy applies to both Merger.All and Merger.Batches, including to merges
+// of a single source configured with SingleSourcePassthrough. Values which were
+// dropped or folded count as consumed in the positions and resume tokens of
+// the Merger once the value they were deduplicated into was yielded, so
+// resuming a merge does not produce them again.
+//
+// The policies are applied to merges of sequences of values and of slices by
+// MergeDuplicates and MergeSliceDuplicates.
+//
+// NewMerger returns an error wrapping ErrOptionType if the function passed to
+// Combine does not match the type of values of the Merger, and MergeDuplicates
+// and MergeSliceDuplicates yield it.
+func Duplicates(policy DuplicatePolicy) Option {
+	return func(o *options) { o.duplicates = policy }
+}
+
+// MergeDuplicates merges sequences of ordered values like Merge, and applies the
+// duplicate policy to the runs of equal values of the merge, like a Merger
+// configured with Duplicates.
+//
+// The policy is applied to the output of the merge, where equal values are
+// ordered by the index of the sequence they were read from, so the result is
+// the same regardless of the number of sequences and of the merge kernel.
+//
+// See MergeDuplicatesFunc for a version of this function that allows the
+// caller to pass a custom comparison function.
+func MergeDuplicates[T cmp.Ordered](policy DuplicatePolicy, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if policy.mode == keepAll {
+		return Merge(seqs...)
+	}
+	bufferedSeqs := make([]iter.Seq2[[]T, error], len(seqs))
+	for i, seq := range seqs {
+		bufferedSeqs[i] = buffer(bufferSize, seq)
+	}
+	return unbuffer(MergeSliceDuplicates(policy, bufferedSeqs...))
+}
+
+// MergeDuplicatesFunc is like MergeDuplicates but uses the given comparison
+// function to determine the order of values.
+//
+// The sequence yields an error wrapping ErrOptionType if the function passed
+// to Combine does not match the type of values.
+//
+// See MergeDuplicates for more details.
+func MergeDuplicatesFunc[T any](cmp func(T, T) int, policy DuplicatePolicy, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if policy.mode == keepAll {
+		return MergeFunc(cmp, seqs...)
+	}
+	bufferedSeqs := make([]iter.Seq2[[]T, error], len(seqs))
+	for i, seq := range seqs {
+		bufferedSeqs[i] = buffer(bufferSize, seq)
+	}
+	return unbuffer(MergeSliceDuplicatesFunc(cmp, policy, bufferedSeqs...))
+}
+
+// MergeSliceDuplicates merges sequences of slices of ordered values like
+// MergeSlice, and applies the duplicate policy to the runs of equal values of
+// the merge.
+//
+// See MergeDuplicates and MergeSlice for more details.
+func MergeSliceDuplicates[T cmp.Ordered](policy DuplicatePolicy, seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	if policy.mode == keepAll {
+		return MergeSlice(seqs...)
+	}
+	return filterDuplicates(cmp.Compare[T], policy, MergeSlice(seqs...))
+}
+
+// MergeSliceDuplicatesFunc is like MergeSliceDuplicates but uses the given
+// comparison function to determine the order of values.
+//
+// See MergeSliceDuplicates for more details.
+func MergeSliceDuplicatesFunc[T any](cmp func(T, T) int, policy DuplicatePolicy, seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	if policy.mode == keepAll {
+		return MergeSliceFunc(cmp, seqs...)
+	}
+	return filterDuplicates(cmp, policy, MergeSliceFunc(cmp, seqs...))
+}
+
+// filterDuplicates returns a sequence applying the duplicate policy to the
+// batches of seq, which is the output of a merge.
+func filterDuplicates[T any](cmp func(T, T) int, policy DuplicatePolicy, seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		// The values are not attributed to sources, and the positions of the
+		// merge are not tracked, so all values are assigned to source zero and
+		// the deduplicated values are not credited.
+		d, err := newDuplicateFilter(cmp, 1, policy)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		credit := func(T, int, int64) {}
+		var sources []int
+
+		for values, err := range seq {
+			if len(values) > len(sources) {
+				sources = make([]int, len(values))
+			}
+			out, _ := d.filter(values, sources[:len(values)], credit)
+			if (len(out) > 0 || err != nil) && !yield(out, err) {
+				return
+			}
+		}
+
+		if out, _ := d.end(credit); len(out) > 0 {
+			yield(out, nil)
+		}
+	}
+}
+
+// duplicateFilter applies a duplicate policy to the batches of the merge.
+type duplicateFilter[T any] struct {
+	mode    duplicateMode
+	fold    func(T, T) T
+	cmp     func(T, T) int
+	values  []T
+	sources []int
+	// Indexes of the sources of the values compared last, reported when the
+	// comparison panics, see WithComparePanicRecovery.
+	compared [2]int
+	// Current run of equal values: the value representing the run (the first
+	// or last value, or the accumulator), and the source it is attributed to.
+	run       T
+	runSource int
+	hasRun    bool
+	// Number of values of the current run which were deduplicated, indexed by
+	// source, and the sources with a non-zero count.
+	absorbed []int64
+	touched  []int
+	// Deduplicated values of the current batch, which are credited to the
+	// positions of the Merger once the value they were deduplicated into has
+	// been yielded to the application.
+	credits []duplicateCredit[T]
+	next    int
+	yielded int
+}
+
+type duplicateCredit[T any] struct {
+	index  int // index of the value in the output of the filter
+	source int
+	count  int64
+	value  T
+}
+
+func newDuplicateFilter[T any](cmp func(T, T) int, numSources int, policy DuplicatePolicy) (*duplicateFilter[T], error) {
+	if policy.mode == keepAll {
+		return nil, nil
+	}
+	fold, err := typedOption[func(T, T) T]("Combine", policy.fold)
+	if err != nil {
+		return nil, err
+	}
+	return &duplicateFilter[T]{
+		mode:     policy.mode,
+		fold:     fold,
+		cmp:      cmp,
+		values:   make([]T, 0, bufferSize),
+		sources:  make([]int, 0, bufferSize),
+		absorbed: make([]int64, numSources),
+	}, nil
+}
+
+// filter returns the values of the batch which must be yielded, and the
+// indexes of the sources they were read from.
+//
+// The values which were dropped are passed to credit when they were
+// deduplicated into a value yielded by a previous batch, or when observe
+// reports that the value they were deduplicated into has been yielded.
+func (d *duplicateFilter[T]) filter(values []T, sources []int, credit func(T, int, int64)) ([]T, []int) {
+	d.begin(credit)
+
+	for i, v := range values {
+		d.compared = [2]int{d.runSource, sources[i]}
+		if d.hasRun && d.cmp(d.run, v) == 0 {
+			switch d.mode {
+			case keepFirst:
+				if len(d.values) == 0 {
+					credit(v, sources[i], 1)
+				} else {
+					d.credit(len(d.values)-1, sources[i], 1, v)
+				}
+			case keepLast:
+				d.absorb(d.runSource)
+				d.run, d.runSource = v, sources[i]
+			case combine:
+				d.absorb(sources[i])
+				d.run = d.fold(d.run, v)
+			}
+			continue
+		}
+		if d.mode == keepFirst {
+			d.values = append(d.values, v)
+			d.sources = append(d.sources, sources[i])
+		} else if d.hasRun {
+			d.flush()
+		}
+		d.run, d.runSource, d.hasRun = v, sources[i], true
+	}
+	return d.values, d.sources
+}
+
+// end returns the value of the last run if it was not yielded yet, which is the
+// case of the KeepLast and Combine policies.
+func (d *duplicateFilter[T]) end(credit func(T, int, int64)) ([]T, []int) {
+	d.begin(credit)
+	if d.hasRun && d.mode != keepFirst {
+		d.flush()
+		d.hasRun = false
+	}
+	return d.values, d.sources
+}
+
+// observe records that n more values of the output of the filter were yielded
+// to the application, and credits the values deduplicated into them.
+func (d *duplicateFilter[T]) observe(n int, credit func(T, int, int64)) {
+	d.yielded += n
+	for ; d.next < len(d.credits) && d.credits[d.next].index < d.yielded; d.next++ {
+		c := &d.credits[d.next]
+		credit(c.value, c.source, c.count)
+	}
+}
+
+// reset clears the state of the filter, discarding the run and the credits that
+// were pending when the previous merge was interrupted.
+func (d *duplicateFilter[T]) reset() {
+	var zero T
+	d.values, d.sources = d.values[:0], d.sources[:0]
+	d.run, d.runSource, d.hasRun = zero, 0, false
+	clear(d.absorbed)
+	d.touched = d.touched[:0]
+	clear(d.credits)
+	d.credits, d.next, d.yielded = d.credits[:0], 0, 0
+}
+
+// begin prepares the filter for a new batch, crediting the values which remain
+// from the previous batch, whose output was entirely processed.
+func (d *duplicateFilter[T]) begin(credit func(T, int, int64)) {
+	d.observe(len(d.values)-d.yielded, credit)
+	d.values, d.sources = d.values[:0], d.sources[:0]
+	d.credits, d.next, d.yielded = d.credits[:0], 0, 0
+}
+
+func (d *duplicateFilter[T]) absorb(source int) {
+	if d.absorbed[source] == 0 {
+		d.touched = append(d.touched, source)
+	}
+	d.absorbed[source]++
+}
+
+func (d *duplicateFilter[T]) credit(index, source int, count int64, value T) {
+	d.credits = append(d.credits, duplicateCredit[T]{index, source, count, value})
+}
+
+// flush appends the value of the current run to the output of the filter, and
+// records the credits of the values that were deduplicated into it.
+func (d *duplicateFilter[T]) flush() {
+	for _, source := range d.touched {
+		d.credit(len(d.values), source, d.absorbed[source], d.run)
+		d.absorbed[source] = 0
+	}
+	d.touched = d.touched[:0]
+	d.values = append(d.values, d.run)
+	d.sources = append(d.sources, d.runSource)
+}
+
+// creditDuplicates records that n values equal to value were read from a source
+// and consumed by the merge without being yielded to the application.
+func (m *Merger[T]) creditDuplicates(value T, source int, n int64) {
+	p := &m.positions[source]
+	p.Count += n
+	p.Last = value
+}
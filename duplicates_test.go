@@ -0,0 +1,350 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func compareTagged(a, b tagged) int { return cmp.Compare(a.key, b.key) }
+
+func duplicateSources() []iter.Seq2[tagged, error] {
+	return []iter.Seq2[tagged, error]{
+		taggedSeq(0, 1, 1, 2, 4),
+		taggedSeq(1, 1, 3, 4, 4),
+		taggedSeq(2, 0, 1, 4, 5),
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	tests := []struct {
+		scenario string
+		policy   DuplicatePolicy
+		want     []tagged
+	}{
+		{
+			scenario: "keep all",
+			policy:   KeepAll,
+			want: []tagged{
+				{0, 2}, {1, 0}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {3, 1},
+				{4, 0}, {4, 1}, {4, 1}, {4, 2}, {5, 2},
+			},
+		},
+		{
+			scenario: "keep first",
+			policy:   KeepFirst,
+			want:     []tagged{{0, 2}, {1, 0}, {2, 0}, {3, 1}, {4, 0}, {5, 2}},
+		},
+		{
+			scenario: "keep last",
+			policy:   KeepLast,
+			want:     []tagged{{0, 2}, {1, 2}, {2, 0}, {3, 1}, {4, 2}, {5, 2}},
+		},
+		{
+			scenario: "combine",
+			policy: Combine(func(acc, v tagged) tagged {
+				return tagged{acc.key, acc.source + v.source}
+			}),
+			want: []tagged{{0, 2}, {1, 3}, {2, 0}, {3, 1}, {4, 4}, {5, 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			got, err := values(newMerger(t, compareTagged, duplicateSources(), Duplicates(test.policy)).All())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+
+			batches, err := concatValues(newMerger(t, compareTagged, duplicateSources(), Duplicates(test.policy)).Batches())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(batches, test.want) {
+				t.Errorf("batches: expected %v, got %v", test.want, batches)
+			}
+		})
+	}
+}
+
+func TestDuplicatesAcrossBatches(t *testing.T) {
+	seqs := []iter.Seq2[int, error]{
+		sequence(0, 1000, 1),
+		sequence(0, 1000, 2),
+		sequence(500, 1000, 1),
+	}
+	sum := func(acc, v int) int { return acc }
+	for _, policy := range []DuplicatePolicy{KeepFirst, KeepLast, Combine(sum)} {
+		got, err := values(newMerger(t, cmp.Compare[int], seqs, Duplicates(policy)).All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, _ := values(count(1000)); !slices.Equal(got, want) {
+			t.Errorf("expected %d distinct values, got %d", len(want), len(got))
+		}
+	}
+}
+
+func TestDuplicatesSingleSource(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{seqOf(1, 1, 2, 2, 2, 3)},
+		Duplicates(KeepFirst),
+		WithSingleSource(SingleSourcePassthrough),
+	)
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDuplicatesResumeToken(t *testing.T) {
+	for _, policy := range []DuplicatePolicy{KeepFirst, KeepLast} {
+		m := newMerger(t, compareTagged, duplicateSources(), Duplicates(policy))
+		for range m.All() {
+		}
+		if got, want := m.ResumeToken().Offsets, []int64{4, 4, 4}; !slices.Equal(got, want) {
+			t.Errorf("expected offsets %v, got %v", want, got)
+		}
+	}
+
+	// Stop after the value of the run of 1s, all of them are consumed, but not
+	// the values of the following runs which are in the same batch.
+	m := newMerger(t, compareTagged, duplicateSources(), Duplicates(KeepLast))
+	for v := range m.All() {
+		if v.key == 2 {
+			break
+		}
+	}
+	if got, want := m.ResumeToken().Offsets, []int64{2, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("expected offsets %v, got %v", want, got)
+	}
+}
+
+func TestDuplicatesCombineTypeMismatch(t *testing.T) {
+	policy := Combine(func(a, b string) string { return a })
+	if _, err := NewMerger(cmp.Compare[int], []iter.Seq2[int, error]{count(1)}, Duplicates(policy)); !errors.Is(err, ErrOptionType) {
+		t.Errorf("expected an option type error from NewMerger, got %v", err)
+	}
+	if _, err := values(MergeDuplicates(policy, count(1), count(2))); !errors.Is(err, ErrOptionType) {
+		t.Errorf("expected an option type error from MergeDuplicates, got %v", err)
+	}
+}
+
+func TestMergeDuplicates(t *testing.T) {
+	// Sum the sources of the values of each run, which is independent of the
+	// order that equal values are merged in.
+	sum := Combine(func(acc, v tagged) tagged { return tagged{acc.key, acc.source + v.source} })
+	keys := func(values []tagged) []int {
+		k := make([]int, len(values))
+		for i, v := range values {
+			k[i] = v.key
+		}
+		return k
+	}
+
+	for _, test := range []struct {
+		scenario string
+		policy   DuplicatePolicy
+		want     []tagged
+	}{
+		{"keep first", KeepFirst, []tagged{{1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}}},
+		{"keep last", KeepLast, []tagged{{1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}}},
+		{"combine", sum, []tagged{{1, 1}, {2, 0}, {3, 1}, {4, 2}, {5, 1}}},
+	} {
+		t.Run(test.scenario, func(t *testing.T) {
+			// The same values merged from two sequences, which uses the
+			// two-way kernel, and from three sequences, which uses the tree.
+			two := []iter.Seq2[tagged, error]{
+				taggedSeq(0, 1, 1, 2, 4, 4),
+				taggedSeq(1, 1, 3, 4, 4, 5),
+			}
+			three := []iter.Seq2[tagged, error]{
+				taggedSeq(0, 1, 1, 2, 4, 4),
+				taggedSeq(1, 1, 3, 4, 4, 5),
+				taggedSeq(2),
+			}
+			for _, seqs := range [][]iter.Seq2[tagged, error]{two, three} {
+				got, err := values(MergeDuplicatesFunc(compareTagged, test.policy, seqs...))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if test.policy.mode == combine {
+					if !slices.Equal(got, test.want) {
+						t.Errorf("%d sequences: expected %v, got %v", len(seqs), test.want, got)
+					}
+				} else if !slices.Equal(keys(got), keys(test.want)) {
+					t.Errorf("%d sequences: expected %v, got %v", len(seqs), keys(test.want), keys(got))
+				}
+			}
+		})
+	}
+}
+
+func TestMergeDuplicatesTwoWayTies(t *testing.T) {
+	// Equal values of the two sequences are collapsed by the kernel in the
+	// order of the sequences.
+	for _, test := range []struct {
+		policy DuplicatePolicy
+		source int
+	}{
+		{KeepFirst, 0},
+		{KeepLast, 1},
+	} {
+		got, err := values(MergeDuplicatesFunc(compareTagged, test.policy, taggedSeq(0, 1, 2, 3), taggedSeq(1, 1, 2, 3)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []tagged{{1, test.source}, {2, test.source}, {3, test.source}}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestMergeDuplicatesOrdered(t *testing.T) {
+	got, err := values(MergeDuplicates(KeepFirst, seqOf(1, 2, 2, 3), seqOf(2, 3, 3, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4}) {
+		t.Errorf("unexpected values: %v", got)
+	}
+	got, err = values(MergeDuplicates(KeepAll, seqOf(1, 2), seqOf(2, 3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []int{1, 2, 2, 3}) {
+		t.Errorf("unexpected values: %v", got)
+	}
+}
+
+func TestMergeDuplicatesKernels(t *testing.T) {
+	// The two-way kernels of ordered types and of comparison functions, and
+	// the loser-tree of the Merger, must produce the same output.
+	seqs := func() []iter.Seq2[int, error] {
+		return []iter.Seq2[int, error]{duplicates(300, 3), duplicates(200, 7)}
+	}
+	for _, policy := range []DuplicatePolicy{KeepFirst, KeepLast, Combine(func(acc, _ int) int { return acc })} {
+		ordered, err := values(MergeDuplicates(policy, seqs()...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		compared, err := values(MergeDuplicatesFunc(cmp.Compare[int], policy, seqs()...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		merged, err := values(newMerger(t, cmp.Compare[int], seqs(), Duplicates(policy)).All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ordered, merged) || !slices.Equal(compared, merged) {
+			t.Errorf("the kernels diverge from the merger:\nordered:  %v\ncompared: %v\nmerger:   %v", ordered, compared, merged)
+		}
+	}
+}
+
+func TestDuplicatesTieOrder(t *testing.T) {
+	// Equal values are ordered by the index of their sequence, so the policies
+	// give the same result with the two-way kernel, the loser-tree, and the
+	// Merger, regardless of the placement of the sources in the tree.
+	type item struct {
+		key int
+		id  string
+	}
+	compare := func(a, b item) int { return cmp.Compare(a.key, b.key) }
+	seqs := func(n int) []iter.Seq2[item, error] {
+		return []iter.Seq2[item, error]{
+			seqOf(item{1, "a0"}, item{1, "a1"}, item{2, "a2"}),
+			seqOf(item{1, "b0"}, item{2, "b1"}, item{2, "b2"}),
+			seqOf[item](),
+		}[:n]
+	}
+
+	tests := []struct {
+		scenario string
+		policy   DuplicatePolicy
+		want     []string
+	}{
+		{"keep all", KeepAll, []string{"a0", "a1", "b0", "a2", "b1", "b2"}},
+		{"keep first", KeepFirst, []string{"a0", "a2"}},
+		{"keep last", KeepLast, []string{"b0", "b2"}},
+		{"combine", Combine(func(acc, v item) item { return item{acc.key, acc.id + v.id} }), []string{"a0a1b0", "a2b1b2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			paths := map[string]iter.Seq2[item, error]{
+				"two-way":    MergeDuplicatesFunc(compare, test.policy, seqs(2)...),
+				"loser-tree": MergeDuplicatesFunc(compare, test.policy, seqs(3)...),
+				"merger":     newMerger(t, compare, seqs(2), Duplicates(test.policy)).All(),
+				"size hints": newMerger(t, compare, seqs(3), Duplicates(test.policy), WithSizeHints(0, 1, 2)).All(),
+			}
+			for name, seq := range paths {
+				got, err := values(seq)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids := make([]string, len(got))
+				for i, v := range got {
+					ids[i] = v.id
+				}
+				if !slices.Equal(ids, test.want) {
+					t.Errorf("%s: expected %v, got %v", name, test.want, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeSliceDuplicates(t *testing.T) {
+	for _, n := range []int{1, 2, 3} {
+		seqs := []iter.Seq2[[]int, error]{
+			batchesOf([]int{1, 1}, []int{2, 4, 4}),
+			batchesOf([]int{1, 3}, []int{4, 5}),
+			batchesOf([]int{0, 5}, []int{5}),
+		}[:n]
+		var got []int
+		for values, err := range MergeSliceDuplicates(KeepFirst, seqs...) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, values...)
+		}
+		want := [][]int{{1, 2, 4}, {1, 2, 3, 4, 5}, {0, 1, 2, 3, 4, 5}}[n-1]
+		if !slices.Equal(got, want) {
+			t.Errorf("%d sequences: expected %v, got %v", n, want, got)
+		}
+	}
+}
+
+func TestDuplicatesReset(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(1, 1, 2), seqOf(1, 3)},
+		Duplicates(KeepLast),
+	)
+	for v, err := range m.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v == 1 {
+			break
+		}
+	}
+
+	// The run of the interrupted merge must not leak into the next one.
+	m.Reset(seqOf(5, 6), seqOf(6, 7))
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []int{5, 6, 7}) {
+		t.Errorf("unexpected values after reset: %v", got)
+	}
+}
@@ -0,0 +1,42 @@
+package kway
+
+import "encoding/csv"
+
+// NDJSONEncoder returns an encoder writing values as newline-delimited JSON,
+// each value encoded with encoding/json on its own line. This is the format of
+// JSONLinesCodec, which can decode the output of the encoder.
+func NDJSONEncoder[T any]() Encoder[T] { return jsonLinesCodec[T]{}.Encoder() }
+
+// CSVEncoder returns an encoder writing values as CSV records with
+// encoding/csv. The fields function appends the fields of a value to a record
+// and returns it, the record is reused across values. When header is not nil,
+// it is written as the first record of the output.
+//
+//	enc := kway.CSVEncoder([]string{"time", "host", "message"}, func(record []string, e Event) []string {
+//		return append(record, e.Time.Format(time.RFC3339), e.Host, e.Message)
+//	})
+//
+// Each encoder writes one stream of records, the encoders returned by the
+// function must not be shared by multiple readers or files.
+func CSVEncoder[T any](header []string, fields func(record []string, value T) []string) Encoder[T] {
+	w := new(appendWriter)
+	cw := csv.NewWriter(w)
+	var record []string
+	return EncoderFunc[T](func(dst []byte, values []T) ([]byte, error) {
+		w.buf = dst
+		if header != nil {
+			if err := cw.Write(header); err != nil {
+				return w.buf, err
+			}
+			header = nil
+		}
+		for _, v := range values {
+			record = fields(record[:0], v)
+			if err := cw.Write(record); err != nil {
+				return w.buf, err
+			}
+		}
+		cw.Flush()
+		return w.buf, cw.Error()
+	})
+}
@@ -0,0 +1,63 @@
+package kway
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X    int    `json:"x"`
+	Name string `json:"name"`
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	points := []point{{1, "a"}, {2, "b"}, {3, "c"}}
+	r := NewSliceReader(seqOf(points[:2], points[2:]), NDJSONEncoder[point]())
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"x":1,"name":"a"}` + "\n" + `{"x":2,"name":"b"}` + "\n" + `{"x":3,"name":"c"}` + "\n"
+	if string(b) != want {
+		t.Errorf("expected %q, got %q", want, b)
+	}
+
+	decoded, err := values(JSONLinesCodec[point]().Decode(strings.NewReader(want)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, points) {
+		t.Errorf("expected %v, got %v", points, decoded)
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	fields := func(record []string, p point) []string {
+		return append(record, strconv.Itoa(p.X), p.Name)
+	}
+
+	for _, test := range []struct {
+		header []string
+		want   string
+	}{
+		{want: "1,a\n2,\"b,c\"\n3,\"d\"\"e\"\n"},
+		{header: []string{"x", "name"}, want: "x,name\n1,a\n2,\"b,c\"\n3,\"d\"\"e\"\n"},
+	} {
+		seq := seqOf([]point{{1, "a"}, {2, "b,c"}}, []point{{3, `d"e`}})
+		r := NewSliceReader(seq, CSVEncoder(test.header, fields))
+
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.want {
+			t.Errorf("expected %q, got %q", test.want, b)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Entry is a versioned key/value record, as stored by log-structured storage
+// engines: each write of a key produces a new entry with a higher sequence
+// number, and deletions are recorded as tombstones.
+type Entry[K, V any] struct {
+	Key   K
+	Value V
+	// Sequence number of the write which produced the entry, entries with
+	// higher sequence numbers shadow the entries of the same key with lower
+	// sequence numbers.
+	Seq uint64
+	// Tombstone is true if the entry records the deletion of the key, its
+	// value is then meaningless.
+	Tombstone bool
+}
+
+// CompareEntries orders entries by key, then by decreasing sequence numbers, so
+// the newest version of each key comes first. Sequences of entries merged with
+// MergeEntries must be ordered by this function.
+func CompareEntries[K cmp.Ordered, V any](a, b Entry[K, V]) int {
+	return CompareEntriesFunc[K, V](cmp.Compare[K])(a, b)
+}
+
+// CompareEntriesFunc is like CompareEntries but uses the given comparison
+// function to determine the order of keys.
+func CompareEntriesFunc[K, V any](cmpKey func(K, K) int) func(a, b Entry[K, V]) int {
+	return func(a, b Entry[K, V]) int {
+		if c := cmpKey(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Seq, a.Seq)
+	}
+}
+
+// CompactionPolicy defines which entries MergeEntries retains.
+type CompactionPolicy int
+
+const (
+	// CompactKeepNewest retains the newest entry of each key, including
+	// tombstones, which must be preserved when compacting runs which do not
+	// include all the older entries of the keys (e.g. intermediate levels of
+	// a log-structured merge tree).
+	CompactKeepNewest CompactionPolicy = iota
+	// CompactDropDeleted retains the newest entry of each key, and drops the
+	// keys whose newest entry is a tombstone. It is only safe when the merge
+	// includes all the entries of the keys (e.g. compactions into the last
+	// level of a log-structured merge tree).
+	CompactDropDeleted
+	// CompactKeepAll retains all the entries, the sequences are only merged.
+	CompactKeepAll
+)
+
+// MergeEntries merges sequences of entries ordered by CompareEntries, and
+// retains the entries according to the compaction policy. This is the merge
+// step of the compactions of log-structured storage engines:
+//
+//	err := kway.WriteFile("compacted.gob",
+//		kway.MergeEntries(kway.CompactDropDeleted, runs...),
+//		kway.GobCodec[kway.Entry[string, []byte]]().Encoder(),
+//		0644,
+//	)
+//
+// Errors produced by the sequences are yielded unchanged.
+//
+// See MergeEntriesFunc for a version of this function that allows the caller to
+// pass a custom comparison function for keys.
+func MergeEntries[K cmp.Ordered, V any](policy CompactionPolicy, seqs ...iter.Seq2[Entry[K, V], error]) iter.Seq2[Entry[K, V], error] {
+	return MergeEntriesFunc(cmp.Compare[K], policy, seqs...)
+}
+
+// MergeEntriesFunc is like MergeEntries but uses the given comparison function
+// to determine the order of keys. The sequences must be ordered by the function
+// returned by CompareEntriesFunc for the same comparison function.
+//
+// See MergeEntries for more details.
+func MergeEntriesFunc[K, V any](cmpKey func(K, K) int, policy CompactionPolicy, seqs ...iter.Seq2[Entry[K, V], error]) iter.Seq2[Entry[K, V], error] {
+	merged := MergeFunc(CompareEntriesFunc[K, V](cmpKey), seqs...)
+	switch policy {
+	case CompactKeepAll:
+		return merged
+	case CompactKeepNewest:
+		return newestEntries(cmpKey, merged)
+	default:
+		return liveEntries(newestEntries(cmpKey, merged))
+	}
+}
+
+// MergeSnapshot merges sequences of entries ordered by CompareEntries, and
+// yields the state of the keys as of the given snapshot: for each key, the
+// newest entry with a sequence number lower or equal to the snapshot, unless
+// it is a tombstone. Filtering, deduplication, and the merge are done in one
+// pass, which is how multi-version storage engines scan the runs of data
+// visible to a read transaction.
+//
+// Errors produced by the sequences are yielded unchanged.
+//
+// See MergeSnapshotFunc for a version of this function that allows the caller
+// to pass a custom comparison function for keys.
+func MergeSnapshot[K cmp.Ordered, V any](snapshot uint64, seqs ...iter.Seq2[Entry[K, V], error]) iter.Seq2[Entry[K, V], error] {
+	return MergeSnapshotFunc(cmp.Compare[K], snapshot, seqs...)
+}
+
+// MergeSnapshotFunc is like MergeSnapshot but uses the given comparison
+// function to determine the order of keys.
+//
+// See MergeSnapshot for more details.
+func MergeSnapshotFunc[K, V any](cmpKey func(K, K) int, snapshot uint64, seqs ...iter.Seq2[Entry[K, V], error]) iter.Seq2[Entry[K, V], error] {
+	merged := MergeFunc(CompareEntriesFunc[K, V](cmpKey), seqs...)
+
+	visible := func(yield func(Entry[K, V], error) bool) {
+		for e, err := range merged {
+			if err == nil && e.Seq > snapshot {
+				continue
+			}
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+
+	return liveEntries(newestEntries(cmpKey, visible))
+}
+
+// newestEntries yields the first entry of each key, which is the newest when
+// the entries are ordered by CompareEntries.
+func newestEntries[K, V any](cmpKey func(K, K) int, seq iter.Seq2[Entry[K, V], error]) iter.Seq2[Entry[K, V], error] {
+	return LimitPerKey(1, func(a, b Entry[K, V]) int { return cmpKey(a.Key, b.Key) }, seq)
+}
+
+// liveEntries yields the entries which are not tombstones.
+func liveEntries[K, V any](seq iter.Seq2[Entry[K, V], error]) iter.Seq2[Entry[K, V], error] {
+	return func(yield func(Entry[K, V], error) bool) {
+		for e, err := range seq {
+			if err == nil && e.Tombstone {
+				continue
+			}
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package kway
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergeEntries(t *testing.T) {
+	type entry = Entry[string, int]
+
+	runs := func() [][]entry {
+		return [][]entry{
+			{{Key: "a", Value: 1, Seq: 1}, {Key: "b", Value: 2, Seq: 2}, {Key: "c", Seq: 7, Tombstone: true}},
+			{{Key: "a", Value: 3, Seq: 5}, {Key: "b", Seq: 6, Tombstone: true}, {Key: "c", Value: 4, Seq: 3}},
+			{{Key: "a", Value: 5, Seq: 4}, {Key: "d", Value: 6, Seq: 8}},
+		}
+	}
+
+	tests := []struct {
+		policy CompactionPolicy
+		want   []entry
+	}{
+		{CompactKeepNewest, []entry{
+			{Key: "a", Value: 3, Seq: 5},
+			{Key: "b", Seq: 6, Tombstone: true},
+			{Key: "c", Seq: 7, Tombstone: true},
+			{Key: "d", Value: 6, Seq: 8},
+		}},
+		{CompactDropDeleted, []entry{
+			{Key: "a", Value: 3, Seq: 5},
+			{Key: "d", Value: 6, Seq: 8},
+		}},
+		{CompactKeepAll, []entry{
+			{Key: "a", Value: 3, Seq: 5},
+			{Key: "a", Value: 5, Seq: 4},
+			{Key: "a", Value: 1, Seq: 1},
+			{Key: "b", Seq: 6, Tombstone: true},
+			{Key: "b", Value: 2, Seq: 2},
+			{Key: "c", Seq: 7, Tombstone: true},
+			{Key: "c", Value: 4, Seq: 3},
+			{Key: "d", Value: 6, Seq: 8},
+		}},
+	}
+
+	for _, test := range tests {
+		var seqs []iter.Seq2[entry, error]
+		for _, run := range runs() {
+			if !slices.IsSortedFunc(run, CompareEntries) {
+				t.Fatalf("run is not ordered: %v", run)
+			}
+			seqs = append(seqs, seqOf(run...))
+		}
+
+		got, err := values(MergeEntries(test.policy, seqs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("policy %d: expected %v, got %v", test.policy, test.want, got)
+		}
+	}
+}
+
+func TestMergeSnapshot(t *testing.T) {
+	type entry = Entry[string, int]
+
+	runs := []iter.Seq2[entry, error]{
+		seqOf(
+			entry{Key: "a", Value: 1, Seq: 1},
+			entry{Key: "b", Value: 2, Seq: 2},
+			entry{Key: "c", Seq: 7, Tombstone: true},
+		),
+		seqOf(
+			entry{Key: "a", Value: 3, Seq: 5},
+			entry{Key: "b", Seq: 4, Tombstone: true},
+			entry{Key: "c", Value: 4, Seq: 3},
+		),
+		seqOf(
+			entry{Key: "d", Value: 6, Seq: 8},
+		),
+	}
+
+	tests := []struct {
+		snapshot uint64
+		want     []entry
+	}{
+		{0, nil},
+		{2, []entry{{Key: "a", Value: 1, Seq: 1}, {Key: "b", Value: 2, Seq: 2}}},
+		{4, []entry{{Key: "a", Value: 1, Seq: 1}, {Key: "c", Value: 4, Seq: 3}}},
+		{7, []entry{{Key: "a", Value: 3, Seq: 5}}},
+		{8, []entry{{Key: "a", Value: 3, Seq: 5}, {Key: "d", Value: 6, Seq: 8}}},
+	}
+
+	for _, test := range tests {
+		got, err := values(MergeSnapshot(test.snapshot, runs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("snapshot %d: expected %v, got %v", test.snapshot, test.want, got)
+		}
+	}
+}
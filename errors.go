@@ -0,0 +1,48 @@
+package kway
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SourceError is the error type used by Merger to report errors produced by
+// one of its sources.
+type SourceError struct {
+	// Index of the source in the list of sequences passed to the Merger.
+	Index int
+	// Label of the source configured with WithLabels, or empty if the source
+	// had no label.
+	Label string
+	// The error produced by the source.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *SourceError) Error() string {
+	if e.Label != "" {
+		return fmt.Sprintf("kway: source %q: %v", e.Label, e.Err)
+	}
+	return fmt.Sprintf("kway: source %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error produced by the source.
+func (e *SourceError) Unwrap() error { return e.Err }
+
+// WithCollectErrors configures a Merger to collect the errors produced by its
+// sources instead of yielding them inline with the merged values. The merge
+// carries on after source errors, which makes it a best effort merge, and the
+// errors are reported by the Merger.Err method once iteration completes.
+//
+// Errors which are not produced by the sources, such as the cancellation of the
+// context configured with WithContext, are still yielded to the application.
+func WithCollectErrors() Option {
+	return func(o *options) { o.collectErrors = true }
+}
+
+// Err returns the errors collected by a Merger configured with
+// WithCollectErrors, joined with errors.Join. Each error is a *SourceError
+// identifying the source that produced it. The method returns nil if no errors
+// were collected.
+func (m *Merger[T]) Err() error {
+	return errors.Join(m.errs...)
+}
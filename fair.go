@@ -0,0 +1,21 @@
+package kway
+
+// WithFairTies configures a Merger to break ties between sources with equal
+// head values in round-robin order.
+//
+// By default, ties are broken in favor of the source with the lowest index, so
+// a source producing a long run of values equal to the heads of sources with
+// higher indexes yields its whole run before the others get a turn. This is
+// efficient, since runs are emitted with few comparisons, but starves the
+// other sources of values with the same key, which is undesirable when values
+// with equal keys come from different tenants or partitions that must make
+// progress at the same pace.
+//
+// With fair ties, the source which produced a value least recently wins ties,
+// so values with equal keys interleave across sources. Values which compare
+// differently are still merged in order, and the number of comparisons is the
+// same, but runs of values equal to the heads of other sources are no longer
+// emitted at once.
+func WithFairTies() Option {
+	return func(o *options) { o.fairTies = true }
+}
@@ -0,0 +1,81 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"testing"
+)
+
+type tagged struct{ key, source int }
+
+func taggedSeq(source int, keys ...int) iter.Seq2[tagged, error] {
+	return func(yield func(tagged, error) bool) {
+		for _, key := range keys {
+			if !yield(tagged{key, source}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestWithFairTies(t *testing.T) {
+	compare := func(a, b tagged) int { return cmp.Compare(a.key, b.key) }
+
+	for _, n := range []int{2, 3, 5, 8} {
+		seqs := make([]iter.Seq2[tagged, error], n)
+		for i := range seqs {
+			seqs[i] = taggedSeq(i, 0, 1, 1, 1, 1, 1, 1, 1, 1, 2, 3)
+		}
+
+		got, err := values(newMerger(t, compare, seqs, WithFairTies()).All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.IsSortedFunc(got, compare) {
+			t.Fatalf("values are not ordered: %v", got)
+		}
+
+		// Between two values of a source, all other sources with a value of
+		// the same key must have produced one.
+		last := make([]int, n)
+		for i := range last {
+			last[i] = -1
+		}
+		for i, v := range got {
+			if prev := last[v.source]; prev >= 0 && got[prev].key == v.key {
+				seen := map[int]bool{}
+				for _, w := range got[prev+1 : i] {
+					seen[w.source] = true
+				}
+				if len(seen) != n-1 {
+					t.Fatalf("%d sources: source %d produced key %d twice while other sources waited: %v", n, v.source, v.key, got[prev:i+1])
+				}
+			}
+			last[v.source] = i
+		}
+	}
+}
+
+func TestWithoutFairTies(t *testing.T) {
+	compare := func(a, b tagged) int { return cmp.Compare(a.key, b.key) }
+	seqs := []iter.Seq2[tagged, error]{
+		taggedSeq(0, 1, 1, 1, 1, 1, 1),
+		taggedSeq(1, 1, 1, 1, 1, 1, 1),
+	}
+
+	got, err := values(newMerger(t, compare, seqs).All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without fair ties, sources emit their runs of equal values at once.
+	switches := 0
+	for i := 1; i < len(got); i++ {
+		if got[i].source != got[i-1].source {
+			switches++
+		}
+	}
+	if switches > 2 {
+		t.Errorf("expected runs of equal values to be emitted together: %v", got)
+	}
+}
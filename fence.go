@@ -0,0 +1,63 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// MergeFenced merges sequences of versioned values ordered by the keys returned
+// by the key function, and drops the values with a version lower than the
+// floor of their key.
+//
+// The floors are produced by a sequence of key/version pairs ordered by key,
+// which is advanced in lockstep with the merge, so the number of floors does
+// not affect the memory footprint of the merge. Values with a key absent from
+// the floors are never dropped. This is the core of applying a snapshot or
+// truncation point while compacting logs, for example:
+//
+//	// Drop the records of each partition before its truncation offset.
+//	kway.MergeFenced(Record.Partition, Record.Offset, truncations, segments...)
+//
+// Errors produced by the sequence of floors or the sequences of values are
+// yielded inline.
+//
+// See MergeFencedFunc for a version of this function that allows the caller to
+// pass custom comparison functions for keys and versions.
+func MergeFenced[T any, K, V cmp.Ordered](key func(T) K, version func(T) V, floors iter.Seq2[KeyValue[K, V], error], seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return MergeFencedFunc(key, cmp.Compare[K], version, cmp.Compare[V], floors, seqs...)
+}
+
+// MergeFencedFunc is like MergeFenced but uses the given comparison functions
+// to determine the order of keys and versions.
+//
+// See MergeFenced for more details.
+func MergeFencedFunc[T, K, V any](key func(T) K, cmpKey func(K, K) int, version func(T) V, cmpVersion func(V, V) int, floors iter.Seq2[KeyValue[K, V], error], seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	merged := MergeByFunc(key, cmpKey, seqs...)
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		fail := func(err error) bool { return yield(zero, err) }
+
+		floors := newSeeker(keyOf[K, V], cmpKey, floors)
+		defer floors.stop()
+
+		for value, err := range merged {
+			if err != nil {
+				if !fail(err) {
+					return
+				}
+				continue
+			}
+			k := key(value)
+			if !floors.seek(k, fail) {
+				return
+			}
+			if floors.match(k) && cmpVersion(version(value), floors.current.Value) < 0 {
+				continue
+			}
+			if !yield(value, nil) {
+				return
+			}
+		}
+	}
+}
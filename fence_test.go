@@ -0,0 +1,59 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type versioned struct {
+	key     string
+	version int
+}
+
+func versionedKey(v versioned) string  { return v.key }
+func versionedVersion(v versioned) int { return v.version }
+
+func TestMergeFenced(t *testing.T) {
+	type floor = KeyValue[string, int]
+
+	got, err := values(MergeFenced(versionedKey, versionedVersion,
+		seqOf(floor{"a", 2}, floor{"c", 5}, floor{"z", 1}),
+		seqOf(versioned{"a", 1}, versioned{"b", 1}, versioned{"c", 4}),
+		seqOf(versioned{"a", 2}, versioned{"a", 3}, versioned{"c", 6}, versioned{"d", 0}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []versioned{{"a", 2}, {"a", 3}, {"b", 1}, {"c", 6}, {"d", 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMergeFencedError(t *testing.T) {
+	type floor = KeyValue[string, int]
+	errval := errors.New("")
+
+	floors := func(yield func(floor, error) bool) {
+		_ = yield(floor{}, errval) && yield(floor{"b", 2}, nil)
+	}
+
+	var got []versioned
+	var errs []error
+	for v, err := range MergeFenced(versionedKey, versionedVersion, floors,
+		seqOf(versioned{"a", 0}, versioned{"b", 1}, versioned{"b", 2}),
+	) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []versioned{{"a", 0}, {"b", 2}}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
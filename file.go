@@ -0,0 +1,113 @@
+package kway
+
+import (
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes the encoding of values yielded by seq to the named file,
+// creating it with permissions perm if it does not exist.
+//
+// The file is replaced atomically: values are written to a temporary file in
+// the same directory, which is synced to stable storage and renamed to the
+// target name only after the sequence completed without errors. If an error
+// occurs, the temporary file is removed and the target file is left untouched,
+// so compaction jobs never expose partially written outputs.
+func WriteFile[T any](name string, seq iter.Seq2[T, error], enc Encoder[T], perm fs.FileMode) error {
+	r := NewReader(seq, enc)
+	defer r.Close()
+	return writeFileAtomic(name, r, perm)
+}
+
+func writeFileAtomic(name string, r io.Reader, perm fs.FileMode) (err error) {
+	f, err := createAtomic(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.abort()
+		}
+	}()
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.commit(perm)
+}
+
+// atomicFile is a temporary file which replaces the file at its target name
+// when committed.
+type atomicFile struct {
+	*os.File
+	name string
+	dir  string
+}
+
+func createAtomic(name string) (*atomicFile, error) {
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, name: name, dir: dir}, nil
+}
+
+// commit syncs the temporary file to stable storage and renames it to the
+// target name.
+func (f *atomicFile) commit(perm fs.FileMode) error {
+	if err := f.sync(perm); err != nil {
+		return err
+	}
+	return f.publish()
+}
+
+// sync sets the permissions of the temporary file, syncs it to stable storage,
+// and closes it, without renaming it to the target name.
+func (f *atomicFile) sync(perm fs.FileMode) error {
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// publish renames the synced temporary file to the target name, and syncs the
+// directory to persist the renaming.
+func (f *atomicFile) publish() error {
+	if err := f.rename(); err != nil {
+		return err
+	}
+	return syncDir(f.dir)
+}
+
+// rename renames the synced temporary file to the target name, without syncing
+// the directory.
+func (f *atomicFile) rename() error {
+	return os.Rename(f.Name(), f.name)
+}
+
+// abort closes and removes the temporary file, leaving the target untouched.
+// Files that were already published are not removed.
+func (f *atomicFile) abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// syncDir syncs the directory to stable storage, which persists the renaming
+// of files in the directory.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
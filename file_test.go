@@ -0,0 +1,57 @@
+package kway
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "merged")
+
+	if err := WriteFile(name, Merge(seqOf(1, 3), seqOf(2)), lineEncoder, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "1\n2\n3\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestWriteFileError(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "merged")
+	errval := errors.New("")
+
+	if err := os.WriteFile(name, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval)
+	}
+	if err := WriteFile(name, failing, lineEncoder, 0644); err != errval {
+		t.Fatalf("expected %v, got %v", errval, err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "previous" {
+		t.Errorf("target file was modified: %q", b)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary file was not removed: %v", entries)
+	}
+}
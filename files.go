@@ -0,0 +1,122 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"path/filepath"
+	"slices"
+)
+
+// MergeFiles merges the values of files encoded with the given codec. The files
+// must each contain ordered values.
+//
+// The files are opened when iteration begins, and closed when it ends. Errors
+// opening or decoding a file are yielded in place of its values.
+//
+// Compressed files are decompressed transparently, their format is detected by
+// the extension of their name, see RegisterDecompressor. Sets of compressed and
+// uncompressed files can be merged together. MergeFiles supports the following
+// options, other options are ignored:
+//
+//   - WithCompressionSniffing also detects the compression format of files by
+//     the magic bytes at the beginning of their contents.
+//   - WithManifest uses the bounds and checksums recorded by WriteFiles in a
+//     manifest to merge disjoint files without comparing their values, and to
+//     verify the integrity of the files.
+//
+// Combined with WriteFile, the function implements the merge phase of external
+// sorts and the compaction of sorted files:
+//
+//	err := kway.WriteFile("merged.gob",
+//		kway.MergeFiles(paths, kway.GobCodec[Event]()),
+//		kway.GobCodec[Event]().Encoder(),
+//		0644,
+//	)
+//
+// See MergeFilesFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func MergeFiles[T cmp.Ordered](paths []string, codec Codec[T], opts ...Option) iter.Seq2[T, error] {
+	return MergeFilesFunc(cmp.Compare[T], paths, codec, opts...)
+}
+
+// MergeFilesFunc is like MergeFiles but uses the given comparison function to
+// determine the order of values.
+//
+// See MergeFiles for more details.
+func MergeFilesFunc[T any](cmp func(T, T) int, paths []string, codec Codec[T], opts ...Option) iter.Seq2[T, error] {
+	o := makeOptions(opts)
+	return func(yield func(T, error) bool) {
+		var zero T
+		var files map[string]ManifestFile
+		if o.manifest != "" {
+			var err error
+			if files, err = manifestFiles(o.manifest); err != nil {
+				yield(zero, err)
+				return
+			}
+		}
+
+		seqs := make([]Bounded[T], len(paths))
+		for i, path := range paths {
+			if abs, err := filepath.Abs(path); err == nil {
+				if file, ok := files[abs]; ok {
+					seqs[i] = boundedManifestFile(path, file, codec)
+					continue
+				}
+			}
+			f, err := openFile(path, o.sniffCompress)
+			if err != nil {
+				seqs[i] = Unbounded(func(yield func(T, error) bool) {
+					yield(zero, err)
+				})
+				continue
+			}
+			defer f.Close()
+			seqs[i] = Unbounded(codec.Decode(f))
+		}
+
+		for v, err := range MergeBoundedFunc(cmp, seqs...) {
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// MergeGlob merges the values of the files matching the pattern, with the
+// syntax of filepath.Match. The manifest configured with WithManifest is
+// excluded from the files matching the pattern. An error is yielded if the
+// pattern is malformed.
+//
+// See MergeFiles for more details, and MergeGlobFunc for a version of this
+// function that allows the caller to pass a custom comparison function.
+func MergeGlob[T cmp.Ordered](pattern string, codec Codec[T], opts ...Option) iter.Seq2[T, error] {
+	return MergeGlobFunc(cmp.Compare[T], pattern, codec, opts...)
+}
+
+// MergeGlobFunc is like MergeGlob but uses the given comparison function to
+// determine the order of values.
+//
+// See MergeGlob for more details.
+func MergeGlobFunc[T any](cmp func(T, T) int, pattern string, codec Codec[T], opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		if o := makeOptions(opts); o.manifest != "" {
+			manifest, _ := filepath.Abs(o.manifest)
+			paths = slices.DeleteFunc(paths, func(path string) bool {
+				abs, _ := filepath.Abs(path)
+				return abs == manifest
+			})
+		}
+		for v, err := range MergeFilesFunc(cmp, paths, codec, opts...) {
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package kway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func varintCodec() Codec[int] {
+	return LengthPrefixedCodec(
+		func(b []byte, v int) ([]byte, error) { return binary.AppendVarint(b, int64(v)), nil },
+		func(b []byte) (int, error) {
+			v, n := binary.Varint(b)
+			if n <= 0 {
+				return 0, errors.New("invalid varint")
+			}
+			return int(v), nil
+		},
+	)
+}
+
+func TestMergeFiles(t *testing.T) {
+	codecs := []struct {
+		name  string
+		codec Codec[int]
+	}{
+		{"gob", GobCodec[int]()},
+		{"jsonl", JSONLinesCodec[int]()},
+		{"length-prefixed", varintCodec()},
+	}
+
+	for _, test := range codecs {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := make([]string, 3)
+			for i := range paths {
+				paths[i] = filepath.Join(dir, fmt.Sprint(i))
+				if err := WriteFile(paths[i], sequence(i, 1000, 3), test.codec.Encoder(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			output := filepath.Join(dir, "merged")
+			if err := WriteFile(output, MergeFiles(paths, test.codec), test.codec.Encoder(), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := values(test.codec.Decode(f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := values(count(1000))
+			if !slices.Equal(got, want) {
+				t.Errorf("merged file does not contain the expected values")
+			}
+		})
+	}
+}
+
+func TestMergeFilesMissing(t *testing.T) {
+	_, err := values(MergeFiles([]string{filepath.Join(t.TempDir(), "missing")}, GobCodec[int]()))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestLengthPrefixedCodecTruncated(t *testing.T) {
+	codec := varintCodec()
+	b, err := codec.Encoder().Encode(nil, []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := io.LimitReader(bytes.NewReader(b), int64(len(b)-1))
+	if _, err := values(codec.Decode(r)); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+// retainingCodec is a codec of byte slices whose decoded values retain the
+// buffer passed to unmarshal.
+func retainingCodec() Codec[[]byte] {
+	return LengthPrefixedCodec(
+		func(b []byte, v []byte) ([]byte, error) { return append(b, v...), nil },
+		func(b []byte) ([]byte, error) { return b, nil },
+	)
+}
+
+func TestLengthPrefixedCodecRetainsRecords(t *testing.T) {
+	codec := retainingCodec()
+	b, err := codec.Encoder().Encode(nil, [][]byte{[]byte("aa"), []byte("bb"), []byte("cc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := values(codec.Decode(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{[]byte("aa"), []byte("bb"), []byte("cc")}; !slices.EqualFunc(got, want, bytes.Equal) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestLengthPrefixedCodecTooLarge(t *testing.T) {
+	codec := varintCodec()
+	b, err := codec.Encoder().Encode(nil, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A corrupted length must not be used to allocate the record.
+	b = binary.AppendUvarint(b, 1<<62)
+
+	got, err := values(codec.Decode(bytes.NewReader(b)))
+	if !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("expected %v, got %v (%v)", ErrRecordTooLarge, err, got)
+	}
+}
@@ -0,0 +1,119 @@
+package kway
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// WithFlushInterval configures a Merger to produce values within the given
+// interval after they were read from the sources, even if the internal buffers
+// are not full.
+//
+// By default, the Merger reads values from the sources in batches, and does
+// not produce values until a batch is full or the source is exhausted, which
+// is optimal for throughput but can retain values from slow sources for a long
+// time. With a flush interval, each source is read from a separate goroutine
+// and partial batches are flushed when the interval elapses, and the Merger
+// yields merged values as soon as it would otherwise have to block waiting on
+// a source.
+//
+// Note that the k-way merge can only produce a value once all sources have
+// produced their next value, a merge is always as slow as its slowest source.
+//
+// When the application stops consuming the merge early, the goroutines reading
+// from the sources exit the next time the sources produce a value.
+func WithFlushInterval(d time.Duration) Option {
+	if d <= 0 {
+		panic("kway: flush interval must be positive")
+	}
+	return func(o *options) { o.flushInterval = d }
+}
+
+// bufferInterval is like bufferFunc but also yields partially filled buffers if
+// no new values were produced by the sequence within the given interval.
+//
+// When ctx is canceled, the sequence yields the cause of the cancellation and
+// stops, even if the goroutine reading from seq is blocked.
+func bufferInterval[T any](ctx context.Context, bufferSize int, interval time.Duration, flushFunc func(T) bool, seq iter.Seq2[T, error]) iter.Seq2[[]T, error] {
+	type item struct {
+		value T
+		err   error
+	}
+
+	return func(yield func([]T, error) bool) {
+		items := make(chan item)
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			defer close(items)
+			for value, err := range seq {
+				select {
+				case items <- item{value, err}:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		buf := make([]T, 0, bufferSize)
+		timer := time.NewTimer(interval)
+		timer.Stop()
+		defer timer.Stop()
+		var timeout <-chan time.Time
+
+		flush := func(err error) bool {
+			timer.Stop()
+			timeout = nil
+			ok := yield(buf, err)
+			buf = buf[:0]
+			return ok
+		}
+
+		for {
+			select {
+			case it, ok := <-items:
+				if !ok {
+					if len(buf) > 0 && !flush(nil) {
+						return
+					}
+					// The goroutine also exits when the context is canceled.
+					if ctx.Err() != nil {
+						yield(nil, context.Cause(ctx))
+					}
+					return
+				}
+				if it.err != nil {
+					// Values read before the error are yielded with it, like
+					// bufferFunc does.
+					if !flush(it.err) {
+						return
+					}
+					continue
+				}
+				if len(buf) == 0 {
+					timer.Reset(interval)
+					timeout = timer.C
+				}
+				buf = append(buf, it.value)
+				full := len(buf) == cap(buf) || (flushFunc != nil && flushFunc(it.value))
+				if full && !flush(nil) {
+					return
+				}
+			case <-timeout:
+				if !flush(nil) {
+					return
+				}
+			case <-ctx.Done():
+				if len(buf) > 0 && !flush(nil) {
+					return
+				}
+				yield(nil, context.Cause(ctx))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMergerFlushInterval(t *testing.T) {
+	release := make(chan struct{})
+	closeRelease := sync.OnceFunc(func() { close(release) })
+	// Prevent the test from hanging if values are not flushed.
+	defer time.AfterFunc(5*time.Second, closeRelease).Stop()
+
+	trickle := func(yield func(int, error) bool) {
+		if !yield(1, nil) {
+			return
+		}
+		<-release
+		yield(3, nil)
+	}
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{trickle, seqOf(2)},
+		WithFlushInterval(time.Millisecond),
+	)
+
+	start := time.Now()
+	var got []int
+	for v, err := range m.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v == 1 {
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("first value was not flushed in time (%s)", elapsed)
+			}
+			closeRelease()
+		}
+		got = append(got, v)
+	}
+
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBufferInterval(t *testing.T) {
+	var got []int
+	for values, err := range bufferInterval(context.Background(), 4, time.Hour, nil, count(10)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != 4 && len(got) < 8 {
+			t.Errorf("expected full batches, got %v", values)
+		}
+		got = append(got, values...)
+	}
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBufferIntervalError(t *testing.T) {
+	errFailed := errors.New("failed")
+	source := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(2, nil) && yield(0, errFailed)
+	}
+	seq := bufferInterval(context.Background(), 4, time.Hour, nil, source)
+
+	for values, err := range seq {
+		if !slices.Equal(values, []int{1, 2}) || !errors.Is(err, errFailed) {
+			t.Errorf("expected the values read before the error, got %v, %v", values, err)
+		}
+		break
+	}
+}
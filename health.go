@@ -0,0 +1,98 @@
+package kway
+
+import (
+	"iter"
+	"sync/atomic"
+	"time"
+)
+
+// SourceHealth reports the activity of a Merger's source.
+type SourceHealth struct {
+	Index int
+	Label string
+	// Time elapsed since the source last produced a value, or since the merge
+	// started if it has not produced any values yet.
+	Idle time.Duration
+	// Done is true when the source has been exhausted.
+	Done bool
+}
+
+// WithIdleTimeout configures a Merger to monitor the activity of its sources,
+// and invoke fn when a source has not produced any values for longer than d.
+//
+// The function is called from a separate goroutine, once each time a source
+// becomes idle. Sources that were exhausted are never reported as idle, which
+// allows applications to distinguish sources that are slow from sources that
+// are done. The Merger.Health method can also be used to inspect the state of
+// sources at any time.
+func WithIdleTimeout(d time.Duration, fn func(SourceHealth)) Option {
+	if d <= 0 {
+		panic("kway: idle timeout must be positive")
+	}
+	return func(o *options) {
+		o.idleTimeout = d
+		o.onIdle = fn
+	}
+}
+
+type sourceActivity struct {
+	last atomic.Int64 // unix nanoseconds
+	done atomic.Bool
+}
+
+func (a *sourceActivity) health(now time.Time) (idle time.Duration, done bool) {
+	return now.Sub(time.Unix(0, a.last.Load())), a.done.Load()
+}
+
+func trackActivity[T any](a *sourceActivity, seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for value, err := range seq {
+			a.last.Store(time.Now().UnixNano())
+			if !yield(value, err) {
+				return
+			}
+		}
+		a.done.Store(true)
+	}
+}
+
+// Health returns the activity of the Merger's sources. The method may be
+// called from any goroutine, including while the merge is in progress.
+//
+// Activity is only tracked when the Merger was configured with
+// WithIdleTimeout, otherwise the method returns nil.
+func (m *Merger[T]) Health() []SourceHealth {
+	if m.activity == nil {
+		return nil
+	}
+	now := time.Now()
+	health := make([]SourceHealth, len(m.activity))
+	for i := range m.activity {
+		idle, done := m.activity[i].health(now)
+		health[i] = SourceHealth{Index: i, Label: m.label(i), Idle: idle, Done: done}
+	}
+	return health
+}
+
+// monitor invokes the idle callback for sources that become idle, until the
+// done channel is closed.
+func (m *Merger[T]) monitor(done <-chan struct{}) {
+	ticker := time.NewTicker(max(m.opts.idleTimeout/4, time.Millisecond))
+	defer ticker.Stop()
+
+	reported := make([]bool, len(m.activity))
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		for _, h := range m.Health() {
+			idle := !h.Done && h.Idle > m.opts.idleTimeout
+			if idle && !reported[h.Index] && m.opts.onIdle != nil {
+				m.opts.onIdle(h)
+			}
+			reported[h.Index] = idle
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMergerIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	closeRelease := sync.OnceFunc(func() { close(release) })
+	defer time.AfterFunc(5*time.Second, closeRelease).Stop()
+
+	stalled := func(yield func(int, error) bool) {
+		if !yield(1, nil) {
+			return
+		}
+		<-release
+		yield(3, nil)
+	}
+
+	var mutex sync.Mutex
+	var idle []SourceHealth
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(2), stalled},
+		WithLabels("done", "stalled"),
+		WithIdleTimeout(10*time.Millisecond, func(h SourceHealth) {
+			mutex.Lock()
+			idle = append(idle, h)
+			mutex.Unlock()
+			closeRelease()
+		}),
+	)
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(idle) != 1 {
+		t.Fatalf("expected one idle source to be reported, got %v", idle)
+	}
+	if idle[0].Index != 1 || idle[0].Label != "stalled" || idle[0].Done {
+		t.Errorf("unexpected idle source report: %+v", idle[0])
+	}
+
+	for _, h := range m.Health() {
+		if !h.Done {
+			t.Errorf("expected source %d to be done", h.Index)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package kway
+
+import "iter"
+
+// Histogram is an equi-depth histogram of an ordered sequence of values, which
+// divides the values in buckets holding approximately the same number of
+// values.
+type Histogram[T any] struct {
+	// Total number of values in the sequence.
+	Count int64
+	// Boundaries between the buckets: the bucket at index i holds the values
+	// greater or equal to Boundaries[i-1] and less than Boundaries[i]. The
+	// boundaries can be passed to Partition to split the sequence in ranges
+	// of similar sizes.
+	Boundaries []T
+}
+
+// histogramResolution is the number of samples retained per bucket, the depth
+// of each bucket is accurate to about 1/histogramResolution of the ideal.
+const histogramResolution = 16
+
+// BuildHistogram returns a sequence yielding the values of seq, and a function
+// returning an equi-depth histogram with the given number of buckets of the
+// values yielded so far, which is typically called once the sequence has been
+// consumed. The values of seq must be ordered, like the output of a merge.
+//
+// The histogram is built while consuming the sequence, retaining a number of
+// samples proportional to the number of buckets regardless of the length of
+// the sequence, which gives the split points of subsequent partitioned merges
+// for free.
+//
+// Errors produced by seq are yielded unchanged.
+func BuildHistogram[T any](buckets int, seq iter.Seq2[T, error]) (iter.Seq2[T, error], func() Histogram[T]) {
+	if buckets <= 0 {
+		panic("kway: histogram must have at least one bucket")
+	}
+
+	// Values at indexes multiple of the stride are sampled. When the samples
+	// are full, every other sample is dropped and the stride doubles, so the
+	// samples remain evenly spaced.
+	samples := make([]T, 0, 2*buckets*histogramResolution)
+	stride := int64(1)
+	count := int64(0)
+
+	histogram := func() Histogram[T] {
+		h := Histogram[T]{Count: count}
+		if count == 0 {
+			return h
+		}
+		h.Boundaries = make([]T, buckets-1)
+		for i := range h.Boundaries {
+			target := int64(i+1) * count / int64(buckets)
+			h.Boundaries[i] = samples[target/stride]
+		}
+		return h
+	}
+
+	return func(yield func(T, error) bool) {
+		for value, err := range seq {
+			if err == nil {
+				if count%stride == 0 {
+					if len(samples) == cap(samples) {
+						for i := range len(samples) / 2 {
+							samples[i] = samples[2*i]
+						}
+						clear(samples[len(samples)/2:])
+						samples = samples[:len(samples)/2]
+						stride *= 2
+					}
+					if count%stride == 0 {
+						samples = append(samples, value)
+					}
+				}
+				count++
+			}
+			if !yield(value, err) {
+				return
+			}
+		}
+	}, histogram
+}
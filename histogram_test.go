@@ -0,0 +1,60 @@
+package kway
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildHistogram(t *testing.T) {
+	for _, n := range []int{0, 3, 100, 10_000, 123_457} {
+		seq, histogram := BuildHistogram(8, count(n))
+		got, err := values(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != n {
+			t.Fatalf("n=%d: expected %d values, got %d", n, n, len(got))
+		}
+
+		h := histogram()
+		if h.Count != int64(n) {
+			t.Errorf("n=%d: expected count %d, got %d", n, n, h.Count)
+		}
+		if n == 0 {
+			if h.Boundaries != nil {
+				t.Errorf("n=0: expected no boundaries, got %v", h.Boundaries)
+			}
+			continue
+		}
+		if len(h.Boundaries) != 7 || !slices.IsSorted(h.Boundaries) {
+			t.Fatalf("n=%d: invalid boundaries %v", n, h.Boundaries)
+		}
+
+		// Since the values are 0..n-1, the boundaries are the sizes of
+		// the buckets preceding them.
+		depth := n / 8
+		for i, b := range h.Boundaries {
+			ideal := (i + 1) * n / 8
+			if diff := ideal - b; diff < 0 || diff > depth/histogramResolution+1 {
+				t.Errorf("n=%d: boundary %d is %d, ideal is %d", n, i, b, ideal)
+			}
+		}
+	}
+}
+
+func TestBuildHistogramPartition(t *testing.T) {
+	seq, histogram := BuildHistogram(4, Merge(sequence(0, 1000, 2), sequence(1, 1000, 2)))
+	if _, err := values(seq); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, part := range Partition(histogram().Boundaries, count(1000)) {
+		got, err := values(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) < 240 || len(got) > 260 {
+			t.Errorf("partition %d has %d values", i, len(got))
+		}
+	}
+}
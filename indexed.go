@@ -0,0 +1,116 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Indexed is a value yielded by MergeIndexed, paired with its position in the
+// sequence that produced it.
+type Indexed[T any] struct {
+	Value T
+	// Index of the sequence that produced the value.
+	Source int
+	// Position of the value in its sequence, starting at zero.
+	Offset int64
+}
+
+// MergeIndexed merges sequences like Merge, and pairs each value with the
+// index of its sequence and its position in the sequence.
+//
+// The (source, offset) pairs uniquely identify the merged values, which allows
+// downstream writes to be idempotent: after a restart, a consumer can skip the
+// values it has already written instead of producing duplicates.
+//
+// See MergeIndexedFunc for a version of this function that allows the caller
+// to pass a custom comparison function.
+func MergeIndexed[T cmp.Ordered](seqs ...iter.Seq2[T, error]) iter.Seq2[Indexed[T], error] {
+	return MergeIndexedFunc(cmp.Compare[T], seqs...)
+}
+
+// MergeIndexedFunc is like MergeIndexed but uses the given comparison function
+// to determine the order of values.
+//
+// See MergeIndexed for more details.
+func MergeIndexedFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) iter.Seq2[Indexed[T], error] {
+	indexedSeqs := make([]iter.Seq2[Indexed[T], error], len(seqs))
+	for i, seq := range seqs {
+		indexedSeqs[i] = withOffsets(i, seq)
+	}
+	return MergeFunc(func(a, b Indexed[T]) int {
+		return cmp(a.Value, b.Value)
+	}, indexedSeqs...)
+}
+
+func withOffsets[T any](index int, seq iter.Seq2[T, error]) iter.Seq2[Indexed[T], error] {
+	return func(yield func(Indexed[T], error) bool) {
+		offset := int64(0)
+		for value, err := range seq {
+			if err != nil {
+				if !yield(Indexed[T]{Source: index, Offset: offset}, err) {
+					return
+				}
+				continue
+			}
+			if !yield(Indexed[T]{Value: value, Source: index, Offset: offset}, nil) {
+				return
+			}
+			offset++
+		}
+	}
+}
+
+// Tokened is a batch of values produced by a source along with a token that
+// the source captured for the batch, for example the offset of a record batch
+// in a log, or the continuation token of a page returned by a paging API.
+type Tokened[T, Token any] struct {
+	Values []T
+	Token  Token
+}
+
+// IndexedToken is a value yielded by MergeIndexedTokens, paired with its
+// position in its sequence and the token of the batch that contained it.
+type IndexedToken[T, Token any] struct {
+	Indexed[T]
+	Token Token
+}
+
+// MergeIndexedTokens is like MergeIndexedFunc but merges sequences of batches
+// carrying tokens, and pairs each value with the token of its batch in
+// addition to its position.
+//
+// Tokens let consumers map merged values back to the coordinates of their
+// source systems, for example to commit the offset of a record batch once all
+// its values were written.
+func MergeIndexedTokens[T, Token any](cmp func(T, T) int, seqs ...iter.Seq2[Tokened[T, Token], error]) iter.Seq2[IndexedToken[T, Token], error] {
+	indexedSeqs := make([]iter.Seq2[IndexedToken[T, Token], error], len(seqs))
+	for i, seq := range seqs {
+		indexedSeqs[i] = withTokens(i, seq)
+	}
+	return MergeFunc(func(a, b IndexedToken[T, Token]) int {
+		return cmp(a.Value, b.Value)
+	}, indexedSeqs...)
+}
+
+func withTokens[T, Token any](index int, seq iter.Seq2[Tokened[T, Token], error]) iter.Seq2[IndexedToken[T, Token], error] {
+	return func(yield func(IndexedToken[T, Token], error) bool) {
+		offset := int64(0)
+		for batch, err := range seq {
+			for _, value := range batch.Values {
+				v := IndexedToken[T, Token]{Token: batch.Token}
+				v.Value, v.Source, v.Offset = value, index, offset
+				if !yield(v, nil) {
+					return
+				}
+				offset++
+			}
+			if err != nil {
+				v := IndexedToken[T, Token]{Token: batch.Token}
+				v.Source, v.Offset = index, offset
+				if !yield(v, err) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergeIndexed(t *testing.T) {
+	got, err := values(MergeIndexed(seqOf(1, 4, 6), seqOf(2, 3), seqOf(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Indexed[int]{
+		{Value: 1, Source: 0, Offset: 0},
+		{Value: 2, Source: 1, Offset: 0},
+		{Value: 3, Source: 1, Offset: 1},
+		{Value: 4, Source: 0, Offset: 1},
+		{Value: 5, Source: 2, Offset: 0},
+		{Value: 6, Source: 0, Offset: 2},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMergeIndexedError(t *testing.T) {
+	errval := errors.New("")
+	failing := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(3, nil)
+	}
+
+	var got []Indexed[int]
+	var errs int
+	for v, err := range MergeIndexed(failing, seqOf(2)) {
+		if err != nil {
+			if !errors.Is(err, errval) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			errs++
+			continue
+		}
+		got = append(got, v)
+	}
+	want := []Indexed[int]{
+		{Value: 1, Source: 0, Offset: 0},
+		{Value: 2, Source: 1, Offset: 0},
+		{Value: 3, Source: 0, Offset: 1},
+	}
+	if errs != 1 || !slices.Equal(got, want) {
+		t.Errorf("expected %v and one error, got %v and %d errors", want, got, errs)
+	}
+}
+
+func TestMergeIndexedTokens(t *testing.T) {
+	pages := func(batches ...Tokened[int, string]) iter.Seq2[Tokened[int, string], error] {
+		return func(yield func(Tokened[int, string], error) bool) {
+			for _, batch := range batches {
+				if !yield(batch, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	got, err := values(MergeIndexedTokens(func(a, b int) int { return a - b },
+		pages(Tokened[int, string]{[]int{1, 3}, "a0"}, Tokened[int, string]{[]int{5}, "a1"}),
+		pages(Tokened[int, string]{[]int{2, 4}, "b0"}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		value  int
+		source int
+		offset int64
+		token  string
+	}
+	var results []result
+	for _, v := range got {
+		results = append(results, result{v.Value, v.Source, v.Offset, v.Token})
+	}
+	want := []result{
+		{1, 0, 0, "a0"},
+		{2, 1, 0, "b0"},
+		{3, 0, 1, "a0"},
+		{4, 1, 1, "b0"},
+		{5, 0, 2, "a1"},
+	}
+	if !slices.Equal(results, want) {
+		t.Errorf("expected %v, got %v", want, results)
+	}
+}
@@ -0,0 +1,65 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Interval represents the half-open range of values [Start, End).
+type Interval[T any] struct {
+	Start T
+	End   T
+}
+
+// MergeIntervals merges multiple sequences of intervals into one, coalescing
+// intervals that overlap or are adjacent. The sequences must produce intervals
+// ordered by their start value.
+//
+// The merged sequence yields intervals that are ordered and disjoint, for
+// example merging [0,2),[5,6) with [1,3),[3,4) yields [0,4),[5,6).
+//
+// See MergeIntervalsFunc for a version of this function that allows the caller
+// to pass a custom comparison function.
+func MergeIntervals[T cmp.Ordered](seqs ...iter.Seq2[Interval[T], error]) iter.Seq2[Interval[T], error] {
+	return MergeIntervalsFunc(cmp.Compare[T], seqs...)
+}
+
+// MergeIntervalsFunc merges multiple sequences of intervals using the given
+// comparison function to order the interval bounds.
+//
+// See MergeIntervals for more details.
+func MergeIntervalsFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[Interval[T], error]) iter.Seq2[Interval[T], error] {
+	merged := MergeFunc(func(a, b Interval[T]) int {
+		return cmp(a.Start, b.Start)
+	}, seqs...)
+
+	return func(yield func(Interval[T], error) bool) {
+		var current Interval[T]
+		var hasCurrent bool
+
+		for interval, err := range merged {
+			if err != nil {
+				if !yield(Interval[T]{}, err) {
+					return
+				}
+				continue
+			}
+
+			if hasCurrent && cmp(interval.Start, current.End) <= 0 {
+				if cmp(interval.End, current.End) > 0 {
+					current.End = interval.End
+				}
+				continue
+			}
+
+			if hasCurrent && !yield(current, nil) {
+				return
+			}
+			current, hasCurrent = interval, true
+		}
+
+		if hasCurrent {
+			yield(current, nil)
+		}
+	}
+}
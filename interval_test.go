@@ -0,0 +1,67 @@
+package kway
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergeIntervals(t *testing.T) {
+	type interval = Interval[int]
+
+	tests := []struct {
+		scenario  string
+		sequences [][]interval
+		want      []interval
+	}{
+		{
+			scenario:  "no sequences",
+			sequences: [][]interval{},
+		},
+
+		{
+			scenario: "disjoint intervals",
+			sequences: [][]interval{
+				{{0, 1}, {4, 5}},
+				{{2, 3}},
+			},
+			want: []interval{{0, 1}, {2, 3}, {4, 5}},
+		},
+
+		{
+			scenario: "overlapping intervals",
+			sequences: [][]interval{
+				{{0, 2}, {5, 6}},
+				{{1, 3}, {3, 4}},
+			},
+			want: []interval{{0, 4}, {5, 6}},
+		},
+
+		{
+			scenario: "nested intervals",
+			sequences: [][]interval{
+				{{0, 10}},
+				{{1, 2}, {3, 4}},
+				{{5, 6}, {11, 12}},
+			},
+			want: []interval{{0, 10}, {11, 12}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			seqs := make([]iter.Seq2[interval, error], len(test.sequences))
+			for i, seq := range test.sequences {
+				seqs[i] = seqOf(seq...)
+			}
+
+			got, err := values(MergeIntervals(seqs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package kway
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+)
+
+// KeyedBatch is a batch of values with the keys that the values are ordered by,
+// the key at index i is the key of the value at index i.
+type KeyedBatch[T, K any] struct {
+	Values []T
+	Keys   []K
+}
+
+// MergeKeyed merges sequences of batches of values carrying their keys,
+// ordering values by their keys, and yields the batches of merged values.
+//
+// While MergeSliceBy computes the keys of values in the merge, MergeKeyed lets
+// the sources supply them, which decouples expensive key derivations from the
+// merge: keys may be decoded from a column of the input files, or computed by
+// the producers of the values, possibly on other machines. Like MergeSliceBy,
+// batches which do not need to be interleaved with values of other sequences
+// are yielded as-is.
+//
+// A batch with a different number of keys and values is not merged, an error
+// is yielded in its place.
+//
+// See MergeSlice for more details.
+func MergeKeyed[T any, K cmp.Ordered](seqs ...iter.Seq2[KeyedBatch[T, K], error]) iter.Seq2[[]T, error] {
+	return MergeKeyedFunc(cmp.Compare[K], seqs...)
+}
+
+// MergeKeyedFunc is like MergeKeyed but uses the given comparison function to
+// determine the order of keys.
+//
+// See MergeKeyed for more details.
+func MergeKeyedFunc[T, K any](cmp func(K, K) int, seqs ...iter.Seq2[KeyedBatch[T, K], error]) iter.Seq2[[]T, error] {
+	if len(seqs) == 1 {
+		return keyedValues(seqs[0], new([]K))
+	}
+	return mergeSliceTree(func() tree[T, K] {
+		keys := make([][]K, len(seqs))
+		values := make([]iter.Seq2[[]T, error], len(seqs))
+		for i, seq := range seqs {
+			values[i] = keyedValues(seq, &keys[i])
+		}
+		t := makeKeyTree[T, K](nil, values...)
+		for i := range t.cursors {
+			t.cursors[i].presetKeys = &keys[i]
+		}
+		return t
+	}, cmp)
+}
+
+// keyedValues returns a sequence of the values of the batches of seq, storing
+// the keys of each batch in keys before yielding its values.
+func keyedValues[T, K any](seq iter.Seq2[KeyedBatch[T, K], error], keys *[]K) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for batch, err := range seq {
+			if len(batch.Keys) != len(batch.Values) {
+				*keys = nil
+				if !yield(nil, fmt.Errorf("batch of %d values has %d keys", len(batch.Values), len(batch.Keys))) {
+					return
+				}
+				if err == nil {
+					continue
+				}
+				batch = KeyedBatch[T, K]{}
+			}
+			*keys = batch.Keys
+			if !yield(batch.Values, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package kway
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func keyedBatches(batches ...KeyedBatch[string, int]) iter.Seq2[KeyedBatch[string, int], error] {
+	return func(yield func(KeyedBatch[string, int], error) bool) {
+		for _, batch := range batches {
+			if !yield(batch, nil) {
+				return
+			}
+		}
+	}
+}
+
+// keyedBatch returns a batch of values named after their keys.
+func keyedBatch(keys ...int) KeyedBatch[string, int] {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = "v" + strconv.Itoa(key)
+	}
+	return KeyedBatch[string, int]{Values: values, Keys: keys}
+}
+
+func TestMergeKeyed(t *testing.T) {
+	disjoint := keyedBatch(20, 21, 22)
+
+	var got []string
+	var passedThrough bool
+	for batch, err := range MergeKeyed(
+		keyedBatches(keyedBatch(1, 4, 7), disjoint),
+		keyedBatches(keyedBatch(2, 5), keyedBatch(8, 9)),
+		keyedBatches(keyedBatch(3, 6)),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch) > 0 && &batch[0] == &disjoint.Values[0] {
+			passedThrough = true
+		}
+		got = append(got, batch...)
+	}
+
+	want := []string{"v1", "v2", "v3", "v4", "v5", "v6", "v7", "v8", "v9", "v20", "v21", "v22"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !passedThrough {
+		t.Error("the disjoint batch was not passed through")
+	}
+}
+
+func TestMergeKeyedMismatch(t *testing.T) {
+	invalid := KeyedBatch[string, int]{Values: []string{"a", "b"}, Keys: []int{1}}
+
+	var got []string
+	errs := 0
+	for batch, err := range MergeKeyed(
+		keyedBatches(keyedBatch(1), invalid, keyedBatch(4)),
+		keyedBatches(keyedBatch(2, 3)),
+	) {
+		if err != nil {
+			errs++
+			continue
+		}
+		got = append(got, batch...)
+	}
+
+	if want := []string{"v1", "v2", "v3", "v4"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if errs != 1 {
+		t.Errorf("expected 1 error, got %d", errs)
+	}
+}
@@ -0,0 +1,131 @@
+// Package kwaybench provides generators of sequences with various distributions
+// of values, to benchmark and fuzz merges with inputs representative of the
+// data of applications.
+//
+// All generators produce ordered sequences of integers, except for NearlySorted
+// which is intended to exercise the handling of unordered inputs. Generators
+// using randomness are seeded, and produce the same values for the same seed.
+//
+// Merges are measured with Measure, and the results collected in a Report can
+// be written as JSON to track performance regressions across runs.
+package kwaybench
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+)
+
+// Count returns a sequence of the integers in [0, n).
+func Count(n int) iter.Seq2[int, error] {
+	return Sequence(0, n, 1)
+}
+
+// Sequence returns a sequence of the integers in [min, max) separated by step.
+func Sequence(min, max, step int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i := min; i < max; i += step {
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CountSlice returns a sequence of n slices of r consecutive integers, counting
+// from zero. The slice is reused across iterations.
+func CountSlice(n, r int) iter.Seq2[[]int, error] {
+	return func(yield func([]int, error) bool) {
+		values := make([]int, r)
+		for i := range n {
+			base := i * r
+			for j := range values {
+				values[j] = base + j
+			}
+			if !yield(values, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Duplicates returns a sequence of n integers where each value is repeated r
+// times, which is typical of merges of events keyed by coarse timestamps. Values
+// are not repeated when r is less than one.
+func Duplicates(n, r int) iter.Seq2[int, error] {
+	r = max(r, 1)
+	return func(yield func(int, error) bool) {
+		for i := range n {
+			if !yield(i/r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Zipfian returns an ordered sequence of n integers in [0, max] drawn from a
+// Zipf distribution with parameter s > 1, where small values are much more
+// frequent than large ones (e.g. popular keys of a cache or a database).
+//
+// The values are generated and sorted when the function is called.
+func Zipfian(seed uint64, n int, s float64, max uint64) iter.Seq2[int, error] {
+	r := rand.New(rand.NewPCG(seed, 0))
+	z := rand.NewZipf(r, s, 1, max)
+	values := make([]int, n)
+	for i := range values {
+		values[i] = int(z.Uint64())
+	}
+	slices.Sort(values)
+	return fromSlice(values)
+}
+
+// NearlySorted returns a sequence of the integers in [0, n) shuffled by swapping
+// values at most distance positions apart, similar to events received out of
+// order from a network.
+//
+// The sequence is not ordered, it is intended to exercise the handling of
+// unordered inputs.
+func NearlySorted(seed uint64, n, distance int) iter.Seq2[int, error] {
+	r := rand.New(rand.NewPCG(seed, 0))
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i
+	}
+	if distance > 0 {
+		for i := range values {
+			j := min(n-1, i+r.IntN(distance+1))
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	return fromSlice(values)
+}
+
+// Disjoint returns k sequences of n integers each, covering consecutive ranges
+// of values that do not overlap, like files partitioned by key ranges.
+func Disjoint(k, n int) []iter.Seq2[int, error] {
+	seqs := make([]iter.Seq2[int, error], k)
+	for i := range seqs {
+		seqs[i] = Sequence(i*n, (i+1)*n, 1)
+	}
+	return seqs
+}
+
+// Interleaved returns k sequences of n integers each, where consecutive values
+// are produced by different sequences, which is the worst case for merges.
+func Interleaved(k, n int) []iter.Seq2[int, error] {
+	seqs := make([]iter.Seq2[int, error], k)
+	for i := range seqs {
+		seqs[i] = Sequence(i, k*n, k)
+	}
+	return seqs
+}
+
+func fromSlice(values []int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for _, v := range values {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
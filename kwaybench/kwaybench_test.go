@@ -0,0 +1,137 @@
+package kwaybench_test
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"iter"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/achille-roussel/kway-go"
+	"github.com/achille-roussel/kway-go/kwaybench"
+)
+
+func collect(seq iter.Seq2[int, error]) []int {
+	var values []int
+	for v, err := range seq {
+		if err != nil {
+			panic(err)
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestGeneratorsAreOrdered(t *testing.T) {
+	tests := []struct {
+		scenario string
+		seq      iter.Seq2[int, error]
+		count    int
+	}{
+		{"count", kwaybench.Count(100), 100},
+		{"sequence", kwaybench.Sequence(10, 100, 3), 30},
+		{"duplicates", kwaybench.Duplicates(100, 10), 100},
+		{"duplicates zero", kwaybench.Duplicates(100, 0), 100},
+		{"zipfian", kwaybench.Zipfian(1, 100, 1.5, 1000), 100},
+		{"disjoint", kway.Merge(kwaybench.Disjoint(4, 25)...), 100},
+		{"interleaved", kway.Merge(kwaybench.Interleaved(4, 25)...), 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			values := collect(test.seq)
+			if len(values) != test.count {
+				t.Errorf("expected %d values, got %d", test.count, len(values))
+			}
+			if !slices.IsSorted(values) {
+				t.Error("values are not sorted")
+			}
+		})
+	}
+}
+
+func TestNearlySorted(t *testing.T) {
+	values := collect(kwaybench.NearlySorted(1, 100, 3))
+	if slices.IsSorted(values) {
+		t.Error("values are sorted")
+	}
+	if !slices.Equal(values, collect(kwaybench.NearlySorted(1, 100, 3))) {
+		t.Error("generator is not deterministic")
+	}
+	slices.Sort(values)
+	if !slices.Equal(values, collect(kwaybench.Count(100))) {
+		t.Error("generator did not produce a permutation of the integers")
+	}
+}
+
+func BenchmarkMergeZipfian(b *testing.B) {
+	seqs := make([]iter.Seq2[int, error], 4)
+	for i := range seqs {
+		seqs[i] = kwaybench.Zipfian(uint64(i), b.N/len(seqs)+1, 1.1, 1<<20)
+	}
+	b.ResetTimer()
+	for range kway.Merge(seqs...) {
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	r, err := kwaybench.Measure(cmp.Compare[int], func(cmp func(int, int) int) iter.Seq2[int, error] {
+		return kway.MergeFunc(cmp, kwaybench.Interleaved(4, 25)...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Values != 100 {
+		t.Errorf("expected 100 values, got %d", r.Values)
+	}
+	if r.Comparisons == 0 || r.ComparisonsPerValue() <= 0 {
+		t.Errorf("comparisons were not counted: %+v", r)
+	}
+	if r.Elapsed <= 0 || r.MergeRate() <= 0 {
+		t.Errorf("elapsed time was not measured: %+v", r)
+	}
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	r := kwaybench.Result{
+		Strategy:    "merge",
+		Sources:     4,
+		Values:      1000,
+		Elapsed:     time.Millisecond,
+		Comparisons: 2000,
+		Allocs:      10,
+	}
+
+	var buf bytes.Buffer
+	if err := kwaybench.NewReport(r).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var report map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	results := report["results"].([]any)
+	got := results[0].(map[string]any)
+	want := map[string]any{
+		"strategy":            "merge",
+		"sources":             4.0,
+		"values":              1000.0,
+		"elapsedNanoseconds":  1e6,
+		"comparisons":         2000.0,
+		"allocs":              10.0,
+		"mergesPerSecond":     1e6,
+		"comparisonsPerValue": 2.0,
+		"allocsPerValue":      0.01,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result:\ngot:  %v\nwant: %v", got, want)
+	}
+	if report["goVersion"] != runtime.Version() {
+		t.Errorf("unexpected Go version: %v", report["goVersion"])
+	}
+}
@@ -0,0 +1,126 @@
+package kwaybench
+
+import (
+	"encoding/json"
+	"io"
+	"iter"
+	"runtime"
+	"time"
+)
+
+// Result is the measurement of a merge. Results are serialized to JSON by
+// WriteJSON, so the performance of merges can be tracked across runs (e.g. by
+// comparing the results of continuous integration jobs).
+type Result struct {
+	// Name of the merge strategy, and size of the batches that it merged, or
+	// zero if it merged single values.
+	Strategy string `json:"strategy"`
+	Batch    int    `json:"batch,omitempty"`
+	// Number of sources merged, results of the same strategy with different
+	// numbers of sources show how the merge scales with k.
+	Sources int `json:"sources"`
+	// Number of values produced by the merge, and time that it took.
+	Values  int64         `json:"values"`
+	Elapsed time.Duration `json:"elapsedNanoseconds"`
+	// Number of comparisons and heap allocations performed by the merge.
+	Comparisons int64  `json:"comparisons"`
+	Allocs      uint64 `json:"allocs"`
+}
+
+// MergeRate returns the number of values merged per second.
+func (r Result) MergeRate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Values) / r.Elapsed.Seconds()
+}
+
+// ComparisonsPerValue returns the average number of comparisons performed to
+// merge each value.
+func (r Result) ComparisonsPerValue() float64 {
+	return float64(r.Comparisons) / float64(max(r.Values, 1))
+}
+
+// AllocsPerValue returns the average number of heap allocations performed to
+// merge each value.
+func (r Result) AllocsPerValue() float64 {
+	return float64(r.Allocs) / float64(max(r.Values, 1))
+}
+
+// MarshalJSON satisfies json.Marshaler, the output includes the metrics derived
+// from the measurements so consumers of the results don't have to compute them.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		MergeRate           float64 `json:"mergesPerSecond"`
+		ComparisonsPerValue float64 `json:"comparisonsPerValue"`
+		AllocsPerValue      float64 `json:"allocsPerValue"`
+	}{
+		result:              result(r),
+		MergeRate:           r.MergeRate(),
+		ComparisonsPerValue: r.ComparisonsPerValue(),
+		AllocsPerValue:      r.AllocsPerValue(),
+	})
+}
+
+// Measure runs a merge and returns its measurement. The merge function receives
+// a comparison function wrapping cmp to count comparisons, and returns the
+// merged sequence, which is fully consumed. The Strategy, Batch and Sources
+// fields of the result are left for the caller to set.
+//
+// The first error produced by the merge is returned with the partial result.
+func Measure[T any](cmp func(T, T) int, merge func(cmp func(T, T) int) iter.Seq2[T, error]) (Result, error) {
+	var r Result
+	compare := func(a, b T) int {
+		r.Comparisons++
+		return cmp(a, b)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	var err error
+	for _, err = range merge(compare) {
+		if err != nil {
+			break
+		}
+		r.Values++
+	}
+
+	r.Elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	r.Allocs = after.Mallocs - before.Mallocs
+	return r, err
+}
+
+// Report is a set of results, with a description of the environment that they
+// were measured in.
+type Report struct {
+	GoVersion string   `json:"goVersion"`
+	GOOS      string   `json:"goos"`
+	GOARCH    string   `json:"goarch"`
+	CPUs      int      `json:"cpus"`
+	Results   []Result `json:"results"`
+}
+
+// NewReport returns a report of the results measured in the current
+// environment.
+func NewReport(results ...Result) *Report {
+	return &Report{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Results:   results,
+	}
+}
+
+// WriteJSON writes the report to w as an indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
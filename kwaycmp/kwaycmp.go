@@ -0,0 +1,131 @@
+// Package kwaycmp provides comparison functions commonly needed when merging
+// sequences with the kway package.
+//
+// Merging sequences requires that the comparison function used by the merge is
+// the same as the one used to order each sequence, and that it implements a
+// strict weak ordering. Writing those functions by hand is a frequent source
+// of bugs, the functions of this package are intended to be used as building
+// blocks instead.
+package kwaycmp
+
+import (
+	"cmp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// By returns a comparison function ordering values by the key returned by the
+// given function.
+func By[T any, K cmp.Ordered](key func(T) K) func(T, T) int {
+	return func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	}
+}
+
+// ByFunc is like By but compares the keys with the given comparison function,
+// for example to order values by a string field ignoring case:
+//
+//	compare := kwaycmp.ByFunc(func(u User) string { return u.Name }, kwaycmp.Fold)
+func ByFunc[T, K any](key func(T) K, cmp func(K, K) int) func(T, T) int {
+	return func(a, b T) int {
+		return cmp(key(a), key(b))
+	}
+}
+
+// Reverse returns a comparison function which orders values in the reverse
+// order of the given comparison function.
+func Reverse[T any](cmp func(T, T) int) func(T, T) int {
+	return func(a, b T) int {
+		return cmp(b, a)
+	}
+}
+
+// Chain returns a comparison function which orders values by the first of the
+// comparison functions that reports them as different. It is typically used to
+// order structs by multiple fields, each in ascending or descending order:
+//
+//	compare := kwaycmp.Chain(
+//		kwaycmp.By(func(e Event) string { return e.Source }),
+//		kwaycmp.Reverse(kwaycmp.By(func(e Event) int64 { return e.Time })),
+//	)
+func Chain[T any](cmps ...func(T, T) int) func(T, T) int {
+	return func(a, b T) int {
+		for _, cmp := range cmps {
+			if c := cmp(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+}
+
+// Fold compares strings ignoring case, using simple Unicode case folding.
+//
+// Strings that only differ by case compare equal, which means that merging
+// sequences with this function yields them in an unspecified order.
+func Fold(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if c := cmp.Compare(fold(ra), fold(rb)); c != 0 {
+			return c
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func fold(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+// Natural compares strings in natural order, where sequences of decimal digits
+// are compared by their numeric values, for example "file2" sorts before
+// "file10".
+//
+// Numbers of arbitrary length are supported. When strings are equal under the
+// natural order but differ in their representation (e.g. "a01" and "a1"), they
+// are ordered by byte-wise comparison, so the function remains a total order.
+func Natural(a, b string) int {
+	x, y := a, b
+	for x != "" && y != "" {
+		if isDigit(x[0]) && isDigit(y[0]) {
+			nx, ny := digits(x), digits(y)
+			if c := compareNumbers(x[:nx], y[:ny]); c != 0 {
+				return c
+			}
+			x, y = x[nx:], y[ny:]
+			continue
+		}
+		if c := cmp.Compare(x[0], y[0]); c != 0 {
+			return c
+		}
+		x, y = x[1:], y[1:]
+	}
+	if c := cmp.Compare(len(x), len(y)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digits(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if c := cmp.Compare(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
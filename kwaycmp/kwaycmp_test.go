@@ -0,0 +1,71 @@
+package kwaycmp_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/achille-roussel/kway-go/kwaycmp"
+)
+
+func TestNatural(t *testing.T) {
+	values := []string{"file10", "file2", "file1", "file02", "file", "file1a", "a100b", "a99c"}
+	slices.SortFunc(values, kwaycmp.Natural)
+
+	want := []string{"a99c", "a100b", "file", "file1", "file1a", "file02", "file2", "file10"}
+	if !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "ABC", 0},
+		{"abc", "ABD", -1},
+		{"Straße", "STRASSE", 1},
+		{"ab", "A", 1},
+	}
+	for _, test := range tests {
+		if got := kwaycmp.Fold(test.a, test.b); got != test.want {
+			t.Errorf("Fold(%q, %q): expected %d, got %d", test.a, test.b, test.want, got)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	type person struct {
+		name string
+		age  int
+	}
+
+	people := []person{{"b", 30}, {"a", 30}, {"c", 20}}
+	slices.SortFunc(people, kwaycmp.Chain(
+		kwaycmp.Reverse(kwaycmp.By(func(p person) int { return p.age })),
+		kwaycmp.By(func(p person) string { return p.name }),
+	))
+
+	want := []person{{"a", 30}, {"b", 30}, {"c", 20}}
+	if !slices.Equal(people, want) {
+		t.Errorf("expected %v, got %v", want, people)
+	}
+}
+
+func TestByFunc(t *testing.T) {
+	values := []string{"b", "C", "a"}
+	slices.SortFunc(values, kwaycmp.ByFunc(func(s string) string { return s }, kwaycmp.Fold))
+	if want := []string{"a", "b", "C"}; !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	values := []string{"a", "c", "b"}
+	slices.SortFunc(values, kwaycmp.Reverse(strings.Compare))
+	if want := []string{"c", "b", "a"}; !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+}
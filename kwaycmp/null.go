@@ -0,0 +1,57 @@
+package kwaycmp
+
+// NullOrder determines where null values sort relative to non-null values.
+type NullOrder int
+
+const (
+	// NullsFirst orders null values before all other values.
+	NullsFirst NullOrder = iota
+	// NullsLast orders null values after all other values.
+	NullsLast
+)
+
+func (n NullOrder) compare(aValid, bValid bool) int {
+	switch {
+	case aValid == bValid:
+		return 0
+	case aValid == (n == NullsFirst):
+		return +1
+	default:
+		return -1
+	}
+}
+
+// Ptr returns a comparison function for pointers, where nil pointers are
+// ordered according to nulls and non-nil pointers are ordered by comparing the
+// values they point to with cmp.
+//
+// Nil pointers all compare equal to each other.
+func Ptr[T any](cmp func(T, T) int, nulls NullOrder) func(*T, *T) int {
+	return func(a, b *T) int {
+		if a == nil || b == nil {
+			return nulls.compare(a != nil, b != nil)
+		}
+		return cmp(*a, *b)
+	}
+}
+
+// Optional returns a comparison function for option-style values such as
+// sql.Null[T] or sql.NullString. The get function returns the underlying value
+// and whether it is valid (non-null). Null values are ordered according to
+// nulls, and valid values are ordered with cmp.
+//
+// For example, merging query results ordered by a nullable column:
+//
+//	compare := kwaycmp.Optional(func(v sql.NullInt64) (int64, bool) {
+//		return v.Int64, v.Valid
+//	}, cmp.Compare[int64], kwaycmp.NullsLast)
+func Optional[O, T any](get func(O) (T, bool), cmp func(T, T) int, nulls NullOrder) func(O, O) int {
+	return func(a, b O) int {
+		va, aValid := get(a)
+		vb, bValid := get(b)
+		if !aValid || !bValid {
+			return nulls.compare(aValid, bValid)
+		}
+		return cmp(va, vb)
+	}
+}
@@ -0,0 +1,57 @@
+package kwaycmp_test
+
+import (
+	"cmp"
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/achille-roussel/kway-go/kwaycmp"
+)
+
+func ptr(v int) *int { return &v }
+
+func derefs(values []*int) []int {
+	out := make([]int, len(values))
+	for i, v := range values {
+		if v == nil {
+			out[i] = -1
+		} else {
+			out[i] = *v
+		}
+	}
+	return out
+}
+
+func TestPtr(t *testing.T) {
+	tests := []struct {
+		nulls kwaycmp.NullOrder
+		want  []int
+	}{
+		{kwaycmp.NullsFirst, []int{-1, -1, 1, 2, 3}},
+		{kwaycmp.NullsLast, []int{1, 2, 3, -1, -1}},
+	}
+	for _, test := range tests {
+		values := []*int{ptr(3), nil, ptr(1), nil, ptr(2)}
+		slices.SortFunc(values, kwaycmp.Ptr(cmp.Compare[int], test.nulls))
+		if got := derefs(values); !slices.Equal(got, test.want) {
+			t.Errorf("expected %v, got %v", test.want, got)
+		}
+	}
+}
+
+func TestOptional(t *testing.T) {
+	values := []sql.Null[string]{
+		{V: "b", Valid: true},
+		{},
+		{V: "a", Valid: true},
+	}
+	slices.SortFunc(values, kwaycmp.Optional(func(v sql.Null[string]) (string, bool) {
+		return v.V, v.Valid
+	}, cmp.Compare[string], kwaycmp.NullsLast))
+
+	want := []sql.Null[string]{{V: "a", Valid: true}, {V: "b", Valid: true}, {}}
+	if !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+}
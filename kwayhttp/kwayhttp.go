@@ -0,0 +1,94 @@
+// Package kwayhttp serves merged sequences over HTTP, which is the building
+// block of endpoints answering sorted queries by merging the results of
+// multiple backends.
+package kwayhttp
+
+import (
+	"iter"
+	"net/http"
+
+	"github.com/achille-roussel/kway-go"
+)
+
+// Handler is an http.Handler streaming the values of a merge to clients.
+//
+// The response is written with chunked transfer encoding, and flushed after
+// each batch of values, so clients start receiving values as soon as they are
+// merged. When the client disconnects, the handler stops consuming the merged
+// sequence; sources reading from remote backends should use the request
+// context to abort their reads as well.
+//
+// If the merge produces an error before any values were written, the handler
+// responds with a 500 status code and the error message. Errors occurring after
+// the response has started abort the response, so clients do not mistake a
+// truncated response for a complete one.
+type Handler[T any] struct {
+	// Merge returns the merged sequence to serve for the request, typically
+	// constructed with kway.MergeSlice.
+	Merge func(*http.Request) iter.Seq2[[]T, error]
+	// Encoder used to write values to the response, values are written as
+	// newline-delimited JSON if nil.
+	Encoder kway.Encoder[T]
+	// Content type of the response, defaults to "application/x-ndjson" when
+	// Encoder is nil.
+	ContentType string
+}
+
+// ServeHTTP satisfies the http.Handler interface.
+func (h *Handler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	enc, contentType := h.Encoder, h.ContentType
+	if enc == nil {
+		enc = NDJSON[T]()
+		if contentType == "" {
+			contentType = "application/x-ndjson"
+		}
+	}
+
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	started := false
+	start := func() {
+		started = true
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	var buf []byte
+
+	for values, err := range h.Merge(r) {
+		if ctx.Err() != nil {
+			return
+		}
+		if len(values) > 0 {
+			buf, err = enc.Encode(buf[:0], values)
+		}
+		if err != nil {
+			if !started {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			panic(http.ErrAbortHandler)
+		}
+		if len(buf) == 0 {
+			continue
+		}
+		if !started {
+			start()
+		}
+		if _, err := w.Write(buf); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil && err != http.ErrNotSupported {
+			return
+		}
+	}
+
+	if !started {
+		start()
+	}
+}
+
+// NDJSON returns an encoder writing values as newline-delimited JSON, see
+// kway.NDJSONEncoder.
+func NDJSON[T any]() kway.Encoder[T] { return kway.NDJSONEncoder[T]() }
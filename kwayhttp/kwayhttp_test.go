@@ -0,0 +1,97 @@
+package kwayhttp_test
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/achille-roussel/kway-go"
+	"github.com/achille-roussel/kway-go/kwayhttp"
+)
+
+func slices(batches ...[]int) iter.Seq2[[]int, error] {
+	return func(yield func([]int, error) bool) {
+		for _, batch := range batches {
+			if !yield(batch, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(&kwayhttp.Handler[int]{
+		Merge: func(*http.Request) iter.Seq2[[]int, error] {
+			return kway.MergeSlice(slices([]int{1, 3}), slices([]int{2, 4}))
+		},
+	})
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "1\n2\n3\n4\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("unexpected content type: %q", contentType)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	errval := errors.New("backend unavailable")
+
+	failing := func(yield func([]int, error) bool) {
+		yield(nil, errval)
+	}
+
+	server := httptest.NewServer(&kwayhttp.Handler[int]{
+		Merge: func(*http.Request) iter.Seq2[[]int, error] {
+			return failing
+		},
+	})
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", res.StatusCode)
+	}
+}
+
+func TestHandlerAbortsAfterStart(t *testing.T) {
+	failing := func(yield func([]int, error) bool) {
+		_ = yield([]int{1, 2}, nil) && yield(nil, errors.New("lost connection"))
+	}
+
+	server := httptest.NewServer(&kwayhttp.Handler[int]{
+		Merge: func(*http.Request) iter.Seq2[[]int, error] {
+			return failing
+		},
+	})
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if _, err := io.ReadAll(res.Body); err == nil {
+		t.Error("expected reading the truncated response to fail")
+	}
+}
@@ -0,0 +1,118 @@
+// Package kwaykafka adapts per-partition Kafka consumers into ordered sequences
+// that can be merged with the kway package.
+//
+// The package does not depend on a specific Kafka client, applications wrap
+// their consumers in the Fetcher interface, which is usually a few lines of
+// code with any of the popular client libraries.
+package kwaykafka
+
+import (
+	"cmp"
+	"errors"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/achille-roussel/kway-go"
+)
+
+// Record is a Kafka record read from a partition.
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Timestamp time.Time
+}
+
+// Fetcher is the interface implemented by per-partition consumers.
+//
+// Fetch blocks until records are available and returns them in offset order.
+// When the partition has no more records to produce (e.g. when replaying a
+// bounded range of offsets), Fetch returns io.EOF.
+type Fetcher interface {
+	Fetch() ([]Record, error)
+}
+
+// FetcherFunc is an adapter to use ordinary functions as Fetcher.
+type FetcherFunc func() ([]Record, error)
+
+// Fetch calls f.
+func (f FetcherFunc) Fetch() ([]Record, error) { return f() }
+
+// Batches returns a sequence yielding the batches of records returned by the
+// fetcher. The sequence ends when the fetcher returns io.EOF, other errors are
+// yielded to the application, which may carry on to fetch more records.
+//
+// The sequence is intended to be used with kway.MergeSliceFunc, and does not
+// copy the slices returned by the fetcher.
+func Batches(f Fetcher) iter.Seq2[[]Record, error] {
+	return func(yield func([]Record, error) bool) {
+		for {
+			records, err := f.Fetch()
+			if len(records) > 0 && !yield(records, nil) {
+				return
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) || !yield(nil, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Records returns a sequence yielding the records returned by the fetcher one
+// at a time.
+//
+// See Batches for more details.
+func Records(f Fetcher) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		for records, err := range Batches(f) {
+			if err != nil {
+				if !yield(Record{}, err) {
+					return
+				}
+				continue
+			}
+			for _, r := range records {
+				if !yield(r, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ByTimestamp compares records by timestamp. Records with equal timestamps are
+// ordered by partition then offset, so the order of merged records is
+// deterministic.
+//
+// Merging by timestamp requires that timestamps never decrease within a
+// partition, which is guaranteed when the topic uses log append time.
+func ByTimestamp(a, b Record) int {
+	if c := a.Timestamp.Compare(b.Timestamp); c != 0 {
+		return c
+	}
+	return ByOffset(a, b)
+}
+
+// ByOffset compares records by partition then offset.
+func ByOffset(a, b Record) int {
+	if c := cmp.Compare(a.Partition, b.Partition); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Offset, b.Offset)
+}
+
+// Merge merges the records read from the fetchers in timestamp order.
+//
+// See ByTimestamp for the ordering requirements.
+func Merge(fetchers ...Fetcher) iter.Seq2[Record, error] {
+	seqs := make([]iter.Seq2[Record, error], len(fetchers))
+	for i, f := range fetchers {
+		seqs[i] = Records(f)
+	}
+	return kway.MergeFunc(ByTimestamp, seqs...)
+}
@@ -0,0 +1,85 @@
+package kwaykafka_test
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/achille-roussel/kway-go/kwaykafka"
+)
+
+func partition(id int32, batches ...[]int64) kwaykafka.Fetcher {
+	offset := int64(0)
+	return kwaykafka.FetcherFunc(func() ([]kwaykafka.Record, error) {
+		if len(batches) == 0 {
+			return nil, io.EOF
+		}
+		records := make([]kwaykafka.Record, len(batches[0]))
+		for i, ts := range batches[0] {
+			records[i] = kwaykafka.Record{
+				Partition: id,
+				Offset:    offset,
+				Timestamp: time.Unix(ts, 0),
+			}
+			offset++
+		}
+		batches = batches[1:]
+		return records, nil
+	})
+}
+
+func TestMerge(t *testing.T) {
+	type position struct {
+		partition int32
+		offset    int64
+	}
+
+	var got []position
+	for r, err := range kwaykafka.Merge(
+		partition(0, []int64{1, 3}, []int64{5}),
+		partition(1, []int64{2, 3, 4}),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, position{r.Partition, r.Offset})
+	}
+
+	want := []position{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {1, 2}, {0, 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBatchesError(t *testing.T) {
+	errval := errors.New("")
+	calls := 0
+	f := kwaykafka.FetcherFunc(func() ([]kwaykafka.Record, error) {
+		switch calls++; calls {
+		case 1:
+			return nil, errval
+		case 2:
+			return []kwaykafka.Record{{Offset: 42}}, nil
+		default:
+			return nil, io.EOF
+		}
+	})
+
+	var records []kwaykafka.Record
+	var errs []error
+	for batch, err := range kwaykafka.Batches(f) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			records = append(records, batch...)
+		}
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+	if len(records) != 1 || records[0].Offset != 42 {
+		t.Errorf("expected one record at offset 42, got %v", records)
+	}
+}
@@ -0,0 +1,325 @@
+// Package kwaynet runs merges across processes and machines: workers serve
+// their local merged sequences over stream connections (TCP or unix sockets),
+// and coordinators merge the sequences of multiple workers.
+//
+// The protocol is a stream of length-prefixed frames. The worker first writes
+// a header made of the bytes "KWAY" followed by the protocol version, then one
+// frame per batch of values or error produced by its merge, and terminates the
+// stream with an end frame:
+//
+//	header = "KWAY" version:byte
+//	batch  = 'B' count:uvarint size:uvarint values:[size]byte
+//	error  = 'E' size:uvarint message:[size]byte
+//	end    = 'Z'
+//
+// The values of each batch are encoded with a kway.Codec, as an independent
+// stream of the codec. Errors are not fatal, the worker carries on with its
+// merge after reporting them, like the merge functions of the kway package.
+// A stream which ends without an end frame was truncated, which coordinators
+// report as io.ErrUnexpectedEOF.
+package kwaynet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net"
+	"sync"
+
+	"github.com/achille-roussel/kway-go"
+)
+
+const (
+	magic   = "KWAY"
+	version = 1
+
+	frameBatch = 'B'
+	frameError = 'E'
+	frameEnd   = 'Z'
+)
+
+// MaxFrameSize is the maximum size of frames accepted by coordinators, which
+// protects them from allocating unbounded buffers when reading from a peer
+// which does not implement the protocol.
+const MaxFrameSize = 64 << 20
+
+// RemoteError is the error yielded by coordinators when a worker reported an
+// error in its merge.
+type RemoteError struct {
+	// Address of the worker that reported the error.
+	Addr string
+	// Message of the error reported by the worker.
+	Message string
+}
+
+// Error satisfies the error interface.
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("kwaynet: %s: %s", e.Addr, e.Message)
+}
+
+// Write writes the batches of values and errors yielded by seq to w, framed
+// by the protocol of the package. The function returns when seq is exhausted
+// and the end frame was written, or when writing to w fails.
+func Write[T any](w io.Writer, seq iter.Seq2[[]T, error], codec kway.Codec[T]) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(magic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(version); err != nil {
+		return err
+	}
+
+	var buf, frame []byte
+	for values, err := range seq {
+		if len(values) > 0 {
+			var encodeErr error
+			buf, encodeErr = codec.Encoder().Encode(buf[:0], values)
+			if encodeErr != nil {
+				err = errors.Join(encodeErr, err)
+			} else {
+				frame = append(frame[:0], frameBatch)
+				frame = binary.AppendUvarint(frame, uint64(len(values)))
+				frame = binary.AppendUvarint(frame, uint64(len(buf)))
+				if err := writeFrame(bw, frame, buf); err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			msg := err.Error()
+			frame = append(frame[:0], frameError)
+			frame = binary.AppendUvarint(frame, uint64(len(msg)))
+			if err := writeFrame(bw, frame, []byte(msg)); err != nil {
+				return err
+			}
+		}
+		// Flush after each batch so the coordinator can make progress while
+		// the worker waits on its sources.
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if err := bw.WriteByte(frameEnd); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeFrame(w *bufio.Writer, header, payload []byte) error {
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// Read returns a sequence of the batches of values and errors read from r,
+// which must produce a stream written by Write. The addr argument identifies
+// the peer in the errors that it reported, see RemoteError.
+//
+// Errors reading or decoding the stream are yielded and end the sequence. The
+// batches are reused across iterations, the application must not retain them
+// beyond the body of the loop ranging over the sequence.
+func Read[T any](r io.Reader, addr string, codec kway.Codec[T]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		br := bufio.NewReader(r)
+
+		var header [len(magic) + 1]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			yield(nil, unexpectedEOF(err))
+			return
+		}
+		if string(header[:len(magic)]) != magic {
+			yield(nil, fmt.Errorf("kwaynet: %s: invalid protocol header", addr))
+			return
+		}
+		if header[len(magic)] != version {
+			yield(nil, fmt.Errorf("kwaynet: %s: unsupported protocol version %d", addr, header[len(magic)]))
+			return
+		}
+
+		var payload []byte
+		var values []T
+		for {
+			kind, err := br.ReadByte()
+			if err != nil {
+				yield(nil, unexpectedEOF(err))
+				return
+			}
+
+			switch kind {
+			case frameEnd:
+				return
+
+			case frameBatch:
+				count, err := readSize(br)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if payload, err = readPayload(br, payload); err != nil {
+					yield(nil, err)
+					return
+				}
+				values = values[:0]
+				for v, err := range codec.Decode(bytes.NewReader(payload)) {
+					if err != nil {
+						yield(nil, err)
+						return
+					}
+					values = append(values, v)
+				}
+				if uint64(len(values)) != count {
+					yield(nil, fmt.Errorf("kwaynet: %s: batch of %d values decoded as %d values", addr, count, len(values)))
+					return
+				}
+				if !yield(values, nil) {
+					return
+				}
+
+			case frameError:
+				if payload, err = readPayload(br, payload); err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(nil, &RemoteError{Addr: addr, Message: string(payload)}) {
+					return
+				}
+
+			default:
+				yield(nil, fmt.Errorf("kwaynet: %s: invalid frame type %q", addr, kind))
+				return
+			}
+		}
+	}
+}
+
+func readSize(r *bufio.Reader) (uint64, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if n > MaxFrameSize {
+		return 0, fmt.Errorf("kwaynet: frame of %d bytes exceeds the maximum size", n)
+	}
+	return n, nil
+}
+
+func readPayload(r *bufio.Reader, buf []byte) ([]byte, error) {
+	n, err := readSize(r)
+	if err != nil {
+		return buf, err
+	}
+	if uint64(cap(buf)) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return buf, unexpectedEOF(err)
+	}
+	return buf, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Worker serves merged sequences to coordinators.
+type Worker[T any] struct {
+	// Merge returns the sequence served on a new connection, typically the
+	// merge of the local sources of the worker constructed with
+	// kway.MergeSlice. It is called once per connection.
+	Merge func(ctx context.Context) iter.Seq2[[]T, error]
+	// Codec encoding the values of the sequence.
+	Codec kway.Codec[T]
+}
+
+// Serve accepts connections on l and serves the sequence returned by Merge on
+// each of them, until l is closed or ctx is canceled. The merges of open
+// connections are canceled when Serve returns.
+func (w *Worker[T]) Serve(ctx context.Context, l net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.ServeConn(ctx, conn)
+		}()
+	}
+}
+
+// ServeConn serves the sequence returned by Merge on conn, and closes it. The
+// merge is canceled if ctx is canceled or if the coordinator disconnects.
+func (w *Worker[T]) ServeConn(ctx context.Context, conn net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+
+	// Interrupt writes blocked on the connection when the context is canceled.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	return Write(conn, w.Merge(ctx), w.Codec)
+}
+
+// Dial returns a sequence of the batches of values served by the worker at
+// the given network address. The connection is established when iteration
+// begins, and closed when it ends or when ctx is canceled.
+func Dial[T any](ctx context.Context, network, addr string, codec kway.Codec[T]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer conn.Close()
+
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+
+		for values, err := range Read(conn, addr, codec) {
+			if err != nil && ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			if !yield(values, err) {
+				return
+			}
+		}
+	}
+}
+
+// Merge is the coordinator of a distributed merge: it merges the sequences
+// served by the workers at the given addresses, using the comparison function
+// to determine the order of values.
+func Merge[T any](ctx context.Context, cmp func(T, T) int, codec kway.Codec[T], network string, addrs ...string) iter.Seq2[[]T, error] {
+	seqs := make([]iter.Seq2[[]T, error], len(addrs))
+	for i, addr := range addrs {
+		seqs[i] = Dial(ctx, network, addr, codec)
+	}
+	return kway.MergeSliceFunc(cmp, seqs...)
+}
@@ -0,0 +1,136 @@
+package kwaynet_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/achille-roussel/kway-go"
+	"github.com/achille-roussel/kway-go/kwaynet"
+)
+
+func batches(batches ...[]int) iter.Seq2[[]int, error] {
+	return func(yield func([]int, error) bool) {
+		for _, batch := range batches {
+			if !yield(batch, nil) {
+				return
+			}
+		}
+	}
+}
+
+func collect(t *testing.T, seq iter.Seq2[[]int, error]) ([]int, []error) {
+	t.Helper()
+	var values []int
+	var errs []error
+	for batch, err := range seq {
+		values = append(values, batch...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return values, errs
+}
+
+func TestWriteRead(t *testing.T) {
+	errval := errors.New("source unavailable")
+	seq := func(yield func([]int, error) bool) {
+		_ = yield([]int{1, 2}, nil) && yield([]int{3}, errval) && yield([]int{4, 5, 6}, nil)
+	}
+
+	var buf bytes.Buffer
+	if err := kwaynet.Write(&buf, seq, kway.JSONLinesCodec[int]()); err != nil {
+		t.Fatal(err)
+	}
+
+	values, errs := collect(t, kwaynet.Read(&buf, "worker", kway.JSONLinesCodec[int]()))
+	if want := []int{1, 2, 3, 4, 5, 6}; !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+	var remote *kwaynet.RemoteError
+	if len(errs) != 1 || !errors.As(errs[0], &remote) || remote.Message != errval.Error() || remote.Addr != "worker" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := kwaynet.Write(&buf, batches([]int{1, 2}, []int{3}), kway.GobCodec[int]()); err != nil {
+		t.Fatal(err)
+	}
+	truncated := buf.Bytes()[:buf.Len()-1]
+
+	values, errs := collect(t, kwaynet.Read(bytes.NewReader(truncated), "worker", kway.GobCodec[int]()))
+	if want := []int{1, 2, 3}; !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", errs)
+	}
+}
+
+func TestReadInvalidHeader(t *testing.T) {
+	_, errs := collect(t, kwaynet.Read(bytes.NewReader([]byte("HTTP/1.1 200 OK\r\n")), "worker", kway.GobCodec[int]()))
+	if len(errs) != 1 {
+		t.Errorf("expected an error, got %v", errs)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	workers := [][]iter.Seq2[[]int, error]{
+		{batches([]int{0, 3}, []int{6, 9}), batches([]int{1, 4})},
+		{batches([]int{2, 5, 7, 8})},
+	}
+
+	addrs := make([]string, len(workers))
+	for i, sources := range workers {
+		addrs[i] = filepath.Join(dir, "worker"+string(rune('0'+i))+".sock")
+		l, err := net.Listen("unix", addrs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := &kwaynet.Worker[int]{
+			Merge: func(context.Context) iter.Seq2[[]int, error] {
+				return kway.MergeSlice(sources...)
+			},
+			Codec: kway.GobCodec[int](),
+		}
+		done := make(chan error, 1)
+		go func() { done <- w.Serve(ctx, l) }()
+		defer func() {
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Errorf("unexpected error returned by Serve: %v", err)
+			}
+		}()
+	}
+
+	// Each iteration establishes new connections to the workers.
+	for range 2 {
+		values, errs := collect(t, kwaynet.Merge(ctx, func(a, b int) int { return a - b }, kway.GobCodec[int](), "unix", addrs...))
+		if len(errs) != 0 {
+			t.Fatal(errs)
+		}
+		if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(values, want) {
+			t.Errorf("expected %v, got %v", want, values)
+		}
+	}
+}
+
+func TestDialError(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "missing.sock")
+	_, errs := collect(t, kwaynet.Dial(context.Background(), "unix", addr, kway.GobCodec[int]()))
+	if len(errs) != 1 {
+		t.Errorf("expected a dial error, got %v", errs)
+	}
+}
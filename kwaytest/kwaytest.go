@@ -0,0 +1,78 @@
+// Package kwaytest provides wrappers of sequences injecting failures and
+// latency, which applications can use to test how their merges behave when
+// sources misbehave (e.g. error handling, timeouts, and concurrency options).
+//
+// The fault patterns are deterministic, so tests using them are reproducible.
+package kwaytest
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrInjected is the error yielded by sequences wrapped with Flaky.
+var ErrInjected = errors.New("kwaytest: injected failure")
+
+// Flaky returns a sequence yielding the values of seq, with an ErrInjected
+// error yielded before every errEvery values. No values are lost, which
+// simulates sources encountering transient errors that succeed on retry.
+func Flaky[T any](seq iter.Seq2[T, error], errEvery int) iter.Seq2[T, error] {
+	if errEvery <= 0 {
+		panic("kwaytest: error interval must be positive")
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		count := 0
+		for v, err := range seq {
+			if count++; count%errEvery == 0 && !yield(zero, ErrInjected) {
+				return
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// Slow returns a sequence yielding the values of seq, sleeping for up to jitter
+// before each value.
+//
+// The sleep durations are pseudo-random, but follow the same pattern for every
+// iteration of the sequence.
+func Slow[T any](seq iter.Seq2[T, error], jitter time.Duration) iter.Seq2[T, error] {
+	if jitter <= 0 {
+		panic("kwaytest: jitter must be positive")
+	}
+	return func(yield func(T, error) bool) {
+		prng := rand.New(rand.NewPCG(0, uint64(jitter)))
+		for v, err := range seq {
+			time.Sleep(time.Duration(prng.Int64N(int64(jitter))))
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// Truncated returns a sequence yielding the first n values of seq, followed by
+// io.ErrUnexpectedEOF if seq had more values, which simulates a source cut off
+// in the middle (e.g. a partially written file or a closed connection).
+func Truncated[T any](seq iter.Seq2[T, error], n int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		count := 0
+		for v, err := range seq {
+			if count == n {
+				yield(zero, io.ErrUnexpectedEOF)
+				return
+			}
+			count++
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
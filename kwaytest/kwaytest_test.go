@@ -0,0 +1,79 @@
+package kwaytest_test
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/achille-roussel/kway-go"
+	"github.com/achille-roussel/kway-go/kwaytest"
+)
+
+func count(n int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i := range n {
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+}
+
+func collect(seq iter.Seq2[int, error]) (values []int, errs []error) {
+	for v, err := range seq {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			values = append(values, v)
+		}
+	}
+	return values, errs
+}
+
+func TestFlaky(t *testing.T) {
+	values, errs := collect(kwaytest.Flaky(count(10), 3))
+
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %d", len(errs))
+	}
+	for _, err := range errs {
+		if err != kwaytest.ErrInjected {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestSlow(t *testing.T) {
+	values, errs := collect(kway.Merge(
+		kwaytest.Slow(count(5), time.Millisecond),
+		kwaytest.Slow(count(5), time.Millisecond),
+	))
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(values) != 10 {
+		t.Errorf("expected 10 values, got %d", len(values))
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	values, errs := collect(kwaytest.Truncated(count(10), 4))
+
+	if want := []int{0, 1, 2, 3}; !slices.Equal(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", errs)
+	}
+
+	values, errs = collect(kwaytest.Truncated(count(4), 4))
+	if len(values) != 4 || len(errs) != 0 {
+		t.Errorf("expected 4 values and no errors, got %v and %v", values, errs)
+	}
+}
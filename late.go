@@ -0,0 +1,102 @@
+package kway
+
+import "fmt"
+
+// LatePolicy defines how a Merger handles late values, see WithLateData.
+type LatePolicy int
+
+const (
+	// LateDrop silently drops late values.
+	LateDrop LatePolicy = iota
+	// LateEmit yields late values to the application as *LateError values,
+	// at the position where they were merged.
+	LateEmit
+	// LateDivert passes late values to a side output function instead of
+	// yielding them.
+	LateDivert
+)
+
+// LateError is the error yielded by a Merger configured with
+// WithLateData(LateEmit, ...) when a source produced a late value.
+type LateError[T any] struct {
+	// Index of the source that produced the value.
+	Index int
+	// Label of the source configured with WithLabels, or empty if the source
+	// had no label.
+	Label string
+	// The late value.
+	Value T
+}
+
+// Error satisfies the error interface.
+func (e *LateError[T]) Error() string {
+	if e.Label != "" {
+		return fmt.Sprintf("kway: source %q: late value: %v", e.Label, e.Value)
+	}
+	return fmt.Sprintf("kway: source %d: late value: %v", e.Index, e.Value)
+}
+
+// WithLateData configures a Merger to detect late values, and handle them
+// according to the given policy.
+//
+// A value is late when it is ordered before values that were already yielded
+// to the application, or before the last watermark reported by WithWatermark.
+// This happens when sources do not produce ordered values, for example events
+// received with delays larger than the disorder that the sources can repair.
+//
+// The divert function receives the index of the source and the late value when
+// the policy is LateDivert, it is ignored otherwise.
+func WithLateData[T any](policy LatePolicy, divert func(source int, value T)) Option {
+	if policy == LateDivert && divert == nil {
+		panic("kway: late data diverted to a nil function")
+	}
+	return func(o *options) {
+		o.lateData = true
+		o.latePolicy = policy
+		o.lateDivert = divert
+	}
+}
+
+// filterLate wraps emit to handle late values in the batches that it receives.
+// Values ordered after the late ones are emitted in separate sub-batches, the
+// batches are never modified.
+func (m *Merger[T]) filterLate(emit func([]T, []int) bool, fail func(error) bool) func([]T, []int) bool {
+	var last T
+	var hasLast bool
+
+	isLate := func(v T) bool {
+		if hasLast && m.cmp(v, last) < 0 {
+			return true
+		}
+		return m.hasWatermark && m.cmp(v, m.lastWatermark) < 0
+	}
+
+	return func(values []T, sources []int) bool {
+		start := 0
+		for i, v := range values {
+			if !isLate(v) {
+				last, hasLast = v, true
+				continue
+			}
+			if start < i && !emit(values[start:i], sources[start:i]) {
+				return false
+			}
+			start = i + 1
+
+			switch m.opts.latePolicy {
+			case LateEmit:
+				source := sources[i]
+				err := &LateError[T]{Index: source, Label: m.label(source), Value: v}
+				if !fail(err) {
+					return false
+				}
+			case LateDivert:
+				m.lateDivert(sources[i], v)
+			}
+		}
+		if start < len(values) {
+			return emit(values[start:], sources[start:])
+		}
+		return true
+	}
+}
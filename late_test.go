@@ -0,0 +1,74 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerLateData(t *testing.T) {
+	sources := func() []iter.Seq2[int, error] {
+		return []iter.Seq2[int, error]{
+			seqOf(1, 5, 3, 7),
+			seqOf(2, 4, 6),
+		}
+	}
+	want := []int{1, 2, 4, 5, 6, 7}
+
+	t.Run("drop", func(t *testing.T) {
+		m := newMerger(t, cmp.Compare[int], sources(), WithLateData[int](LateDrop, nil))
+		got, err := values(m.All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("emit", func(t *testing.T) {
+		m := newMerger(t, cmp.Compare[int], sources(), WithLabels("a", "b"), WithLateData[int](LateEmit, nil))
+
+		var got []int
+		var late []*LateError[int]
+		for v, err := range m.All() {
+			if err != nil {
+				var lateErr *LateError[int]
+				if !errors.As(err, &lateErr) {
+					t.Fatal(err)
+				}
+				late = append(late, lateErr)
+			} else {
+				got = append(got, v)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if len(late) != 1 || late[0].Value != 3 || late[0].Index != 0 || late[0].Label != "a" {
+			t.Errorf("unexpected late values: %v", late)
+		}
+	})
+
+	t.Run("divert", func(t *testing.T) {
+		var diverted []int
+		m := newMerger(t, cmp.Compare[int], sources(), WithLateData(LateDivert, func(source int, v int) {
+			if source != 0 {
+				t.Errorf("unexpected source of late value: %d", source)
+			}
+			diverted = append(diverted, v)
+		}))
+		got, err := values(m.All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if !slices.Equal(diverted, []int{3}) {
+			t.Errorf("expected [3] to be diverted, got %v", diverted)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package kway
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// LimitPerKey returns a sequence yielding at most n values of each run of
+// values of seq which compare equal according to cmp, dropping the rest.
+//
+// The comparison function typically compares a subset of the fields used to
+// order the merge, for example to implement a "keep the newest n versions of
+// each key" retention policy while compacting data ordered by key then by
+// descending version:
+//
+//	sameKey := func(a, b Record) int { return cmp.Compare(a.Key, b.Key) }
+//
+//	for r, err := range kway.LimitPerKey(3, sameKey, kway.MergeFunc(byKeyThenNewest, seqs...)) {
+//		...
+//	}
+//
+// Errors produced by seq are yielded unchanged.
+func LimitPerKey[T any](n int, cmp func(T, T) int, seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if n < 0 {
+		panic("kway: limit per key must not be negative")
+	}
+	return func(yield func(T, error) bool) {
+		var last T
+		var count int
+
+		for value, err := range seq {
+			if err == nil {
+				if count == 0 || cmp(last, value) != 0 {
+					last, count = value, 0
+				}
+				if count == n {
+					continue
+				}
+				count++
+			}
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
+}
+
+// ErrKeyOverflow is the error yielded by sequences returned by CapPerKey when
+// the overflow function aborts the sequence.
+var ErrKeyOverflow = errors.New("too many values with the same key")
+
+// OverflowAction is the action taken by sequences returned by CapPerKey when a
+// key exceeds the cap.
+type OverflowAction int
+
+const (
+	// OverflowKeep yields the values of the key beyond the cap, the overflow
+	// is only reported to the overflow function (e.g. to log it).
+	OverflowKeep OverflowAction = iota
+	// OverflowDrop drops the values of the key beyond the cap.
+	OverflowDrop
+	// OverflowAbort yields ErrKeyOverflow in place of the first value beyond
+	// the cap, and ends the sequence.
+	OverflowAbort
+)
+
+// CapPerKey returns a sequence guarding against pathological keys in seq: when
+// a run of values which compare equal according to cmp has more than n values,
+// the overflow function is called with the first value beyond the cap, and
+// the action it returns determines how the values of the key are handled.
+//
+// Unlike LimitPerKey, which implements a retention policy, CapPerKey detects
+// data which breaks the assumptions of the application, for example a corrupt
+// producer emitting millions of copies of a key, which would otherwise stall
+// downstream consumers grouping values by key:
+//
+//	capped := kway.CapPerKey(1000, sameKey, merged, func(v Record) kway.OverflowAction {
+//		log.Printf("key %q has more than 1000 versions, dropping the rest", v.Key)
+//		return kway.OverflowDrop
+//	})
+//
+// The overflow function is called at most once per run of values. Errors
+// produced by seq are yielded unchanged.
+func CapPerKey[T any](n int, cmp func(T, T) int, seq iter.Seq2[T, error], overflow func(value T) OverflowAction) iter.Seq2[T, error] {
+	if n < 0 {
+		panic("kway: cap per key must not be negative")
+	}
+	return func(yield func(T, error) bool) {
+		var zero, last T
+		var count int
+		var action OverflowAction
+
+		for value, err := range seq {
+			if err == nil {
+				if count == 0 || cmp(last, value) != 0 {
+					last, count, action = value, 0, OverflowKeep
+				}
+				if count++; count == n+1 {
+					action = overflow(value)
+				}
+				if count > n {
+					switch action {
+					case OverflowDrop:
+						continue
+					case OverflowAbort:
+						yield(zero, fmt.Errorf("%w: more than %d values", ErrKeyOverflow, n))
+						return
+					}
+				}
+			}
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
+}
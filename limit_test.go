@@ -0,0 +1,102 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestLimitPerKey(t *testing.T) {
+	type version struct {
+		key     string
+		version int
+	}
+	byKeyThenNewest := func(a, b version) int {
+		if c := cmp.Compare(a.key, b.key); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.version, a.version)
+	}
+	sameKey := func(a, b version) int { return cmp.Compare(a.key, b.key) }
+
+	merged := MergeFunc(byKeyThenNewest,
+		seqOf(version{"a", 5}, version{"a", 3}, version{"b", 1}),
+		seqOf(version{"a", 4}, version{"a", 1}, version{"c", 2}, version{"c", 1}),
+		seqOf(version{"a", 2}, version{"c", 3}),
+	)
+
+	got, err := values(LimitPerKey(2, sameKey, merged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []version{{"a", 5}, {"a", 4}, {"b", 1}, {"c", 3}, {"c", 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLimitPerKeyZero(t *testing.T) {
+	errval := errors.New("")
+	seq := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(2, nil)
+	}
+
+	var got []int
+	var errs []error
+	for v, err := range LimitPerKey(0, cmp.Compare[int], seq) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if len(got) != 0 || len(errs) != 1 {
+		t.Errorf("expected only one error, got values %v and errors %v", got, errs)
+	}
+}
+
+func TestCapPerKey(t *testing.T) {
+	merged := func() iter.Seq2[int, error] {
+		return Merge(seqOf(1, 2, 2, 3), seqOf(2, 2, 3), seqOf(2, 4))
+	}
+
+	tests := []struct {
+		action OverflowAction
+		want   []int
+		err    error
+	}{
+		{action: OverflowKeep, want: []int{1, 2, 2, 2, 2, 2, 3, 3, 4}},
+		{action: OverflowDrop, want: []int{1, 2, 2, 2, 3, 3, 4}},
+		{action: OverflowAbort, want: []int{1, 2, 2, 2}, err: ErrKeyOverflow},
+	}
+
+	for _, test := range tests {
+		var overflows []int
+		overflow := func(v int) OverflowAction {
+			overflows = append(overflows, v)
+			return test.action
+		}
+
+		var got []int
+		var err error
+		for v, e := range CapPerKey(3, cmp.Compare[int], merged(), overflow) {
+			if e != nil {
+				err = e
+			} else {
+				got = append(got, v)
+			}
+		}
+
+		if !slices.Equal(got, test.want) {
+			t.Errorf("action %d: expected %v, got %v", test.action, test.want, got)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("action %d: expected error %v, got %v", test.action, test.err, err)
+		}
+		if !slices.Equal(overflows, []int{2}) {
+			t.Errorf("action %d: expected one overflow of key 2, got %v", test.action, overflows)
+		}
+	}
+}
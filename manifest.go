@@ -0,0 +1,233 @@
+package kway
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+)
+
+// ErrManifestMismatch is the error yielded by MergeManifest when the contents
+// of a file do not match its description in the manifest.
+var ErrManifestMismatch = errors.New("file does not match its manifest")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Manifest is the index of the files written by WriteFiles, in the order of
+// the values they contain.
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one of the files of a Manifest.
+type ManifestFile struct {
+	// Name of the file, relative to the directory of the manifest.
+	Name string `json:"name"`
+	// Number of values in the file.
+	Values int64 `json:"values"`
+	// Size of the file in bytes.
+	Size int64 `json:"size"`
+	// CRC-32 checksum of the file contents, using the Castagnoli polynomial.
+	Checksum uint32 `json:"crc32c"`
+	// Encoding of the first and last values of the file with the codec of the
+	// file, which are its minimum and maximum when the file contains ordered
+	// values. The bounds are encoded as a stream of the codec holding a single
+	// value, and are omitted if the values cannot be encoded.
+	Min []byte `json:"min,omitempty"`
+	Max []byte `json:"max,omitempty"`
+}
+
+// encodeBound returns the encoding of v with the codec, or nil if v cannot be
+// encoded.
+func encodeBound[T any](codec Codec[T], v T) []byte {
+	b, err := codec.Encoder().Encode(nil, []T{v})
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// decodeBound decodes a bound encoded by encodeBound. It returns false if b
+// does not contain exactly one value of the codec.
+func decodeBound[T any](codec Codec[T], b []byte) (bound T, ok bool) {
+	if len(b) == 0 {
+		return bound, false
+	}
+	n := 0
+	for v, err := range codec.Decode(bytes.NewReader(b)) {
+		if err != nil {
+			return bound, false
+		}
+		bound, n = v, n+1
+	}
+	return bound, n == 1
+}
+
+// ReadManifest reads the manifest written by WriteFiles at the given path.
+func ReadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// WithManifest configures MergeFiles and MergeGlob to use the manifest at the
+// given path, written by WriteFiles, for the files that it lists. Those files
+// are merged like MergeManifest does: their bounds allow disjoint files to be
+// read back-to-back, and they are verified against the manifest as they are
+// read. The paths of the files must name the same files as the manifest, which
+// lists them relative to its own directory.
+//
+// Files which are not listed in the manifest are merged with unknown bounds and
+// are not verified.
+func WithManifest(path string) Option {
+	return func(o *options) { o.manifest = path }
+}
+
+// manifestFiles returns the files listed in the manifest at the given path,
+// indexed by their absolute path.
+func manifestFiles(path string) (map[string]ManifestFile, error) {
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]ManifestFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		files[filepath.Join(dir, file.Name)] = file
+	}
+	return files, nil
+}
+
+// MergeManifest merges the files listed in the manifest at the given path,
+// decoding them with the codec.
+//
+// The bounds of the files recorded in the manifest allow files with disjoint
+// ranges of values to be read back-to-back without comparing their values,
+// see MergeBounded. The outputs of WriteFiles being partitioned in contiguous
+// ranges, merging them costs only a few comparisons per file. The bounds are
+// decoded with the codec, and ignored if they cannot be decoded into a single
+// value.
+//
+// The files are verified against the manifest as they are read: an error
+// wrapping ErrManifestMismatch is yielded after the values of a file if its
+// size, number of values, or checksum differ from the manifest.
+//
+// See MergeManifestFunc for a version of this function that allows the caller
+// to pass a custom comparison function.
+func MergeManifest[T cmp.Ordered](path string, codec Codec[T]) iter.Seq2[T, error] {
+	return MergeManifestFunc(cmp.Compare[T], path, codec)
+}
+
+// MergeManifestFunc is like MergeManifest but uses the given comparison
+// function to determine the order of values.
+//
+// See MergeManifest for more details.
+func MergeManifestFunc[T any](cmp func(T, T) int, path string, codec Codec[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		manifest, err := ReadManifest(path)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		dir := filepath.Dir(path)
+		seqs := make([]Bounded[T], len(manifest.Files))
+		for i, file := range manifest.Files {
+			seqs[i] = boundedManifestFile(filepath.Join(dir, file.Name), file, codec)
+		}
+
+		for v, err := range MergeBoundedFunc(cmp, seqs...) {
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// boundedManifestFile returns the sequence of values of a file listed in a
+// manifest, bounded by the bounds recorded in the manifest if they can be
+// decoded with the codec.
+func boundedManifestFile[T any](path string, file ManifestFile, codec Codec[T]) Bounded[T] {
+	seq := readManifestFile(path, file, codec)
+	min, minOK := decodeBound(codec, file.Min)
+	max, maxOK := decodeBound(codec, file.Max)
+	if !minOK || !maxOK {
+		return Unbounded(seq)
+	}
+	return WithBounds(seq, min, max)
+}
+
+// readManifestFile returns a sequence of the values decoded from the file at
+// path, which yields an error after the values if the file does not match its
+// description in the manifest.
+func readManifestFile[T any](path string, file ManifestFile, codec Codec[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		f, err := os.Open(path)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer f.Close()
+
+		checksum := crc32.New(castagnoli)
+		r := &countWriter{w: checksum}
+		var values int64
+
+		for v, err := range codec.Decode(io.TeeReader(f, r)) {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+			values++
+		}
+
+		if _, err := io.Copy(r, f); err != nil {
+			yield(zero, err)
+			return
+		}
+		switch {
+		case r.n != file.Size:
+			err = fmt.Errorf("%w: %s: size %d, expected %d", ErrManifestMismatch, path, r.n, file.Size)
+		case values != file.Values:
+			err = fmt.Errorf("%w: %s: %d values, expected %d", ErrManifestMismatch, path, values, file.Values)
+		case checksum.Sum32() != file.Checksum:
+			err = fmt.Errorf("%w: %s: checksum %08x, expected %08x", ErrManifestMismatch, path, checksum.Sum32(), file.Checksum)
+		}
+		if err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// countWriter is an io.Writer counting the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
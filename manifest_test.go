@@ -0,0 +1,181 @@
+package kway
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeManifest(t *testing.T, n int, rotation Rotation) string {
+	t.Helper()
+	dir := t.TempDir()
+	rotation.Manifest = "manifest.json"
+	if _, err := WriteFiles(dir, count(n), varintCodec(), rotation, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, rotation.Manifest)
+}
+
+func TestManifestBounds(t *testing.T) {
+	manifest, err := ReadManifest(writeManifest(t, 100, Rotation{MaxValues: 30}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bounds []int
+	for _, file := range manifest.Files {
+		min, minOK := decodeBound(varintCodec(), file.Min)
+		max, maxOK := decodeBound(varintCodec(), file.Max)
+		if !minOK || !maxOK {
+			t.Fatalf("invalid bounds in file %s", file.Name)
+		}
+		bounds = append(bounds, min, max)
+	}
+	want := []int{0, 29, 30, 59, 60, 89, 90, 99}
+	if !slices.Equal(bounds, want) {
+		t.Errorf("expected bounds %v, got %v", want, bounds)
+	}
+}
+
+func TestMergeManifest(t *testing.T) {
+	path := writeManifest(t, 1000, Rotation{MaxValues: 100})
+
+	comparisons := 0
+	got, err := values(MergeManifestFunc(func(a, b int) int {
+		comparisons++
+		return a - b
+	}, path, varintCodec()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(1000)); !slices.Equal(got, want) {
+		t.Errorf("unexpected values: %v", got)
+	}
+	// The files are disjoint, only their bounds should have been compared.
+	if comparisons > 100 {
+		t.Errorf("too many comparisons for disjoint files: %d", comparisons)
+	}
+}
+
+func TestMergeManifestMismatch(t *testing.T) {
+	for _, test := range []struct {
+		scenario string
+		corrupt  func([]byte) []byte
+	}{
+		{
+			scenario: "checksum",
+			// Replace the value 2 by 3, which is still within the bounds.
+			corrupt: func(b []byte) []byte { b[5] = 6; return b },
+		},
+		{
+			scenario: "size and values",
+			// Append the value 9 again, the last value of the file.
+			corrupt: func(b []byte) []byte { return append(b, 1, 18) },
+		},
+	} {
+		t.Run(test.scenario, func(t *testing.T) {
+			path := writeManifest(t, 100, Rotation{MaxValues: 10})
+			file := filepath.Join(filepath.Dir(path), "000000")
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, test.corrupt(b), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			n := 0
+			for _, err := range MergeManifest(path, varintCodec()) {
+				if err != nil {
+					if !errors.Is(err, ErrManifestMismatch) {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if n < 10 {
+						t.Errorf("error yielded before the values of the file: %d values", n)
+					}
+					return
+				}
+				n++
+			}
+			t.Error("corruption was not detected")
+		})
+	}
+}
+
+// opaque is a type which does not survive a JSON round-trip.
+type opaque struct{ v int }
+
+func TestMergeManifestOpaqueBounds(t *testing.T) {
+	codec := LengthPrefixedCodec(
+		func(b []byte, v opaque) ([]byte, error) { return binary.AppendVarint(b, int64(v.v)), nil },
+		func(b []byte) (opaque, error) {
+			v, _ := binary.Varint(b)
+			return opaque{int(v)}, nil
+		},
+	)
+	seq := func(yield func(opaque, error) bool) {
+		for i := range 100 {
+			if !yield(opaque{i}, nil) {
+				return
+			}
+		}
+	}
+
+	dir := t.TempDir()
+	if _, err := WriteFiles(dir, seq, codec, Rotation{MaxValues: 30, Manifest: "manifest.json"}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	comparisons := 0
+	got, err := values(MergeManifestFunc(func(a, b opaque) int {
+		comparisons++
+		return a.v - b.v
+	}, filepath.Join(dir, "manifest.json"), codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 100 || !slices.IsSortedFunc(got, func(a, b opaque) int { return a.v - b.v }) {
+		t.Errorf("unexpected values: %v", got)
+	}
+	if comparisons > 2*len(got)+10 {
+		t.Errorf("too many comparisons for disjoint files: %d", comparisons)
+	}
+}
+
+func TestMergeFilesManifest(t *testing.T) {
+	path := writeManifest(t, 1000, Rotation{MaxValues: 100})
+	dir := filepath.Dir(path)
+
+	comparisons := 0
+	compare := func(a, b int) int {
+		comparisons++
+		return a - b
+	}
+	got, err := values(MergeGlobFunc(compare, filepath.Join(dir, "*"), varintCodec(), WithManifest(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(1000)); !slices.Equal(got, want) {
+		t.Errorf("unexpected values: %v", got)
+	}
+	if comparisons > 2*len(got)+100 {
+		t.Errorf("too many comparisons for disjoint files: %d", comparisons)
+	}
+
+	// Replace the value 2 by 3, which is still within the bounds.
+	file := filepath.Join(dir, "000000")
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[5] = 6
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{file, filepath.Join(dir, "000001")}
+	if _, err := values(MergeFiles(paths, varintCodec(), WithManifest(path))); !errors.Is(err, ErrManifestMismatch) {
+		t.Errorf("expected %v, got %v", ErrManifestMismatch, err)
+	}
+}
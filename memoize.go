@@ -0,0 +1,117 @@
+package kway
+
+// Memoize returns a comparison function caching the results of cmp in a least
+// recently used cache of the given size.
+//
+// Memoization is intended for expensive comparison functions, for example
+// functions parsing semantic version strings or timestamps. The loser tree of
+// the merge replays games between the same heads of sequences many times, and
+// the heads only change when values are consumed, so the cache absorbs a large
+// fraction of the comparisons.
+//
+// The key function returns the cache key of values, for example a pointer to
+// the value or a hash of its contents. Values with equal keys must be ordered
+// identically by cmp; hashes are therefore only suitable when collisions are
+// impossible or negligible. Computing keys must be much cheaper than comparing
+// values, or memoization will not pay off.
+//
+// The returned function is not safe for concurrent use, each merge must be
+// given its own memoized comparison function.
+func Memoize[T any, K comparable](cmp func(T, T) int, key func(T) K, size int) func(T, T) int {
+	if size <= 0 {
+		panic("kway: memoization cache size must be positive")
+	}
+	c := &compareCache[K]{
+		index:   make(map[[2]K]int32, size),
+		entries: make([]cacheEntry[K], 0, size),
+		head:    -1,
+		tail:    -1,
+	}
+	return func(a, b T) int {
+		ka, kb := key(a), key(b)
+		if ka == kb {
+			return 0
+		}
+		if r, ok := c.get([2]K{ka, kb}); ok {
+			return r
+		}
+		if r, ok := c.get([2]K{kb, ka}); ok {
+			return -r
+		}
+		r := cmp(a, b)
+		c.put([2]K{ka, kb}, r)
+		return r
+	}
+}
+
+// compareCache is a least recently used cache of comparison results, entries
+// are stored in a slice and linked in order of use by their indexes.
+type compareCache[K comparable] struct {
+	index      map[[2]K]int32
+	entries    []cacheEntry[K]
+	head, tail int32 // most and least recently used entries
+}
+
+type cacheEntry[K comparable] struct {
+	key        [2]K
+	result     int
+	prev, next int32
+}
+
+func (c *compareCache[K]) get(key [2]K) (int, bool) {
+	i, ok := c.index[key]
+	if !ok {
+		return 0, false
+	}
+	c.moveToFront(i)
+	return c.entries[i].result, true
+}
+
+func (c *compareCache[K]) put(key [2]K, result int) {
+	var i int32
+	if len(c.entries) < cap(c.entries) {
+		i = int32(len(c.entries))
+		c.entries = append(c.entries, cacheEntry[K]{prev: -1, next: -1})
+	} else {
+		i = c.tail
+		delete(c.index, c.entries[i].key)
+		c.unlink(i)
+	}
+	c.entries[i].key, c.entries[i].result = key, result
+	c.index[key] = i
+	c.pushFront(i)
+}
+
+func (c *compareCache[K]) moveToFront(i int32) {
+	if c.head != i {
+		c.unlink(i)
+		c.pushFront(i)
+	}
+}
+
+func (c *compareCache[K]) unlink(i int32) {
+	e := &c.entries[i]
+	if e.prev >= 0 {
+		c.entries[e.prev].next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next >= 0 {
+		c.entries[e.next].prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+	e.prev, e.next = -1, -1
+}
+
+func (c *compareCache[K]) pushFront(i int32) {
+	e := &c.entries[i]
+	e.prev, e.next = -1, c.head
+	if c.head >= 0 {
+		c.entries[c.head].prev = i
+	}
+	c.head = i
+	if c.tail < 0 {
+		c.tail = i
+	}
+}
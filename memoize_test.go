@@ -0,0 +1,95 @@
+package kway
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// compareVersions compares version strings of the form "major.minor.patch".
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range pa {
+		x, _ := strconv.Atoi(pa[i])
+		y, _ := strconv.Atoi(pb[i])
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+func identity[T any](v T) T { return v }
+
+func TestMemoize(t *testing.T) {
+	versions := func(major int) iter.Seq2[string, error] {
+		return func(yield func(string, error) bool) {
+			for minor := range 20 {
+				for patch := range 20 {
+					if !yield(fmt.Sprintf("%d.%d.%d", major, minor, patch), nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+	seqs := func() []iter.Seq2[string, error] {
+		return []iter.Seq2[string, error]{versions(1), versions(1), versions(1), versions(2), versions(2)}
+	}
+
+	want, err := values(MergeFunc(compareVersions, seqs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls, total := 0, 0
+	memoized := Memoize(func(a, b string) int {
+		calls++
+		return compareVersions(a, b)
+	}, identity[string], 64)
+	got, err := values(MergeFunc(func(a, b string) int {
+		total++
+		return memoized(a, b)
+	}, seqs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Error("memoized merge produced different values")
+	}
+	if calls >= total {
+		t.Errorf("memoization did not save comparisons: %d/%d", calls, total)
+	}
+	t.Logf("%d/%d comparisons (%.1f%%)", calls, total, 100*float64(calls)/float64(total))
+}
+
+func TestMemoizeEviction(t *testing.T) {
+	calls := 0
+	cmp := Memoize(func(a, b int) int {
+		calls++
+		return a - b
+	}, identity[int], 2)
+
+	check := func(a, b, want, wantCalls int) {
+		t.Helper()
+		if got := cmp(a, b); (got < 0) != (want < 0) || (got > 0) != (want > 0) {
+			t.Errorf("cmp(%d, %d): expected %d, got %d", a, b, want, got)
+		}
+		if calls != wantCalls {
+			t.Errorf("cmp(%d, %d): expected %d calls, got %d", a, b, wantCalls, calls)
+		}
+	}
+
+	check(1, 2, -1, 1)
+	check(2, 1, +1, 1) // reversed pair hits the cache
+	check(1, 1, 0, 1)  // equal keys are not compared
+	check(1, 3, -1, 2)
+	check(1, 2, -1, 2) // most recently used
+	check(2, 3, -1, 3) // evicts (1, 3)
+	check(1, 2, -1, 3)
+	check(1, 3, -1, 4)
+}
@@ -0,0 +1,57 @@
+package kway
+
+// WithMemoryLimit configures a Merger to bound the memory used to buffer values
+// read from its sources. The sizeOf function returns the size in bytes that a
+// value occupies in memory, it must always return the same size for a given
+// value.
+//
+// The Merger accounts for the size of all values held in its internal buffers,
+// from the time they are read from the sources until they are yielded to the
+// application. When the limit is reached, the Merger stops reading ahead from
+// the sources and flushes its buffers early, reducing its batch sizes until
+// memory is released.
+//
+// Because the merge needs at least one value from each source to make progress,
+// the limit may be exceeded by up to the size of one value per source.
+func WithMemoryLimit[T any](bytes int64, sizeOf func(T) int) Option {
+	if bytes <= 0 {
+		panic("kway: memory limit must be positive")
+	}
+	return func(o *options) {
+		o.memoryLimit = bytes
+		o.sizeOf = sizeOf
+	}
+}
+
+// memoryBudget tracks the memory used by values buffered in a Merger.
+type memoryBudget[T any] struct {
+	limit  int64
+	used   int64
+	sizeOf func(T) int
+}
+
+// acquire accounts for the value being buffered and returns true if the memory
+// limit has been reached.
+func (b *memoryBudget[T]) acquire(value T) bool {
+	b.used += int64(b.sizeOf(value))
+	return b.used >= b.limit
+}
+
+// release accounts for values leaving the buffers.
+func (b *memoryBudget[T]) release(values []T) {
+	for _, v := range values {
+		b.used -= int64(b.sizeOf(v))
+	}
+}
+
+// MemoryUsage returns the number of bytes currently held in the Merger's
+// buffers, as reported by the size function passed to WithMemoryLimit.
+//
+// The method returns zero if the Merger was not configured with a memory
+// limit.
+func (m *Merger[T]) MemoryUsage() int64 {
+	if m.memory == nil {
+		return 0
+	}
+	return m.memory.used
+}
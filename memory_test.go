@@ -0,0 +1,48 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"testing"
+)
+
+func TestMergerMemoryLimit(t *testing.T) {
+	const limit = 100
+	const k = 4
+
+	seqs := make([]iter.Seq2[int, error], k)
+	for i := range seqs {
+		seqs[i] = sequence(i, 10000, k)
+	}
+
+	var m *Merger[int]
+	var peak int64
+	sizeOf := func(int) int {
+		if m != nil {
+			peak = max(peak, m.MemoryUsage())
+		}
+		return 8
+	}
+	m = newMerger(t, cmp.Compare[int], seqs, WithMemoryLimit(limit, sizeOf))
+
+	next := 0
+	for v, err := range m.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != next {
+			t.Fatalf("expected %d, got %d", next, v)
+		}
+		next++
+	}
+	if next != 10000 {
+		t.Errorf("expected 10000 values, got %d", next)
+	}
+
+	if peak > limit+(k*8) {
+		t.Errorf("memory usage exceeded the limit: %d > %d", peak, limit)
+	}
+	if usage := m.MemoryUsage(); usage != 0 {
+		t.Errorf("expected all memory to be released, %d bytes still in use", usage)
+	}
+}
@@ -0,0 +1,105 @@
+package kway
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// WithBoundedMemory configures a Merger to estimate a worst-case bound of the
+// memory it retains, computed from the number of sources and the maximum size
+// of values, which is returned by Merger.MemoryBound.
+//
+// The maximum value size is the number of bytes that a value occupies in
+// memory, including the memory it references (e.g. the bytes of a string). The
+// bound accounts for the batches buffered for each source and for the merged
+// output, the loser tree, and the state retained by the options of the Merger
+// (e.g. the last values of checkpoints and statistics):
+//
+//	(k × 128 + 128) × maxValueSize + k × (tree + options) + options
+//
+// where k is the number of sources and 128 the number of values in the batches
+// of the Merger. With WithMemoryLimit, the memory held by buffered values is
+// bounded by the limit instead, plus one value per source.
+//
+// The bound is an estimate, not a guarantee: it leaves out the memory used by
+// some options, such as the contexts and timers of WithSourceContexts,
+// WithDeadline, and WithIdleTimeout, the labels of WithLabels, or the details
+// of the panics recovered by WithComparePanicRecovery, as well as the memory
+// held by the sources themselves and the stacks of the goroutines used to read
+// them.
+//
+// Configurations which retain an unbounded amount of memory are rejected:
+// NewMerger returns an error when the option is combined with
+// WithCollectErrors, which retains all the errors of the sources until the
+// merge completes.
+func WithBoundedMemory(maxValueSize int) Option {
+	if maxValueSize <= 0 {
+		panic("kway: maximum value size must be positive")
+	}
+	return func(o *options) { o.maxValueSize = maxValueSize }
+}
+
+// checkBoundedMemory returns an error if the options of the Merger are
+// incompatible with WithBoundedMemory.
+func (m *Merger[T]) checkBoundedMemory() error {
+	if m.opts.maxValueSize > 0 && m.opts.collectErrors {
+		return errors.New("WithBoundedMemory cannot be combined with WithCollectErrors, which retains an unbounded number of errors")
+	}
+	return nil
+}
+
+// MemoryBound returns an estimate of the worst-case number of bytes retained by
+// the Merger, as computed by WithBoundedMemory. The method returns zero if the
+// Merger was not configured with WithBoundedMemory.
+//
+// The bound can be computed before the merge starts, which allows applications
+// to plan the capacity of merges with many sources.
+func (m *Merger[T]) MemoryBound() int64 {
+	if m.opts.maxValueSize == 0 {
+		return 0
+	}
+	var zero T
+	k := int64(len(m.seqs))
+	slot := int64(unsafe.Sizeof(zero))
+	size := max(int64(m.opts.maxValueSize), slot)
+
+	// Values buffered for each source, and the output buffer of the merge. With
+	// a flush interval, each source also holds one value in flight between the
+	// goroutine reading it and the merge.
+	values := (k + 1) * bufferSize
+	if m.opts.flushInterval > 0 {
+		values += k
+	}
+	bound := values * size
+	if m.opts.memoryLimit > 0 {
+		bound = min(bound, values*slot+m.opts.memoryLimit+k*size)
+	}
+
+	// Output buffer of source indexes, nodes and cursors of the loser tree,
+	// and per-source state of the Merger.
+	bound += bufferSize * int64(unsafe.Sizeof(int(0)))
+	bound += k * (2*int64(unsafe.Sizeof(node[T]{})) + int64(unsafe.Sizeof(cursor[T, T]{})))
+	bound += k * (int64(unsafe.Sizeof(SourcePosition[T]{})) + size + 3*int64(unsafe.Sizeof(int64(0))))
+	if m.opts.stats {
+		bound += (k + 1) * (int64(unsafe.Sizeof(SourceStats[T]{})) + 2*size)
+	}
+	if m.opts.adaptive {
+		bound += k * int64(unsafe.Sizeof(int64(0)))
+	}
+	if m.opts.idleTimeout > 0 {
+		bound += k * int64(unsafe.Sizeof(sourceActivity{}))
+	}
+	if m.opts.validateOrder {
+		bound += k * size
+	}
+	if m.opts.dropFailed {
+		bound += k * int64(unsafe.Sizeof(SourceError{})+unsafe.Sizeof((*SourceError)(nil)))
+	}
+	if m.opts.duplicates.mode != keepAll {
+		bound += size + k*(int64(unsafe.Sizeof(int64(0)))+int64(unsafe.Sizeof(int(0))))
+	}
+	// Last values retained to report watermarks and distinct values, and to
+	// detect late data.
+	bound += 3 * size
+	return bound
+}
@@ -0,0 +1,60 @@
+package kway
+
+import (
+	"iter"
+	"testing"
+	"time"
+)
+
+func TestMemoryBound(t *testing.T) {
+	sources := func(k int) []iter.Seq2[int, error] {
+		seqs := make([]iter.Seq2[int, error], k)
+		for i := range seqs {
+			seqs[i] = count(10)
+		}
+		return seqs
+	}
+	compare := func(a, b int) int { return a - b }
+
+	if bound := newMerger(t, compare, sources(4)).MemoryBound(); bound != 0 {
+		t.Errorf("expected no bound without WithBoundedMemory, got %d", bound)
+	}
+
+	bound4 := newMerger(t, compare, sources(4), WithBoundedMemory(100)).MemoryBound()
+	bound8 := newMerger(t, compare, sources(8), WithBoundedMemory(100)).MemoryBound()
+	if min := int64(5 * bufferSize * 100); bound4 < min {
+		t.Errorf("bound %d is lower than the size of the buffered values %d", bound4, min)
+	}
+	if bound8 <= bound4 {
+		t.Errorf("bound does not grow with the number of sources: %d <= %d", bound8, bound4)
+	}
+
+	options := []struct {
+		name   string
+		option Option
+		larger bool
+	}{
+		{"WithStats", WithStats(), true},
+		{"WithFlushInterval", WithFlushInterval(time.Second), true},
+		{"WithMemoryLimit", WithMemoryLimit(1000, func(int) int { return 100 }), false},
+	}
+	for _, opt := range options {
+		bound := newMerger(t, compare, sources(4), WithBoundedMemory(100), opt.option).MemoryBound()
+		if opt.larger && bound <= bound4 {
+			t.Errorf("%s: expected the bound to increase: %d <= %d", opt.name, bound, bound4)
+		}
+		if !opt.larger && bound >= bound4 {
+			t.Errorf("%s: expected the bound to decrease: %d >= %d", opt.name, bound, bound4)
+		}
+	}
+}
+
+func TestBoundedMemoryRejectsCollectErrors(t *testing.T) {
+	_, err := NewMerger(func(a, b int) int { return a - b }, []iter.Seq2[int, error]{count(1), count(2)},
+		WithBoundedMemory(8),
+		WithCollectErrors(),
+	)
+	if err == nil {
+		t.Error("expected NewMerger to return an error")
+	}
+}
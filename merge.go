@@ -60,13 +60,18 @@ const (
 //		}
 //	}
 //
+// Values which compare equal are all yielded, ordered by the index of the
+// sequence they were read from, whether they are merged by the loser-tree or by
+// the two-way kernel used for two sequences. MergeDuplicates applies a
+// DuplicatePolicy to the runs of equal values instead.
+//
 // The inner implementation of the merge algorithm does not spawn goroutines to
 // concurrently read values from the sequences. In some cases where values are
 // retrieved from remote sources, it can become a performance bottleneck because
 // the total time for the merge becomes bound on the sum of read latency.
 // In those cases, it is recommended to wrap the sequences so values can be
-// retrieved concurrently from the remote sources and psuhed into the merge
-// algorithm via a channel.
+// retrieved concurrently from the remote sources and pushed into the merge
+// algorithm via a channel, which is what Prefetch does.
 //
 // For applications that aim to achieve the highest throughput should also use
 // MergeSlice instead, as it allows end-to-end batching which greatly amortizes
@@ -75,6 +80,11 @@ const (
 // See MergeFunc for a version of this function that allows the caller to pass
 // a custom comparison function.
 func Merge[T cmp.Ordered](seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if len(seqs) == 2 {
+		seq0 := buffer(bufferSize, seqs[0])
+		seq1 := buffer(bufferSize, seqs[1])
+		return unbuffer(merge2Ordered(seq0, seq1))
+	}
 	return MergeFunc(cmp.Compare[T], seqs...)
 }
 
@@ -82,6 +92,10 @@ func Merge[T cmp.Ordered](seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
 // function to determine the order of values. The sequences must be ordered
 // by the same comparison function.
 //
+// When given a single sequence, MergeFunc returns it unchanged. Applications
+// which need the same processing regardless of the number of sequences should
+// use a Merger instead, see WithSingleSource.
+//
 // See Merge for more details.
 func MergeFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
 	if len(seqs) == 1 {
@@ -138,12 +152,22 @@ func MergeFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) iter.Seq2
 //		values = append(values, vs...)
 //	}
 //
+// Once all sequences but one are exhausted, the slices produced by the last
+// sequence are yielded exactly as they were produced, without being copied.
+// Applications producing slices from a pool of buffers can rely on this
+// guarantee to avoid doubling their memory bandwidth. MergeSliceBy extends
+// the guarantee to all slices that do not need to be interleaved with values
+// of other sequences.
+//
 // Due to the increased complexity that derives from using MergeSlice,
 // applications should prefer using Merge, which uses the same algorithm as
 // MergeSlice internally, and can already achieve very decent throughput.
 //
 // See Merge for more details.
 func MergeSlice[T cmp.Ordered](seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	if len(seqs) == 2 {
+		return merge2Ordered(seqs[0], seqs[1])
+	}
 	return MergeSliceFunc(cmp.Compare[T], seqs...)
 }
 
@@ -163,7 +187,40 @@ func MergeSliceFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[[]T, error]) it
 	}
 }
 
+// MergeBy merges multiple sequences into one, ordering values by the keys
+// returned by the key function. The sequences must be ordered by the same keys.
+//
+// The key of each value is computed once and cached by the merge algorithm,
+// which makes MergeBy preferable to MergeFunc when deriving the ordering from
+// values is expensive (e.g. parsing, or computing collation keys).
+//
+// See Merge for more details.
+func MergeBy[T any, K cmp.Ordered](key func(T) K, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return MergeByFunc(key, cmp.Compare[K], seqs...)
+}
+
+// MergeByFunc is like MergeBy but uses the given comparison function to
+// determine the order of keys.
+//
+// See MergeBy for more details.
+func MergeByFunc[T, K any](key func(T) K, cmp func(K, K) int, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if len(seqs) == 1 {
+		return seqs[0]
+	}
+	bufferedSeqs := make([]iter.Seq2[[]T, error], len(seqs))
+	for i, seq := range seqs {
+		bufferedSeqs[i] = buffer(bufferSize, seq)
+	}
+	return unbuffer(mergeBy(key, cmp, bufferedSeqs))
+}
+
 func buffer[T any](bufferSize int, seq iter.Seq2[T, error]) iter.Seq2[[]T, error] {
+	return bufferFunc(bufferSize, nil, seq)
+}
+
+// bufferFunc is like buffer but when flush is not nil, it is called with each
+// value added to the buffer, and the buffer is yielded early if it returns true.
+func bufferFunc[T any](bufferSize int, flush func(T) bool, seq iter.Seq2[T, error]) iter.Seq2[[]T, error] {
 	buf := make([]T, bufferSize)
 	return func(yield func([]T, error) bool) {
 		n := 0
@@ -171,11 +228,14 @@ func buffer[T any](bufferSize int, seq iter.Seq2[T, error]) iter.Seq2[[]T, error
 		var err error
 		for buf[n], err = range seq {
 			if err != nil {
-				if !yield(nil, err) {
+				// Values read before the error are yielded with it, so the
+				// error is not reordered ahead of them.
+				if !yield(buf[:n], err) {
 					return
 				}
-			} else if n++; n == len(buf) {
-				if !yield(buf, nil) {
+				n = 0
+			} else if n++; n == len(buf) || (flush != nil && flush(buf[n-1])) {
+				if !yield(buf[:n], nil) {
 					return
 				}
 				n = 0
@@ -188,122 +248,266 @@ func buffer[T any](bufferSize int, seq iter.Seq2[T, error]) iter.Seq2[[]T, error
 	}
 }
 
+// unbuffer is the reverse of buffer. The values of a batch yielded with an
+// error were read before the error, so they are yielded before it.
 func unbuffer[T any](seq iter.Seq2[[]T, error]) iter.Seq2[T, error] {
 	return func(yield func(T, error) bool) {
 		seq(func(values []T, err error) bool {
-			var value T
-			if err != nil && !yield(value, err) {
-				return false
-			}
-			for _, value = range values {
+			for _, value := range values {
 				if !yield(value, nil) {
 					return false
 				}
 			}
+			if err != nil {
+				var zero T
+				return yield(zero, err)
+			}
 			return true
 		})
 	}
 }
 
-func merge2[T any](cmp func(T, T) int, seq0, seq1 iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
-	return func(yield func([]T, error) bool) {
-		next0, stop0 := iter.Pull2(seq0)
-		defer stop0()
+// kernel2 merges values from a and b into out, stopping when out is full or
+// when either a or b is exhausted. It returns the number of values written to
+// out, and the number of values consumed from a and b.
+type kernel2[T any] func(out, a, b []T) (n, i, j int)
 
-		next1, stop1 := iter.Pull2(seq1)
-		defer stop1()
+// gallopThreshold is the number of consecutive values that compareKernel takes
+// from the same side before it searches for the end of the run.
+const gallopThreshold = 8
 
-		values0, err, ok0 := next0()
-		if err != nil && !yield(nil, err) {
-			return
+// compareKernel is a kernel2 comparing values with the given function.
+//
+// When one side produces gallopThreshold consecutive values, the kernel
+// switches to an exponential search for the first value of this side which is
+// not ordered before the head of the other side, and copies the run at once.
+// This costs O(log r) comparisons for a run of r values instead of r, which is
+// much faster on asymmetric inputs, like merging a large file with a small
+// delta.
+//
+// Equal values are written in the order v0, v1, so values from a are merged
+// before the values from b which compare equal to them, like in the loser-tree.
+func compareKernel[T any](cmp func(T, T) int) kernel2[T] {
+	return func(out, a, b []T) (n, i, j int) {
+		streak0, streak1 := 0, 0
+
+		for i < len(a) && j < len(b) && n < len(out) {
+			v0 := a[i]
+			v1 := b[j]
+
+			if cmp(v0, v1) <= 0 {
+				out[n] = v0
+				n++
+				i++
+				streak0, streak1 = streak0+1, 0
+				if streak0 == gallopThreshold {
+					r := gallop(cmp, a[i:min(len(a), i+len(out)-n)], v1)
+					n += copy(out[n:], a[i:i+r])
+					i += r
+					streak0 = 0
+				}
+			} else {
+				out[n] = v1
+				n++
+				j++
+				streak0, streak1 = 0, streak1+1
+				if streak1 == gallopThreshold {
+					r := gallop(cmp, b[j:min(len(b), j+len(out)-n)], v0)
+					n += copy(out[n:], b[j:j+r])
+					j += r
+					streak1 = 0
+				}
+			}
 		}
+		return n, i, j
+	}
+}
 
-		values1, err, ok1 := next1()
-		if err != nil && !yield(nil, err) {
-			return
+// gallop returns the number of values at the head of s which are ordered before
+// v, using an exponential search.
+func gallop[T any](cmp func(T, T) int, s []T, v T) int {
+	lo, hi := 0, 1
+	for hi < len(s) && cmp(s[hi-1], v) < 0 {
+		lo, hi = hi, 2*hi
+	}
+	hi = min(hi, len(s))
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if cmp(s[mid], v) < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// orderedKernelBlock is the size of the blocks of values that orderedKernel
+// checks for runs.
+const orderedKernelBlock = 32
+
+// orderedKernel is a kernel2 specialized for ordered types, where comparisons
+// are done with operators instead of calling a comparison function.
+//
+// The loop body is written to avoid data-dependent branches, so the compiler
+// can use conditional moves instead, which avoids branch mispredictions when
+// the order of values from a and b is unpredictable. The x != x check orders
+// NaNs first, to match cmp.Compare.
+//
+// Before each block of values, the kernel checks whether the next block of one
+// side is entirely ordered before the head of the other side, and copies it at
+// once if it is. This costs two comparisons per block when values interleave,
+// and saves most of the work on asymmetric inputs.
+func orderedKernel[T cmp.Ordered](out, a, b []T) (n, i, j int) {
+	for i < len(a) && j < len(b) && n < len(out) {
+		if k := i + orderedKernelBlock; k <= len(a) && n+orderedKernelBlock <= len(out) && orderedLess(a[k-1], b[j]) {
+			n += copy(out[n:], a[i:k])
+			i = k
+			continue
+		}
+		if k := j + orderedKernelBlock; k <= len(b) && n+orderedKernelBlock <= len(out) && !orderedLess(a[i], b[k-1]) {
+			n += copy(out[n:], b[j:k])
+			j = k
+			continue
 		}
 
+		for end := min(n+orderedKernelBlock, len(out)); i < len(a) && j < len(b) && n < end; {
+			x, y := a[i], b[j]
+			less := orderedLess(x, y)
+			v := y
+			if less {
+				v = x
+			}
+			out[n] = v
+			n++
+			k := btoi(less)
+			i += k
+			j += 1 - k
+		}
+	}
+	return n, i, j
+}
+
+// orderedLess reports whether x is merged before y by orderedKernel.
+func orderedLess[T cmp.Ordered](x, y T) bool {
+	return x <= y || x != x
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func merge2[T any](cmp func(T, T) int, seq0, seq1 iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return merge2Kernel(compareKernel(cmp), seq0, seq1)
+}
+
+func merge2Ordered[T cmp.Ordered](seq0, seq1 iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return merge2Kernel(orderedKernel[T], seq0, seq1)
+}
+
+// merge2Kernel merges two sequences of slices using the given kernel.
+//
+// The first sequence drives the merge with a regular range loop, only the
+// second sequence is converted to a pull iterator. This halves the number of
+// coroutine switches compared to pulling from both sequences, which would
+// otherwise dominate the cost of merging small values.
+//
+// Like the loser-tree, the kernel yields the error of a batch after the values
+// of the batch were merged.
+func merge2Kernel[T any](kernel kernel2[T], seq0, seq1 iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		next1, stop1 := iter.Pull2(seq1)
+		defer stop1()
+
+		values1, err1, ok1 := next1()
+
 		buffer := make([]T, bufferSize)
 		offset := 0
-		i0 := 0
 		i1 := 0
-		for ok0 && ok1 {
-			for i0 < len(values0) && i1 < len(values1) {
-				v0 := values0[i0]
-				v1 := values1[i1]
 
-				if (offset + 1) >= len(buffer) {
-					if !yield(buffer[:offset], nil) {
+		flush := func(err error) bool {
+			if offset == 0 && err == nil {
+				return true
+			}
+			n := offset
+			offset = 0
+			return yield(buffer[:n], err)
+		}
+
+		for values0, err0 := range seq0 {
+			for i0 := 0; i0 < len(values0); {
+				if !ok1 {
+					if !flush(nil) || !yield(values0[i0:], nil) {
 						return
 					}
-					offset = 0
+					break
 				}
 
-				diff := cmp(v0, v1)
-				switch {
-				case diff < 0:
-					buffer[offset] = v0
-					offset++
-					i0++
-				case diff > 0:
-					buffer[offset] = v1
-					offset++
-					i1++
-				default:
-					buffer[offset+0] = v0
-					buffer[offset+1] = v1
-					offset += 2
-					i0++
-					i1++
-				}
-			}
+				n, d0, d1 := kernel(buffer[offset:], values0[i0:], values1[i1:])
+				offset += n
+				i0 += d0
+				i1 += d1
 
-			if i0 == len(values0) {
-				i0 = 0
-				if values0, err, ok0 = next0(); err != nil && !yield(nil, err) {
-					return
+				if i1 == len(values1) {
+					if err1 != nil && !flush(err1) {
+						return
+					}
+					i1 = 0
+					values1, err1, ok1 = next1()
+				} else if i0 < len(values0) {
+					// The kernel stopped because the output buffer is full.
+					if !flush(nil) {
+						return
+					}
 				}
 			}
 
-			if i1 == len(values1) {
-				i1 = 0
-				if values1, err, ok1 = next1(); err != nil && !yield(nil, err) {
-					return
-				}
+			if err0 != nil && !flush(err0) {
+				return
 			}
 		}
 
-		if offset > 0 && !yield(buffer[:offset], nil) {
+		if !flush(nil) {
 			return
 		}
 
-		values0 = values0[i0:]
-		values1 = values1[i1:]
-
-		for ok0 && yield(values0, nil) {
-			if values0, err, ok0 = next0(); err != nil && !yield(nil, err) {
-				return
-			}
-		}
-
-		for ok1 && yield(values1, nil) {
-			if values1, err, ok1 = next1(); err != nil && !yield(nil, err) {
+		for ok1 {
+			if !yield(values1[i1:], err1) {
 				return
 			}
+			i1 = 0
+			values1, err1, ok1 = next1()
 		}
 	}
 }
 
 func merge[T any](cmp func(T, T) int, seqs []iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return mergeBy(nil, cmp, seqs)
+}
+
+func mergeBy[T, K any](key func(T) K, cmp func(K, K) int, seqs []iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return mergeTree(func() tree[T, K] { return makeKeyTree(key, seqs...) }, cmp)
+}
+
+// mergeTree merges the sequences of the tree returned by newTree, which is
+// called each time the returned sequence is iterated.
+func mergeTree[T, K any](newTree func() tree[T, K], cmp func(K, K) int) iter.Seq2[[]T, error] {
 	return func(yield func([]T, error) bool) {
-		tree := makeTree(seqs...)
+		tree := newTree()
+		tree.drainable = true
 		defer tree.stop()
 
 		buffer := make([]T, bufferSize)
-		for {
+		for !tree.drain(yield) {
 			n, err := tree.next(buffer, cmp)
 			if err == nil && n == 0 {
-				return
+				if tree.count == 0 {
+					return
+				}
+				continue
 			}
 			if !yield(buffer[:n], err) {
 				return
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"math"
+	"math/rand"
 	"slices"
 	"testing"
 	"time"
@@ -48,6 +50,19 @@ func sequence(min, max, step int) iter.Seq2[int, error] {
 	}
 }
 
+// duplicates produces n values where each value is repeated r times.
+//
+//go:noinline
+func duplicates(n, r int) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		for i := range n {
+			if !yield(i/r, nil) {
+				return
+			}
+		}
+	}
+}
+
 func TestMerge(t *testing.T) {
 	for n := range 10 {
 		t.Run(fmt.Sprint(n), func(t *testing.T) {
@@ -95,6 +110,152 @@ func TestMerge2(t *testing.T) {
 	}
 }
 
+func TestMergeBy(t *testing.T) {
+	type record struct {
+		key   string
+		value int
+	}
+
+	calls := 0
+	key := func(r record) int {
+		calls++
+		return r.value
+	}
+
+	seqs := make([]iter.Seq2[record, error], 5)
+	for i := range seqs {
+		seqs[i] = func(yield func(record, error) bool) {
+			for v := range 100 {
+				if v%len(seqs) == i && !yield(record{fmt.Sprint(v), v}, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	merged, err := values(MergeBy(key, seqs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 100 {
+		t.Fatalf("expected 100 values, got %d", len(merged))
+	}
+	for i, r := range merged {
+		if r.value != i {
+			t.Fatalf("expected value %d at index %d, got %d", i, i, r.value)
+		}
+	}
+	if calls != len(merged) {
+		t.Errorf("expected the key function to be called once per value (%d), got %d", len(merged), calls)
+	}
+}
+
+func TestMergeOrdered2(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+
+	for _, n := range []int{0, 1, 10, 1000} {
+		s0 := make([]float64, n)
+		s1 := make([]float64, n/2)
+		for _, s := range [][]float64{s0, s1} {
+			for i := range s {
+				s[i] = float64(prng.Intn(n))
+			}
+			if len(s) > 0 {
+				s[0] = math.NaN()
+			}
+			slices.Sort(s)
+		}
+
+		want := append(slices.Clone(s0), s1...)
+		slices.Sort(want)
+
+		got, err := values(Merge(seqOf(s0...), seqOf(s1...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		equal := func(a, b float64) bool { return a == b || (a != a && b != b) }
+		if !slices.EqualFunc(got, want, equal) {
+			t.Errorf("n=%d: expected %v, got %v", n, want, got)
+		}
+	}
+}
+
+func TestMergeSlice2EmptyBatchesAndErrors(t *testing.T) {
+	errval := errors.New("")
+
+	batches := func(batches ...[]int) iter.Seq2[[]int, error] {
+		return func(yield func([]int, error) bool) {
+			for _, batch := range batches {
+				var err error
+				if batch == nil {
+					err = errval
+				}
+				if !yield(batch, err) {
+					return
+				}
+			}
+		}
+	}
+
+	for _, swap := range []bool{false, true} {
+		seq0 := batches([]int{}, []int{1, 4}, nil, []int{5, 9})
+		seq1 := batches([]int{2}, nil, []int{}, []int{3, 6, 7, 8, 10})
+		if swap {
+			seq0, seq1 = seq1, seq0
+		}
+
+		var got []int
+		var errCount int
+		for values, err := range MergeSliceFunc(cmp.Compare[int], seq0, seq1) {
+			if err != nil {
+				errCount++
+			}
+			got = append(got, values...)
+		}
+		if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if errCount != 2 {
+			t.Errorf("expected 2 errors, got %d", errCount)
+		}
+	}
+}
+
+func TestMerge2ErrorAfterValues(t *testing.T) {
+	errval := errors.New("")
+
+	seq0 := func(yield func([]int, error) bool) {
+		_ = yield([]int{1, 3}, errval) && yield([]int{5}, nil)
+	}
+	seq1 := func(yield func([]int, error) bool) {
+		yield([]int{2, 4, 6}, nil)
+	}
+
+	for _, swap := range []bool{false, true} {
+		seqs := []iter.Seq2[[]int, error]{seq0, seq1}
+		if swap {
+			seqs[0], seqs[1] = seqs[1], seqs[0]
+		}
+
+		var got []int
+		var errs []int
+		for values, err := range MergeSliceFunc(cmp.Compare[int], seqs...) {
+			got = append(got, values...)
+			if err != nil {
+				errs = append(errs, len(got))
+			}
+		}
+		if want := []int{1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		// The error of the first batch of seq0 must be yielded after its last
+		// value (3) was merged, and before the values that follow it.
+		if want := []int{3}; !slices.Equal(errs, want) {
+			t.Errorf("expected error after %v values, got %v", want, errs)
+		}
+	}
+}
+
 func assertCorrectMerge(t *testing.T, seqs []iter.Seq2[int, error]) {
 	want := make([]int, 0)
 	for _, seq := range seqs {
@@ -226,6 +387,16 @@ func TestMergeContinueAfterError3(t *testing.T) {
 	}
 }
 
+func seqOf[T any](values ...T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for _, v := range values {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
 func values[T any](seq iter.Seq2[T, error]) (values []T, err error) {
 	for v, err := range seq {
 		if err != nil {
@@ -261,6 +432,25 @@ func BenchmarkMerge3(b *testing.B) {
 	})
 }
 
+func BenchmarkMergeDuplicates3(b *testing.B) {
+	benchmark(b, func(n int, cmp func(int, int) int) iter.Seq2[int, error] {
+		return MergeFunc(cmp,
+			duplicates(n, 100),
+			duplicates(n, 300),
+			duplicates(n, 200),
+		)
+	})
+}
+
+func BenchmarkMergeOrdered2(b *testing.B) {
+	benchmark(b, func(n int, _ func(int, int) int) iter.Seq2[int, error] {
+		return Merge(
+			sequence(0, n-(n/4), 1),
+			sequence(n/4, n, 2),
+		)
+	})
+}
+
 func benchmark[V cmp.Ordered](b *testing.B, merge func(int, func(V, V) int) iter.Seq2[V, error]) {
 	comparisons := 0
 	compare := func(a, b V) int {
@@ -349,6 +539,15 @@ func BenchmarkMergeSlice3(b *testing.B) {
 	})
 }
 
+func BenchmarkMergeSliceOrdered2(b *testing.B) {
+	benchmarkSlice(b, func(n int, _ func(int, int) int) iter.Seq2[[]int, error] {
+		return MergeSlice(
+			countSlice(n, 100),
+			countSlice(n, 127),
+		)
+	})
+}
+
 func benchmarkSlice[V cmp.Ordered](b *testing.B, merge func(int, func(V, V) int) iter.Seq2[[]V, error]) {
 	comparisons := 0
 	compare := func(a, b V) int {
@@ -372,3 +571,161 @@ func benchmarkSlice[V cmp.Ordered](b *testing.B, merge func(int, func(V, V) int)
 	b.ReportMetric(float64(b.N)/duration.Seconds(), "merge/s")
 	b.ReportMetric(float64(comparisons)/float64(b.N), "comp/op")
 }
+
+func TestMergeSliceLastSequenceIsNotCopied(t *testing.T) {
+	batches := [][]int{{3, 4, 5}, {6, 7}, {8, 9}}
+	last := func(yield func([]int, error) bool) {
+		for _, batch := range batches {
+			if !yield(batch, nil) {
+				return
+			}
+		}
+	}
+
+	var yielded [][]int
+	for values, err := range MergeSlice(
+		func(yield func([]int, error) bool) { yield([]int{0, 1}, nil) },
+		func(yield func([]int, error) bool) { yield([]int{2}, nil) },
+		last,
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		yielded = append(yielded, values)
+	}
+
+	for _, batch := range batches[1:] {
+		if !slices.ContainsFunc(yielded, func(values []int) bool {
+			return len(values) == len(batch) && &values[0] == &batch[0]
+		}) {
+			t.Errorf("batch %v was copied", batch)
+		}
+	}
+}
+
+func TestMerge2Asymmetric(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+
+	for _, n := range []int{10, 1000, 100_000} {
+		large := make([]int, n)
+		for i := range large {
+			large[i] = prng.Intn(n)
+		}
+		slices.Sort(large)
+		small := []int{-1, large[n/3], n / 2, large[n-1], n}
+
+		want := append(slices.Clone(large), small...)
+		slices.Sort(want)
+
+		for _, inputs := range [][2][]int{{large, small}, {small, large}} {
+			comparisons := 0
+			compare := func(a, b int) int {
+				comparisons++
+				return cmp.Compare(a, b)
+			}
+
+			got, err := values(MergeFunc(compare, seqOf(inputs[0]...), seqOf(inputs[1]...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("n=%d: merge with comparison function returned wrong values", n)
+			}
+			if n == 100_000 && comparisons > n/4 {
+				t.Errorf("n=%d: too many comparisons: %d", n, comparisons)
+			}
+
+			got, err = values(Merge(seqOf(inputs[0]...), seqOf(inputs[1]...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("n=%d: merge of ordered values returned wrong values", n)
+			}
+		}
+	}
+}
+
+func TestGallop(t *testing.T) {
+	s := []int{0, 1, 2, 2, 2, 3, 5, 8, 13}
+	for v := -1; v <= 14; v++ {
+		want, _ := slices.BinarySearch(s, v)
+		if got := gallop(cmp.Compare[int], s, v); got != want {
+			t.Errorf("gallop(%d): expected %d, got %d", v, want, got)
+		}
+	}
+}
+
+func BenchmarkMerge2Asymmetric(b *testing.B) {
+	large := make([]int, 100_000)
+	for i := range large {
+		large[i] = 2 * i
+	}
+	small := []int{1, 50_001, 150_001}
+
+	for _, bench := range []struct {
+		name  string
+		merge func(...iter.Seq2[int, error]) iter.Seq2[int, error]
+	}{
+		{"func", func(seqs ...iter.Seq2[int, error]) iter.Seq2[int, error] {
+			return MergeFunc(cmp.Compare[int], seqs...)
+		}},
+		{"ordered", Merge[int]},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for range b.N {
+				for _, err := range bench.merge(seqOf(large...), seqOf(small...)) {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(len(large)*b.N)/b.Elapsed().Seconds(), "merge/s")
+		})
+	}
+}
+
+func TestMergeStableTies(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+	compare := func(a, b tagged) int { return cmp.Compare(a.key, b.key) }
+
+	for _, k := range []int{2, 3, 5, 8} {
+		keys := make([][]int, k)
+		hints := make([]int, k)
+		var want []tagged
+		for i := range keys {
+			keys[i] = make([]int, prng.Intn(300))
+			for j := range keys[i] {
+				keys[i][j] = prng.Intn(20)
+			}
+			slices.Sort(keys[i])
+			hints[i] = prng.Intn(300)
+			for _, key := range keys[i] {
+				want = append(want, tagged{key, i})
+			}
+		}
+		slices.SortStableFunc(want, compare)
+
+		seqs := func() []iter.Seq2[tagged, error] {
+			seqs := make([]iter.Seq2[tagged, error], k)
+			for i := range seqs {
+				seqs[i] = taggedSeq(i, keys[i]...)
+			}
+			return seqs
+		}
+		paths := map[string]iter.Seq2[tagged, error]{
+			"merge":      MergeFunc(compare, seqs()...),
+			"merger":     newMerger(t, compare, seqs()).All(),
+			"size hints": newMerger(t, compare, seqs(), WithSizeHints(hints...)).All(),
+		}
+		for name, seq := range paths {
+			got, err := values(seq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("k=%d %s: equal values are not ordered by source", k, name)
+			}
+		}
+	}
+}
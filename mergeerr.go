@@ -0,0 +1,55 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// MergeErr is like Merge but returns a sequence of values and a function
+// reporting the error that ended the merge, following the pattern of types
+// like bufio.Scanner where the body of the loop does not need to handle
+// errors:
+//
+//	seq, errf := kway.MergeErr(seq0, seq1, seq2)
+//	for v := range seq {
+//		...
+//	}
+//	if err := errf(); err != nil {
+//		...
+//	}
+//
+// The first error produced by one of the sequences stops the merge. The error
+// function returns nil if the merge completed or the loop was exited early.
+//
+// See MergeErrFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func MergeErr[T cmp.Ordered](seqs ...iter.Seq2[T, error]) (iter.Seq[T], func() error) {
+	return untilError(Merge(seqs...))
+}
+
+// MergeErrFunc is like MergeErr but uses the given comparison function to
+// determine the order of values.
+//
+// See MergeErr for more details.
+func MergeErrFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) (iter.Seq[T], func() error) {
+	return untilError(MergeFunc(cmp, seqs...))
+}
+
+// untilError converts seq to a sequence of values which stops at the first
+// error, and a function returning that error.
+func untilError[T any](seq iter.Seq2[T, error]) (iter.Seq[T], func() error) {
+	var err error
+	values := func(yield func(T) bool) {
+		err = nil
+		for v, e := range seq {
+			if e != nil {
+				err = e
+				return
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	return values, func() error { return err }
+}
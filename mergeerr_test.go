@@ -0,0 +1,37 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeErr(t *testing.T) {
+	seq, errf := MergeErr(seqOf(1, 4), seqOf(2, 5), seqOf(3, 6))
+
+	if got, want := slices.Collect(seq), []int{1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if err := errf(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeErrStopsAtError(t *testing.T) {
+	errval := errors.New("")
+
+	seq0 := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(5, nil)
+	}
+
+	seq, errf := MergeErr(seq0, seqOf(2, 3), seqOf(4))
+
+	for v := range seq {
+		if v > 1 {
+			t.Errorf("unexpected value after the error: %d", v)
+		}
+	}
+	if err := errf(); err != errval {
+		t.Errorf("expected %v, got %v", errval, err)
+	}
+}
@@ -0,0 +1,73 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// KeyValue is a value associated with a key.
+type KeyValue[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// MergeMap merges sequences of key/value pairs ordered by key, and combines the
+// values of equal keys with the combine function, yielding one pair for each
+// unique key. A typical use case is merging sorted counters or sketches:
+//
+//	sum := func(a, b int) int { return a + b }
+//
+//	for kv, err := range kway.MergeMap(sum, counters...) {
+//		...
+//	}
+//
+// The order in which values of equal keys are passed to the combine function
+// is unspecified, so it should be associative and commutative. The values of a
+// key are combined as soon as they are produced, the function only retains one
+// value in memory regardless of the number of duplicates.
+//
+// Errors produced by the sequences are yielded inline and do not interrupt the
+// pending combination of values.
+//
+// See MergeMapFunc for a version of this function that allows the caller to
+// pass a custom comparison function for keys.
+func MergeMap[K cmp.Ordered, V any](combine func(V, V) V, seqs ...iter.Seq2[KeyValue[K, V], error]) iter.Seq2[KeyValue[K, V], error] {
+	return MergeMapFunc(cmp.Compare[K], combine, seqs...)
+}
+
+// MergeMapFunc is like MergeMap but uses the given comparison function to
+// determine the order and equality of keys.
+//
+// See MergeMap for more details.
+func MergeMapFunc[K, V any](cmp func(K, K) int, combine func(V, V) V, seqs ...iter.Seq2[KeyValue[K, V], error]) iter.Seq2[KeyValue[K, V], error] {
+	merged := MergeByFunc(keyOf[K, V], cmp, seqs...)
+
+	return func(yield func(KeyValue[K, V], error) bool) {
+		var current KeyValue[K, V]
+		var hasCurrent bool
+
+		for kv, err := range merged {
+			if err != nil {
+				if !yield(KeyValue[K, V]{}, err) {
+					return
+				}
+				continue
+			}
+
+			if hasCurrent && cmp(current.Key, kv.Key) == 0 {
+				current.Value = combine(current.Value, kv.Value)
+				continue
+			}
+			if hasCurrent && !yield(current, nil) {
+				return
+			}
+			current, hasCurrent = kv, true
+		}
+
+		if hasCurrent {
+			yield(current, nil)
+		}
+	}
+}
+
+func keyOf[K, V any](kv KeyValue[K, V]) K { return kv.Key }
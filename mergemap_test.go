@@ -0,0 +1,51 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeMap(t *testing.T) {
+	type kv = KeyValue[string, int]
+	sum := func(a, b int) int { return a + b }
+
+	got, err := values(MergeMap(sum,
+		seqOf(kv{"a", 1}, kv{"b", 2}, kv{"d", 4}),
+		seqOf(kv{"b", 3}, kv{"c", 1}),
+		seqOf(kv{"a", 10}, kv{"b", 1}, kv{"d", 1}, kv{"d", 1}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []kv{{"a", 11}, {"b", 6}, {"c", 1}, {"d", 6}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMergeMapError(t *testing.T) {
+	type kv = KeyValue[int, int]
+	errval := errors.New("")
+	sum := func(a, b int) int { return a + b }
+
+	failing := func(yield func(kv, error) bool) {
+		_ = yield(kv{1, 1}, nil) && yield(kv{}, errval) && yield(kv{2, 1}, nil)
+	}
+
+	var got []kv
+	var errs []error
+	for v, err := range MergeMap(sum, failing, seqOf(kv{1, 1}, kv{2, 2})) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []kv{{1, 2}, {2, 3}}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
@@ -0,0 +1,485 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Merger is a configurable k-way merge of sequences.
+//
+// While the Merge functions cover the common use cases, the Merger type allows
+// applications to customize the merge with options, and to observe the state
+// of the merge through its methods.
+//
+// A Merger is intended to be consumed once, and is not safe to use from
+// multiple goroutines concurrently, except for the Stop method.
+type Merger[T any] struct {
+	cmp  func(T, T) int
+	seqs []iter.Seq2[T, error]
+	opts options
+
+	checkpoint      func([]SourcePosition[T]) error
+	transform       func(int, T) (T, error)
+	watermark       func(T)
+	lastWatermark   T
+	hasWatermark    bool
+	lateDivert      func(int, T)
+	distinct        func(T)
+	duplicates      *duplicateFilter[T]
+	lastDistinct    T
+	hasLastDistinct bool
+	positions       []SourcePosition[T]
+	sinceCheckpoint int
+
+	tree         tree[T, T]
+	treeCmp      func(T, T) int
+	comparePanic *comparePanic[T]
+	comparisons  int64
+	adaptation   adaptation
+	values       []T
+	sources      []int
+	stopMonitor  chan struct{}
+	pull         *pullState[T]
+	reads        []int64
+	progress     progress
+
+	started   atomic.Bool
+	stopping  atomic.Bool
+	done      chan struct{}
+	doneOnce  sync.Once
+	closeOnce sync.Once
+
+	activity []sourceActivity
+	memory   *memoryBudget[T]
+	stats    *Stats[T]
+	errs     []error
+	cancels  []func()
+	failed   []*SourceError
+}
+
+// NewMerger constructs a Merger of the given sequences, using the comparison
+// function to determine the order of values.
+//
+// NewMerger returns an error if the options are invalid: when one of the
+// options was constructed for a different type of values (the error wraps
+// ErrOptionType), when the options are incompatible with WithBoundedMemory, or
+// when the token passed to WithResumeToken does not match the number of
+// sequences. A Merger constructed without options never fails.
+func NewMerger[T any](cmp func(T, T) int, seqs []iter.Seq2[T, error], opts ...Option) (*Merger[T], error) {
+	m := &Merger[T]{
+		cmp:  cmp,
+		seqs: seqs,
+		opts: makeOptions(opts),
+		done: make(chan struct{}),
+	}
+	if err := m.checkBoundedMemory(); err != nil {
+		return nil, err
+	}
+	if m.opts.resume != nil && len(m.opts.resume) != len(seqs) {
+		return nil, fmt.Errorf("resume token has %d offsets for %d sources", len(m.opts.resume), len(seqs))
+	}
+	if len(seqs) == 1 && m.opts.singleSource == SingleSourceValidate && !m.opts.validateOrder {
+		m.opts.validateOrder = true
+		m.opts.unordered = UnorderedError
+	}
+
+	var errs [8]error
+	var format func(T) string
+	var sizeOf func(T) int
+	m.checkpoint, errs[0] = typedOption[func([]SourcePosition[T]) error]("WithCheckpoint", m.opts.checkpoint)
+	m.transform, errs[1] = typedOption[func(int, T) (T, error)]("WithSourceTransform", m.opts.transform)
+	m.watermark, errs[2] = typedOption[func(T)]("WithWatermark", m.opts.watermark)
+	m.lateDivert, errs[3] = typedOption[func(int, T)]("WithLateData", m.opts.lateDivert)
+	m.distinct, errs[4] = typedOption[func(T)]("WithDistinct", m.opts.distinct)
+	m.duplicates, errs[5] = newDuplicateFilter(cmp, len(seqs), m.opts.duplicates)
+	format, errs[6] = typedOption[func(T) string]("WithComparePanicRecovery", m.opts.panicFormat)
+	sizeOf, errs[7] = typedOption[func(T) int]("WithMemoryLimit", m.opts.sizeOf)
+	if err := errors.Join(errs[:]...); err != nil {
+		return nil, err
+	}
+
+	if format != nil {
+		m.comparePanic = &comparePanic[T]{format: format}
+	}
+	m.positions = make([]SourcePosition[T], len(seqs))
+	for i := range m.positions {
+		m.positions[i].Index = i
+		m.positions[i].Label = m.label(i)
+	}
+	if m.opts.idleTimeout > 0 {
+		m.activity = make([]sourceActivity, len(seqs))
+	}
+	if m.opts.memoryLimit > 0 {
+		m.memory = &memoryBudget[T]{
+			limit:  m.opts.memoryLimit,
+			sizeOf: sizeOf,
+		}
+	}
+	if m.opts.stats {
+		m.stats = &Stats[T]{Sources: make([]SourceStats[T], len(seqs))}
+		for i := range m.stats.Sources {
+			m.stats.Sources[i].Index = i
+			m.stats.Sources[i].Label = m.label(i)
+		}
+	}
+	return m, nil
+}
+
+// label returns the label of the source at index i, or the empty string if it
+// has none.
+func (m *Merger[T]) label(i int) string {
+	if i < len(m.opts.labels) {
+		return m.opts.labels[i]
+	}
+	return ""
+}
+
+func (m *Merger[T]) sourceError(i int, err error) error {
+	return &SourceError{Index: i, Label: m.label(i), Err: err}
+}
+
+// All returns a sequence yielding the merged values.
+//
+// Errors produced by the sources are yielded as *SourceError values, which
+// identify the source that the error originated from.
+//
+// See Merge for more details.
+func (m *Merger[T]) All() iter.Seq2[T, error] {
+	if seq := m.passthrough(); seq != nil {
+		return seq
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		fail := func(err error) bool { return yield(zero, err) }
+		m.run(func(values []T, sources []int) bool {
+			for i, value := range values {
+				if !yield(value, nil) {
+					return false
+				}
+				if !m.observe(values[i:i+1], sources[i:i+1], fail) {
+					return false
+				}
+			}
+			return true
+		}, fail)
+	}
+}
+
+// Batches returns a sequence yielding the merged values in batches.
+//
+// The batches are reused across iterations, the application must not retain
+// them beyond the body of the loop ranging over the sequence.
+//
+// See All and MergeSlice for more details.
+func (m *Merger[T]) Batches() iter.Seq2[[]T, error] {
+	if seq := m.passthrough(); seq != nil {
+		return buffer(bufferSize, seq)
+	}
+	return func(yield func([]T, error) bool) {
+		fail := func(err error) bool { return yield(nil, err) }
+		m.run(func(values []T, sources []int) bool {
+			return yield(values, nil) && m.observe(values, sources, fail)
+		}, fail)
+	}
+}
+
+// run drives the merge, calling emit with each batch of merged values and the
+// indexes of the sources they were read from, and fail with errors.
+func (m *Merger[T]) run(emit func([]T, []int) bool, fail func(error) bool) {
+	defer m.finish()
+	if !m.start() {
+		return
+	}
+
+	if m.opts.lateData {
+		emit = m.filterLate(emit, fail)
+	}
+
+	for m.step(emit, fail) {
+	}
+}
+
+// start prepares the sources and the tree of the merge. It returns false if the
+// Merger was stopped before the merge started, in which case the sources must
+// not be read.
+func (m *Merger[T]) start() bool {
+	// Stop sets stopping before checking started, and start sets started before
+	// checking stopping: either Stop observes that the merge started and waits
+	// for it to end, or the merge observes that it was stopped.
+	m.started.Store(true)
+	if m.stopping.Load() {
+		return false
+	}
+
+	if m.activity != nil {
+		now := time.Now().UnixNano()
+		for i := range m.activity {
+			m.activity[i].last.Store(now)
+		}
+		m.stopMonitor = make(chan struct{})
+		go m.monitor(m.stopMonitor)
+	}
+
+	if m.reads == nil {
+		m.reads = make([]int64, len(m.seqs))
+	}
+
+	seqs := make([]iter.Seq2[[]T, error], len(m.seqs))
+	for i, seq := range m.seqs {
+		if m.opts.dropFailed {
+			seq = markFailures(seq)
+		}
+		if m.opts.resume != nil {
+			seq = skipValues(m.opts.resume[i], seq)
+		}
+		if m.activity != nil {
+			seq = trackActivity(&m.activity[i], seq)
+		}
+		var flush func(T) bool
+		if m.memory != nil {
+			flush = m.memory.acquire
+		}
+		// When sources are read concurrently, bufferInterval watches the
+		// context so it does not wait on sources blocked on reads. Otherwise
+		// the contexts are checked between values, starting with the merge
+		// context, which propagates its cancellation to the context of the
+		// source asynchronously.
+		ctx := m.deadlineContext(m.sourceContext(i))
+		if m.opts.flushInterval > 0 {
+			seqs[i] = bufferInterval(ctx, bufferSize, m.opts.flushInterval, flush, seq)
+		} else {
+			seqs[i] = bufferFunc(bufferSize, flush, withContext(seq, m.context(), ctx))
+		}
+		seqs[i] = m.countReads(i, seqs[i])
+		if m.transform != nil {
+			seqs[i] = transformBatches(i, m.transform, m.memory, seqs[i])
+		}
+		if m.opts.validateOrder {
+			cmp := m.cmp
+			if m.comparePanic != nil {
+				cmp = m.comparePanic.wrap(cmp, func() []int { return []int{i, i} })
+			}
+			seqs[i] = orderBatches(cmp, m.opts.unordered, m.memory, seqs[i], func(err error) error { return err })
+		}
+		if m.opts.dropFailed {
+			seqs[i] = stopOnFailure(seqs[i])
+		}
+		seqs[i] = m.stoppable(seqs[i])
+		if m.stats != nil {
+			seqs[i] = m.measureBlocking(i, seqs[i])
+		}
+	}
+
+	m.tree.reset(seqs...)
+	if m.opts.sizeHints != nil {
+		m.tree.reorder(sizeOrder(len(seqs), m.opts.sizeHints))
+	}
+	m.tree.eager = m.opts.flushInterval > 0 || m.memory != nil
+	m.tree.fair = m.opts.fairTies
+
+	m.treeCmp = m.cmp
+	if m.stats != nil || m.opts.adaptive {
+		m.treeCmp = func(a, b T) int {
+			m.comparisons++
+			return m.cmp(a, b)
+		}
+	}
+	if m.comparePanic != nil {
+		m.treeCmp = m.comparePanic.wrap(m.treeCmp, func() []int {
+			return []int{m.tree.compared[0], m.tree.compared[1]}
+		})
+		if d := m.duplicates; d != nil {
+			d.cmp = m.comparePanic.wrap(m.cmp, func() []int {
+				return []int{d.compared[0], d.compared[1]}
+			})
+		}
+	}
+	if m.opts.adaptive && m.adaptation.wins == nil {
+		m.adaptation.wins = make([]int64, len(seqs))
+	}
+
+	if m.values == nil {
+		m.values = make([]T, bufferSize)
+		m.sources = make([]int, bufferSize)
+	}
+	return true
+}
+
+// finish releases the resources held by the merge, it may be called multiple
+// times.
+func (m *Merger[T]) finish() {
+	m.doneOnce.Do(func() {
+		m.tree.stop()
+		if m.stopMonitor != nil {
+			close(m.stopMonitor)
+		}
+		for _, cancel := range m.cancels {
+			cancel()
+		}
+		close(m.done)
+	})
+}
+
+// step produces the next batch of merged values, calling emit and fail like
+// run. It returns false when the merge ended, or when emit or fail returned
+// false.
+func (m *Merger[T]) step(emit func([]T, []int) bool, fail func(error) bool) bool {
+	defer m.publishProgress()
+
+	if m.expired() {
+		// The values buffered in the cursor of the winner are ordered before
+		// the heads of all other cursors, they are yielded until a cursor must
+		// be read from.
+		if !m.tree.buffered() {
+			return m.expire(emit, fail)
+		}
+		m.tree.eager = true
+	}
+
+	values, sources := m.values, m.sources
+
+	n, err := m.next(values, sources)
+	if m.stats != nil {
+		m.stats.Comparisons = m.comparisons
+	}
+	if m.comparePanic != nil && m.comparePanic.err != nil {
+		fail(m.comparePanic.err)
+		return false
+	}
+	if err == nil && n == 0 {
+		if m.duplicates != nil {
+			values, sources := m.duplicates.end(m.creditDuplicates)
+			if len(values) > 0 && !emit(values, sources) {
+				return false
+			}
+		}
+		if m.sinceCheckpoint > 0 {
+			m.commit(fail)
+		}
+		return false
+	}
+	if n > 0 {
+		output, outputSources := values[:n], sources[:n]
+		if m.duplicates != nil {
+			output, outputSources = m.filterDuplicates(output, outputSources)
+			if m.comparePanic != nil && m.comparePanic.err != nil {
+				fail(m.comparePanic.err)
+				return false
+			}
+		}
+		ok := true
+		if len(output) > 0 {
+			if err := m.wait(len(output)); err != nil {
+				fail(err)
+				return false
+			}
+			ok = emit(output, outputSources)
+		}
+		if m.memory != nil {
+			m.memory.release(values[:n])
+		}
+		if !ok {
+			return false
+		}
+		if m.watermark != nil {
+			m.advanceWatermark(&m.tree)
+		}
+		if m.opts.adaptive {
+			m.adapt(sources[:n])
+		}
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && m.expired() {
+			return m.expire(emit, fail)
+		}
+		if m.canceled(err) {
+			fail(err)
+			return false
+		}
+		var failure *sourceFailure
+		if m.opts.dropFailed && errors.As(err, &failure) {
+			return m.dropSource(m.tree.errSource, failure.err, fail)
+		}
+		err = m.sourceError(m.tree.errSource, err)
+		if m.opts.collectErrors {
+			m.errs = append(m.errs, err)
+		} else if !fail(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// observe records that the values read from the sources at the given indexes
+// were yielded to the application.
+func (m *Merger[T]) observe(values []T, sources []int, fail func(error) bool) bool {
+	for i, source := range sources {
+		p := &m.positions[source]
+		p.Count++
+		p.Last = values[i]
+	}
+
+	if m.duplicates != nil {
+		m.duplicates.observe(len(values), m.creditDuplicates)
+	}
+
+	if m.stats != nil {
+		m.collectStats(values, sources)
+	}
+
+	if m.distinct != nil {
+		m.observeDistinct(values)
+	}
+
+	if m.checkpoint != nil {
+		if m.sinceCheckpoint += len(values); m.sinceCheckpoint >= m.opts.checkpointEvery {
+			return m.commit(fail)
+		}
+	}
+	return true
+}
+
+func (m *Merger[T]) commit(fail func(error) bool) bool {
+	m.sinceCheckpoint = 0
+	if m.checkpoint == nil {
+		return true
+	}
+	positions := make([]SourcePosition[T], len(m.positions))
+	copy(positions, m.positions)
+	if err := m.checkpoint(positions); err != nil {
+		return fail(err)
+	}
+	return true
+}
+
+// wait blocks until the rate limiters configured on the Merger allow n more
+// values to be produced.
+func (m *Merger[T]) wait(n int) error {
+	ctx := m.context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l := m.opts.valueLimiter; l != nil {
+		if err := waitN(ctx, l, n); err != nil {
+			return err
+		}
+	}
+	if l := m.opts.batchLimiter; l != nil {
+		if err := l.WaitN(ctx, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Merger[T]) context() context.Context {
+	if m.opts.context != nil {
+		return m.opts.context
+	}
+	return context.Background()
+}
@@ -0,0 +1,178 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+// newMerger is like NewMerger, but fails the test if the options are invalid.
+func newMerger[T any](t testing.TB, cmp func(T, T) int, seqs []iter.Seq2[T, error], opts ...Option) *Merger[T] {
+	t.Helper()
+	m, err := NewMerger(cmp, seqs, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMerger(t *testing.T) {
+	for n := range 10 {
+		seqs := make([]iter.Seq2[int, error], n)
+		for i := range seqs {
+			seqs[i] = sequence(i, 100, i+1)
+		}
+
+		want, err := values(MergeFunc(cmp.Compare[int], seqs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := values(newMerger(t, cmp.Compare[int], seqs).All())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("k=%d: expected %v, got %v", n, want, got)
+		}
+	}
+}
+
+func TestMergerCheckpoint(t *testing.T) {
+	var checkpoints [][]SourcePosition[int]
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			seqOf(0, 2, 4, 6),
+			seqOf(1, 3, 5),
+		},
+		WithCheckpoint(3, func(positions []SourcePosition[int]) error {
+			checkpoints = append(checkpoints, positions)
+			return nil
+		}),
+	)
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	want := [][]SourcePosition[int]{
+		{{Index: 0, Count: 2, Last: 2}, {Index: 1, Count: 1, Last: 1}},
+		{{Index: 0, Count: 3, Last: 4}, {Index: 1, Count: 3, Last: 5}},
+		{{Index: 0, Count: 4, Last: 6}, {Index: 1, Count: 3, Last: 5}},
+	}
+	if !slices.EqualFunc(checkpoints, want, slices.Equal) {
+		t.Errorf("expected checkpoints %v, got %v", want, checkpoints)
+	}
+}
+
+func TestMergerCheckpointError(t *testing.T) {
+	errval := errors.New("")
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(0, 1, 2, 3)},
+		WithCheckpoint(2, func([]SourcePosition[int]) error { return errval }),
+	)
+
+	errCount := 0
+	for _, err := range m.All() {
+		if err != nil {
+			if err != errval {
+				t.Fatal(err)
+			}
+			errCount++
+		}
+	}
+	if errCount != 2 {
+		t.Errorf("expected 2 errors, got %d", errCount)
+	}
+}
+
+func TestMergerOptionTypeMismatch(t *testing.T) {
+	_, err := NewMerger(cmp.Compare[int], nil,
+		WithCheckpoint(1, func([]SourcePosition[string]) error { return nil }),
+	)
+	if !errors.Is(err, ErrOptionType) {
+		t.Errorf("expected an option type error, got %v", err)
+	}
+}
+
+func TestMergerSourceError(t *testing.T) {
+	errval := errors.New("oops")
+
+	failing := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(3, nil)
+	}
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(0, 2, 4), failing},
+		WithLabels("a.log", "b.log"),
+	)
+
+	var got []int
+	var errs []error
+	for v, err := range m.All() {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+
+	var sourceErr *SourceError
+	if !errors.As(errs[0], &sourceErr) {
+		t.Fatalf("expected *SourceError, got %T", errs[0])
+	}
+	if sourceErr.Index != 1 || sourceErr.Label != "b.log" || !errors.Is(sourceErr, errval) {
+		t.Errorf("unexpected source error: %+v", sourceErr)
+	}
+	if msg := sourceErr.Error(); msg != `kway: source "b.log": oops` {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+}
+
+func TestMergerCollectErrors(t *testing.T) {
+	errval := errors.New("failed")
+
+	seq0 := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(3, nil) && yield(0, errval)
+	}
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seq0, seqOf(2, 4)},
+		WithLabels("a", "b"),
+		WithCollectErrors(),
+	)
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatalf("unexpected error yielded inline: %v", err)
+	}
+	if want := []int{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	err = m.Err()
+	if !errors.Is(err, errval) {
+		t.Fatalf("expected %v, got %v", errval, err)
+	}
+	if errs := err.(interface{ Unwrap() []error }).Unwrap(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %d", len(errs))
+	}
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Label != "a" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package kway
+
+import "iter"
+
+// Monotonic guards the output of a merge against values which regress compared
+// to the previously yielded value, which could otherwise corrupt sorted data
+// produced downstream (e.g. when a merge is misconfigured with a comparison
+// function that does not match the order of its sources).
+//
+// The policy determines how regressions are handled: UnorderedDrop silently
+// drops the values, UnorderedError drops the values and yields ErrUnordered in
+// their place. Equal values are not considered regressions. Monotonic panics if
+// called with UnorderedSort, since a sequence cannot be repaired after values
+// were yielded.
+//
+// The function returned alongside the sequence reports the number of values
+// that were dropped.
+func Monotonic[T any](cmp func(T, T) int, seq iter.Seq2[T, error], policy UnorderedPolicy) (iter.Seq2[T, error], func() int) {
+	if policy == UnorderedSort {
+		panic("kway: monotonic sequences cannot be sorted")
+	}
+	violations := 0
+	guarded := func(yield func(T, error) bool) {
+		var zero, last T
+		var hasLast bool
+		violations = 0
+
+		for v, err := range seq {
+			if err != nil {
+				if !yield(v, err) {
+					return
+				}
+				continue
+			}
+			if hasLast && cmp(v, last) < 0 {
+				violations++
+				if policy == UnorderedError && !yield(zero, ErrUnordered) {
+					return
+				}
+				continue
+			}
+			last, hasLast = v, true
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+	return guarded, func() int { return violations }
+}
@@ -0,0 +1,45 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMonotonic(t *testing.T) {
+	input := seqOf(1, 2, 2, 1, 3, 0, 4)
+
+	seq, violations := Monotonic(cmp.Compare[int], input, UnorderedDrop)
+	got, err := values(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if n := violations(); n != 2 {
+		t.Errorf("expected 2 violations, got %d", n)
+	}
+}
+
+func TestMonotonicError(t *testing.T) {
+	seq, violations := Monotonic(cmp.Compare[int], seqOf(1, 3, 2, 4), UnorderedError)
+
+	var got []int
+	for v, err := range seq {
+		if err != nil {
+			if !errors.Is(err, ErrUnordered) {
+				t.Fatal(err)
+			}
+			continue
+		}
+		got = append(got, v)
+	}
+	if want := []int{1, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if n := violations(); n != 1 {
+		t.Errorf("expected 1 violation, got %d", n)
+	}
+}
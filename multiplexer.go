@@ -0,0 +1,186 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"sync"
+)
+
+var (
+	// ErrBeforeWatermark is the error reported by a Multiplexer when a source
+	// produces a value ordered before the last value it yielded.
+	ErrBeforeWatermark = errors.New("value ordered before the watermark of the merge")
+
+	// ErrMultiplexerClosed is the error returned when adding a source to a
+	// Multiplexer that was closed.
+	ErrMultiplexerClosed = errors.New("source added to a closed multiplexer")
+)
+
+// Multiplexer is a k-way merge of sources which can be added while the merge is
+// running, for example to merge sorted segments which are continuously
+// discovered (e.g. new files flushed during a long compaction).
+//
+// The values of a source added to a running merge must not be ordered before
+// the values already yielded by the Multiplexer, its watermark is the last of
+// those values. This is enforced when the values are read: values ordered
+// before the watermark are dropped and reported as ErrBeforeWatermark, wrapped
+// in a *SourceError. Since values are merged and yielded in batches, sources
+// added while a batch is being yielded are only read after the watermark moved
+// to the end of the batch; applications should add sources with a margin after
+// the watermark returned by the Watermark method, or tolerate the errors.
+//
+// The merge ends when all the sources are exhausted and the Multiplexer was
+// closed. Until then, the merge blocks waiting for new sources when all the
+// sources are exhausted.
+type Multiplexer[T any] struct {
+	cmp func(T, T) int
+
+	mutex        sync.Mutex
+	pending      []iter.Seq2[[]T, error]
+	sources      int
+	closed       bool
+	watermark    T
+	hasWatermark bool
+	notify       chan struct{}
+}
+
+// NewMultiplexer constructs a Multiplexer merging the given sequences, using
+// the comparison function to determine the order of values.
+func NewMultiplexer[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) *Multiplexer[T] {
+	m := &Multiplexer[T]{cmp: cmp, notify: make(chan struct{}, 1)}
+	for _, seq := range seqs {
+		m.Add(seq)
+	}
+	return m
+}
+
+// Add adds a source to the merge, and returns its index, which identifies the
+// source in the *SourceError values yielded by the merge. The method is safe
+// to call concurrently with the iteration over the sequence returned by All.
+//
+// The method returns ErrMultiplexerClosed if the Multiplexer was closed.
+func (m *Multiplexer[T]) Add(seq iter.Seq2[T, error]) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return -1, ErrMultiplexerClosed
+	}
+	index := m.sources
+	m.sources++
+	m.pending = append(m.pending, bufferFunc(bufferSize, nil, m.fence(index, seq)))
+	m.signal()
+	return index, nil
+}
+
+// Close signals that no more sources will be added, the merge ends once all the
+// sources are exhausted.
+func (m *Multiplexer[T]) Close() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.closed = true
+	m.signal()
+}
+
+// Watermark returns the watermark of the merge, and false if it did not yield
+// any values yet. The watermark is updated after each batch of values yielded
+// by the merge. The method is safe to call concurrently with the iteration over
+// the sequence returned by All.
+func (m *Multiplexer[T]) Watermark() (T, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.watermark, m.hasWatermark
+}
+
+// advance sets the watermark to the last value yielded by the merge.
+func (m *Multiplexer[T]) advance(value T) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.watermark, m.hasWatermark = value, true
+}
+
+func (m *Multiplexer[T]) signal() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the sources added since the last call, and whether the
+// Multiplexer was closed.
+func (m *Multiplexer[T]) take() ([]iter.Seq2[[]T, error], bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	pending := m.pending
+	m.pending = nil
+	return pending, m.closed
+}
+
+// fence returns a sequence yielding the values of seq, replacing the values
+// ordered before the watermark with ErrBeforeWatermark errors.
+func (m *Multiplexer[T]) fence(index int, seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for value, err := range seq {
+			if err == nil {
+				// The watermark is only written by the goroutine running
+				// the merge, which is also the one reading the sources.
+				if m.hasWatermark && m.cmp(value, m.watermark) < 0 {
+					err = ErrBeforeWatermark
+				}
+			}
+			if err != nil {
+				var zero T
+				err = &SourceError{Index: index, Err: err}
+				value = zero
+			}
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
+}
+
+// All returns a sequence yielding the merged values of the sources. The
+// sequence can only be iterated once, after which the Multiplexer is closed.
+//
+// See Multiplexer for more details.
+func (m *Multiplexer[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer m.Close()
+
+		tree := makeTree[T]()
+		defer tree.stop()
+
+		var zero T
+		buffer := make([]T, bufferSize)
+		for {
+			pending, closed := m.take()
+			if len(pending) > 0 {
+				tree.add(m.cmp, pending...)
+			}
+
+			n, err := tree.next(buffer, m.cmp)
+			if n == 0 && err == nil {
+				switch {
+				case !closed:
+					<-m.notify
+				case len(pending) == 0:
+					return
+				}
+				continue
+			}
+
+			for i, value := range buffer[:n] {
+				if !yield(value, nil) {
+					m.advance(buffer[i])
+					return
+				}
+			}
+			if n > 0 {
+				m.advance(buffer[n-1])
+			}
+			if err != nil && !yield(zero, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMultiplexer(t *testing.T) {
+	m := NewMultiplexer(cmp.Compare[int], sequence(0, 10, 2), sequence(1, 10, 2))
+	m.Close()
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(10)); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, err := m.Add(seqOf(10)); err != ErrMultiplexerClosed {
+		t.Errorf("expected ErrMultiplexerClosed, got %v", err)
+	}
+}
+
+func TestMultiplexerAddWhileRunning(t *testing.T) {
+	m := NewMultiplexer(cmp.Compare[int], sequence(0, 1000, 2))
+
+	var got []int
+	for v, err := range m.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+
+		switch v {
+		case 100:
+			// Added after the first batch was yielded, the source is
+			// merged with the rest of the first source.
+			if _, err := m.Add(sequence(501, 1000, 2)); err != nil {
+				t.Fatal(err)
+			}
+		case 998:
+			// All the sources are exhausted, the merge waits for new
+			// sources until the multiplexer is closed.
+			go func() {
+				m.Add(seqOf(1000, 1001))
+				m.Close()
+			}()
+		}
+	}
+
+	evens, _ := values(sequence(0, 1000, 2))
+	odds, _ := values(sequence(501, 1000, 2))
+	want := slices.Concat(evens, odds, []int{1000, 1001})
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %d values, got %d", len(want), len(got))
+	}
+	if w, ok := m.Watermark(); !ok || w != 1001 {
+		t.Errorf("unexpected watermark: %d, %t", w, ok)
+	}
+}
+
+func TestMultiplexerBeforeWatermark(t *testing.T) {
+	m := NewMultiplexer(cmp.Compare[int], sequence(0, 1000, 1))
+
+	var errs []error
+	var got []int
+	for v, err := range m.All() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		got = append(got, v)
+		if v == 500 {
+			m.Add(seqOf(10, 2000))
+			m.Close()
+		}
+	}
+
+	if len(got) != 1001 || got[len(got)-1] != 2000 {
+		t.Errorf("unexpected values: %d values ending with %d", len(got), got[len(got)-1])
+	}
+	var sourceErr *SourceError
+	if len(errs) != 1 || !errors.As(errs[0], &sourceErr) || sourceErr.Index != 1 || !errors.Is(errs[0], ErrBeforeWatermark) {
+		t.Errorf("expected ErrBeforeWatermark from source 1, got %v", errs)
+	}
+}
@@ -0,0 +1,46 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Multiplicity merges multiple sorted sequences and yields each distinct value
+// paired with the number of sequences that contained it, which is useful to
+// analyze the overlap between sorted datasets in a single pass.
+//
+// Unlike counting the duplicates of a value, a value repeated within a single
+// sequence only counts once, so the multiplicity of a value is always between
+// 1 and the number of sequences.
+//
+// Since the returned sequence pairs values with their multiplicity, errors are
+// not yielded inline: the first error produced by one of the sequences ends the
+// merge, and is returned by the function returned alongside the sequence once
+// iteration completes.
+//
+// See MultiplicityFunc for a version of this function that allows the caller
+// to pass a custom comparison function.
+func Multiplicity[T cmp.Ordered](seqs ...iter.Seq2[T, error]) (iter.Seq2[T, int], func() error) {
+	return MultiplicityFunc(cmp.Compare[T], seqs...)
+}
+
+// MultiplicityFunc is like Multiplicity but uses the given comparison function
+// to determine the order and equality of values.
+//
+// See Multiplicity for more details.
+func MultiplicityFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) (iter.Seq2[T, int], func() error) {
+	var err error
+	seq := func(yield func(T, int) bool) {
+		err = nil
+		for c, e := range QuorumCountFunc(1, cmp, seqs...) {
+			if e != nil {
+				err = e
+				return
+			}
+			if !yield(c.Value, c.Count) {
+				return
+			}
+		}
+	}
+	return seq, func() error { return err }
+}
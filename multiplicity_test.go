@@ -0,0 +1,44 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMultiplicity(t *testing.T) {
+	seq, done := Multiplicity(
+		seqOf(1, 1, 2, 5),
+		seqOf(1, 3, 5),
+		seqOf(1, 2, 3, 4, 5),
+	)
+
+	var got []Counted[int]
+	for v, n := range seq {
+		got = append(got, Counted[int]{v, n})
+	}
+	if err := done(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Counted[int]{{1, 3}, {2, 2}, {3, 2}, {4, 1}, {5, 3}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMultiplicityError(t *testing.T) {
+	errval := errors.New("")
+
+	seq0 := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(2, nil)
+	}
+
+	seq, done := Multiplicity(seq0, seqOf(1, 2))
+
+	for range seq {
+	}
+	if err := done(); err != errval {
+		t.Errorf("expected %v, got %v", errval, err)
+	}
+}
@@ -0,0 +1,70 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+)
+
+// MergeError is the value that the Must functions panic with when one of the
+// merged sources produces an error. The embedded *SourceError identifies the
+// source that the error originated from.
+//
+// Errors which were not produced by a source, such as the cancellation of a
+// context configured with WithContext, have a negative source index.
+type MergeError struct {
+	*SourceError
+}
+
+// Error satisfies the error interface.
+func (e *MergeError) Error() string {
+	if e.Index < 0 {
+		return "kway: " + e.Err.Error()
+	}
+	return e.SourceError.Error()
+}
+
+// MustMerge is like Merge but panics with a *MergeError if one of the sequences
+// produces an error. It is intended for tests and tools where errors are not
+// expected, and saves the error checks in the body of loops.
+//
+// See MustMergeFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func MustMerge[T cmp.Ordered](seqs ...iter.Seq2[T, error]) iter.Seq[T] {
+	return MustMergeFunc(cmp.Compare[T], seqs...)
+}
+
+// MustMergeFunc is like MustMerge but uses the given comparison function to
+// determine the order of values.
+//
+// See MustMerge for more details.
+func MustMergeFunc[T any](cmp func(T, T) int, seqs ...iter.Seq2[T, error]) iter.Seq[T] {
+	m, _ := NewMerger(cmp, seqs) // cannot fail without options
+	return m.Must()
+}
+
+// Must returns a sequence yielding the merged values, which panics with a
+// *MergeError if an error occurs. Labels configured with WithLabels are
+// reported in the errors.
+//
+// See MustMerge for more details.
+func (m *Merger[T]) Must() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v, err := range m.All() {
+			if err != nil {
+				panic(mergeError(err))
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func mergeError(err error) *MergeError {
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) {
+		sourceErr = &SourceError{Index: -1, Err: err}
+	}
+	return &MergeError{sourceErr}
+}
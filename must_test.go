@@ -0,0 +1,40 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMustMerge(t *testing.T) {
+	got := slices.Collect(MustMerge(seqOf(1, 4), seqOf(2, 5), seqOf(3, 6)))
+	if want := []int{1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMustMergePanics(t *testing.T) {
+	errval := errors.New("failed")
+
+	seq1 := func(yield func(int, error) bool) {
+		_ = yield(2, nil) && yield(0, errval)
+	}
+
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{seqOf(1, 3), seq1}, WithLabels("a", "b"))
+
+	defer func() {
+		e, ok := recover().(*MergeError)
+		if !ok {
+			t.Fatalf("expected *MergeError, got %v", e)
+		}
+		if e.Index != 1 || e.Label != "b" || !errors.Is(e, errval) {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}()
+
+	for range m.Must() {
+	}
+	t.Error("expected a panic")
+}
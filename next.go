@@ -0,0 +1,103 @@
+package kway
+
+import "iter"
+
+// pullState is the state of a Merger consumed with Next.
+type pullState[T any] struct {
+	// Queue of batches and errors produced by the last step of the merge,
+	// which Next returns one value at a time.
+	events []pullEvent[T]
+	emit   func([]T, []int) bool
+	fail   func(error) bool
+	done   bool
+	// When the single source of the Merger is passed through, values are
+	// pulled from it directly.
+	next func() (T, error, bool)
+	stop func()
+}
+
+type pullEvent[T any] struct {
+	values  []T
+	sources []int
+	err     error
+}
+
+// Next returns the next merged value, for applications that cannot consume the
+// merge with a for-range loop (e.g. code structured around cursors). The last
+// return value is false when the merge has ended.
+//
+// Next drives the merge directly, which is cheaper than converting the sequence
+// returned by All with iter.Pull2. A Merger must either be consumed with Next
+// or by ranging over one of its sequences, the two cannot be mixed.
+//
+// Applications which stop calling Next before the end of the merge must call
+// Stop to release the resources held by the Merger.
+func (m *Merger[T]) Next() (T, error, bool) {
+	var zero T
+
+	p := m.pull
+	if p == nil {
+		if seq := m.passthrough(); seq != nil {
+			p = &pullState[T]{}
+			p.next, p.stop = iter.Pull2(seq)
+			m.pull = p
+		}
+	}
+	if p != nil && p.next != nil {
+		if p.done {
+			return zero, nil, false
+		}
+		v, err, ok := p.next()
+		p.done = !ok
+		return v, err, ok
+	}
+	if p == nil {
+		p = &pullState[T]{}
+		p.fail = func(err error) bool {
+			p.events = append(p.events, pullEvent[T]{err: err})
+			return true
+		}
+		p.emit = func(values []T, sources []int) bool {
+			p.events = append(p.events, pullEvent[T]{values: values, sources: sources})
+			return true
+		}
+		if m.opts.lateData {
+			p.emit = m.filterLate(p.emit, p.fail)
+		}
+		m.pull = p
+		if !m.start() {
+			p.done = true
+			m.finish()
+		}
+	}
+
+	for {
+		if len(p.events) > 0 {
+			e := p.events[0]
+			if e.err != nil {
+				p.events = p.events[1:]
+				return zero, e.err, true
+			}
+			if len(e.values) == 0 {
+				p.events = p.events[1:]
+				m.publishProgress()
+				continue
+			}
+			p.events[0].values = e.values[1:]
+			p.events[0].sources = e.sources[1:]
+			m.observe(e.values[:1], e.sources[:1], p.fail)
+			return e.values[0], nil, true
+		}
+
+		if p.done {
+			return zero, nil, false
+		}
+		p.events = p.events[:0]
+		if !m.step(p.emit, p.fail) {
+			p.done = true
+			// The last step may have queued errors, the resources of the
+			// merge are released right away since no more steps are taken.
+			m.finish()
+		}
+	}
+}
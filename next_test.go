@@ -0,0 +1,90 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerNext(t *testing.T) {
+	errval := errors.New("")
+
+	failing := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(300, nil)
+	}
+
+	var positions []SourcePosition[int]
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{sequence(0, 300, 2), failing},
+		WithCheckpoint(1000, func(p []SourcePosition[int]) error {
+			positions = p
+			return nil
+		}),
+	)
+
+	var got []int
+	var errs []error
+	for {
+		v, err, ok := m.Next()
+		if !ok {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+
+	want, _ := values(sequence(0, 300, 2))
+	want = append(want, 300)
+	want = slices.Insert(want, 1, 1)
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errval) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if len(positions) != 2 || positions[0].Count != 150 || positions[1].Count != 2 {
+		t.Errorf("unexpected positions: %+v", positions)
+	}
+
+	if _, _, ok := m.Next(); ok {
+		t.Error("expected the merge to remain ended")
+	}
+}
+
+func TestMergerNextStop(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{count(1000), count(1000)})
+
+	for range 10 {
+		if _, err, ok := m.Next(); !ok || err != nil {
+			t.Fatal(err, ok)
+		}
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := m.Next(); ok {
+		t.Error("expected the merge to be stopped")
+	}
+}
+
+func BenchmarkMergerNext(b *testing.B) {
+	m := newMerger(b, cmp.Compare[int], []iter.Seq2[int, error]{count(b.N), count(b.N), count(b.N)})
+	for range b.N {
+		m.Next()
+	}
+}
+
+func BenchmarkMergerPullAll(b *testing.B) {
+	m := newMerger(b, cmp.Compare[int], []iter.Seq2[int, error]{count(b.N), count(b.N), count(b.N)})
+	next, stop := iter.Pull2(m.All())
+	defer stop()
+	for range b.N {
+		next()
+	}
+}
@@ -0,0 +1,124 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Option is a functional option used to configure a Merger.
+//
+// Options that depend on the type of values being merged are constructed by
+// generic functions (e.g. WithCheckpoint), and must be used with a Merger of
+// the same value type, or NewMerger returns an error wrapping ErrOptionType.
+type Option func(*options)
+
+// ErrOptionType is the error returned when an option constructed for a type of
+// values is used to merge values of a different type.
+var ErrOptionType = errors.New("option used with a different type of values")
+
+type options struct {
+	checkpointEvery int
+	checkpoint      any // func([]SourcePosition[T]) error
+	labels          []string
+	context         context.Context
+	valueLimiter    Limiter
+	batchLimiter    Limiter
+	flushInterval   time.Duration
+	idleTimeout     time.Duration
+	onIdle          func(SourceHealth)
+	memoryLimit     int64
+	sizeOf          any // func(T) int
+	stats           bool
+	transform       any // func(int, T) (T, error)
+	collectErrors   bool
+	validateOrder   bool
+	unordered       UnorderedPolicy
+	sizeHints       []int
+	watermark       any // func(T)
+	lateData        bool
+	latePolicy      LatePolicy
+	lateDivert      any // func(int, T)
+	sourceContexts  []context.Context
+	closers         []io.Closer
+	singleSource    SingleSourceMode
+	batchPool       any // func(int) []T
+	distinct        any // func(T)
+	adaptive        bool
+	fairTies        bool
+	maxValueSize    int
+	resume          []int64
+	duplicates      DuplicatePolicy
+	dropFailed      bool
+	maxFailures     int
+	panicFormat     any // func(T) string
+	deadline        time.Time
+	sniffCompress   bool
+	manifest        string
+}
+
+func makeOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// typedOption returns the value of an option configured by a generic option
+// constructor, or an error wrapping ErrOptionType if it does not match the type
+// expected by the Merger.
+func typedOption[F any](name string, value any) (F, error) {
+	f, ok := value.(F)
+	if !ok && value != nil {
+		var want F
+		return f, fmt.Errorf("%w: %s option of type %T used where %T was expected", ErrOptionType, name, value, want)
+	}
+	return f, nil
+}
+
+// WithLabels assigns labels to the sources of a Merger, the labels are matched
+// to sources by index. Labels are used to identify sources in errors and
+// positions reported by the Merger, and are typically file names or shard IDs.
+//
+// Sources without a label are identified by their index.
+func WithLabels(labels ...string) Option {
+	return func(o *options) { o.labels = labels }
+}
+
+// SourcePosition represents the position of a Merger in one of its sources.
+//
+// Count is the number of values from the source that were yielded to the
+// application. When Count is greater than zero, Last holds the last value
+// yielded from the source. Since sources are ordered, the last value is the
+// natural resume token to restart consuming the source after a checkpoint
+// (e.g. records read from a Kafka partition carry their offset).
+type SourcePosition[T any] struct {
+	Index int
+	Label string
+	Count int64
+	Last  T
+}
+
+// WithCheckpoint configures a Merger to invoke fn every time the given number
+// of values were yielded to the application, and once more when the merge
+// completes if values were yielded since the last checkpoint.
+//
+// The positions passed to fn only account for values that the application has
+// received, which makes it safe to commit them as resume tokens: no values
+// would be lost if the program restarted from the positions. The slice is
+// indexed by source and may be retained by fn.
+//
+// If fn returns an error, the Merger yields it to the application and carries
+// on with the merge.
+func WithCheckpoint[T any](every int, fn func(positions []SourcePosition[T]) error) Option {
+	if every <= 0 {
+		panic("kway: checkpoint interval must be positive")
+	}
+	return func(o *options) {
+		o.checkpointEvery = every
+		o.checkpoint = fn
+	}
+}
@@ -0,0 +1,130 @@
+package kway
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ErrInvalidPageToken is the error returned by Paginate when the continuation
+// token of a page cannot be decoded, or does not match the sources.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Page is a page of a merge served by Paginate.
+type Page[T any] struct {
+	// Values of the page, in order.
+	Values []T
+	// Opaque continuation token to pass to Paginate to serve the next page, or
+	// the empty string if the page is the last one.
+	Next string
+}
+
+// Paginate serves the merge of ordered sources as pages of at most size values,
+// which is the building block of paginated APIs listing values federated from
+// multiple sorted sources (e.g. shards of a database, or files of a bucket).
+//
+// The merge is not held open between pages: each call opens the sources at the
+// position encoded in the token, merges the values of one page, and returns the
+// token of the next page. The open function must return the sequence of values
+// of a source starting at the given offset, which is the number of values that
+// were already served from the source; this requires the sources to be
+// seekable, and to produce the same values across calls. The empty token
+// starts from the beginning of the sources:
+//
+//	page, err := kway.Paginate(len(shards), func(source int, offset int64) iter.Seq2[Item, error] {
+//		return shards[source].List(offset)
+//	}, req.PageToken, 100)
+//
+// Tokens are URL safe strings encoding the offset of each source, they can be
+// returned to clients as-is. If the token cannot be decoded or was produced
+// for a different number of sources, the error wraps ErrInvalidPageToken.
+//
+// The options are applied to the Merger of each page, except WithResumeToken
+// and WithSingleSource(SingleSourcePassthrough), which would misplace the
+// tokens.
+//
+// See PaginateFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func Paginate[T cmp.Ordered](sources int, open func(source int, offset int64) iter.Seq2[T, error], token string, size int, opts ...Option) (Page[T], error) {
+	return PaginateFunc(cmp.Compare[T], sources, open, token, size, opts...)
+}
+
+// PaginateFunc is like Paginate but uses the given comparison function to
+// determine the order of values.
+//
+// See Paginate for more details.
+func PaginateFunc[T any](cmp func(T, T) int, sources int, open func(source int, offset int64) iter.Seq2[T, error], token string, size int, opts ...Option) (Page[T], error) {
+	if size <= 0 {
+		panic("kway: page size must be positive")
+	}
+
+	offsets, err := decodePageToken(token, sources)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	seqs := make([]iter.Seq2[T, error], sources)
+	for i := range seqs {
+		seqs[i] = open(i, offsets[i])
+	}
+
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.resume = nil
+		if o.singleSource == SingleSourcePassthrough {
+			o.singleSource = SingleSourceMerge
+		}
+	})
+	m, err := NewMerger(cmp, seqs, opts...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	// The position of the merge after the last value of the page is only known
+	// once the merge moved past it, which is when the next value is produced.
+	// This also tells whether the page is the last one.
+	page := Page[T]{Values: make([]T, 0, size)}
+	more := false
+	for v, err := range m.All() {
+		if err != nil {
+			return Page[T]{}, err
+		}
+		if len(page.Values) == size {
+			more = true
+			break
+		}
+		page.Values = append(page.Values, v)
+	}
+
+	if more {
+		next := m.ResumeToken()
+		for i := range next.Offsets {
+			next.Offsets[i] += offsets[i]
+		}
+		b, err := next.MarshalBinary()
+		if err != nil {
+			return Page[T]{}, err
+		}
+		page.Next = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return page, nil
+}
+
+func decodePageToken(token string, sources int) ([]int64, error) {
+	if token == "" {
+		return make([]int64, sources), nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+	}
+	var t ResumeToken
+	if err := t.UnmarshalBinary(b); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+	}
+	if len(t.Offsets) != sources {
+		return nil, fmt.Errorf("%w: %d offsets for %d sources", ErrInvalidPageToken, len(t.Offsets), sources)
+	}
+	return t.Offsets, nil
+}
@@ -0,0 +1,92 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	sources := [][]int{
+		{0, 3, 6, 9, 12, 15},
+		{1, 1, 4, 7, 10},
+		{2, 5, 8, 11, 14, 17, 20},
+	}
+	open := func(source int, offset int64) iter.Seq2[int, error] {
+		return seqOf(sources[source][offset:]...)
+	}
+	want := []int{0, 1, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 14, 15, 17, 20}
+
+	for _, size := range []int{1, 3, 6, 7, 18, 100} {
+		var got []int
+		var token string
+		pages := 0
+		for {
+			page, err := Paginate(len(sources), open, token, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Values) > size {
+				t.Fatalf("size %d: page of %d values", size, len(page.Values))
+			}
+			got = append(got, page.Values...)
+			pages++
+			if token = page.Next; token == "" {
+				break
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("size %d: expected %v, got %v", size, want, got)
+		}
+		if n := (len(want) + size - 1) / size; pages != n {
+			t.Errorf("size %d: expected %d pages, got %d", size, n, pages)
+		}
+	}
+}
+
+func TestPaginateSingleSource(t *testing.T) {
+	open := func(source int, offset int64) iter.Seq2[int, error] {
+		return sequence(int(offset), 10, 1)
+	}
+	page, err := Paginate(1, open, "", 4, WithSingleSource(SingleSourcePassthrough))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = Paginate(1, open, page.Next, 4, WithSingleSource(SingleSourcePassthrough))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(page.Values, []int{4, 5, 6, 7}) {
+		t.Errorf("unexpected second page: %v", page.Values)
+	}
+}
+
+func TestPaginateInvalidToken(t *testing.T) {
+	open := func(source int, offset int64) iter.Seq2[int, error] {
+		return sequence(int(offset), 10, 1)
+	}
+	page, err := Paginate(2, open, "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"!", "AAAA", page.Next} {
+		if _, err := Paginate(3, open, token, 4); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("%q: expected ErrInvalidPageToken, got %v", token, err)
+		}
+	}
+}
+
+func TestPaginateError(t *testing.T) {
+	errval := errors.New("unavailable")
+	open := func(source int, offset int64) iter.Seq2[int, error] {
+		if source == 1 {
+			return func(yield func(int, error) bool) { yield(0, errval) }
+		}
+		return sequence(int(offset), 10, 1)
+	}
+	if _, err := Paginate(2, open, "", 4); !errors.Is(err, errval) {
+		t.Errorf("expected %v, got %v", errval, err)
+	}
+}
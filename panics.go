@@ -0,0 +1,117 @@
+package kway
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// ComparePanicError is the error yielded by a Merger configured with
+// WithComparePanicRecovery when its comparison function panicked.
+type ComparePanicError struct {
+	// Value passed to panic.
+	Value any
+	// Values passed to the comparison function, formatted by the function
+	// given to WithComparePanicRecovery.
+	Left  string
+	Right string
+	// Indexes of the sources that the Left and Right values were read from.
+	Sources []int
+	// Stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error satisfies the error interface.
+func (e *ComparePanicError) Error() string {
+	return fmt.Sprintf("comparison function panicked comparing %s and %s (sources %v): %v", e.Left, e.Right, e.Sources, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error, or nil.
+func (e *ComparePanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithComparePanicRecovery configures a Merger to recover from panics of its
+// comparison function, which otherwise crash the program with little context
+// about the values that triggered the bug.
+//
+// When the comparison function panics, the Merger yields a *ComparePanicError
+// holding the compared values, formatted with the given function, and the
+// indexes of the sources that the values were read from, then stops the merge
+// and its sources.
+//
+// The comparisons made to merge the sources, to validate their order (see
+// WithUnorderedPolicy), and to detect runs of equal values (see Duplicates)
+// are covered. The comparisons made by WithDistinct, WithWatermark, and
+// WithLateData, which run when values are yielded to the application, are not,
+// and neither are panics of the loop body ranging over the merge.
+func WithComparePanicRecovery[T any](format func(T) string) Option {
+	if format == nil {
+		panic("kway: compare panic recovery requires a format function")
+	}
+	return func(o *options) { o.panicFormat = format }
+}
+
+// comparePanic wraps the comparison functions of the Merger to record the values
+// they compare, so they can be reported if they panic.
+type comparePanic[T any] struct {
+	format    func(T) string
+	left      T
+	right     T
+	sources   func() []int
+	comparing bool
+	err       *ComparePanicError
+}
+
+// reset clears the state recorded by the previous merge.
+func (p *comparePanic[T]) reset() {
+	*p = comparePanic[T]{format: p.format}
+}
+
+// wrap returns a comparison function recording the values passed to cmp, the
+// sources function returns the indexes of the sources they were read from.
+func (p *comparePanic[T]) wrap(cmp func(T, T) int, sources func() []int) func(T, T) int {
+	return func(a, b T) int {
+		p.left, p.right, p.sources, p.comparing = a, b, sources, true
+		c := cmp(a, b)
+		p.comparing = false
+		return c
+	}
+}
+
+// recover records the panic of a wrapped comparison function, it must be
+// deferred by the functions calling them. Other panics are propagated.
+func (p *comparePanic[T]) recover() {
+	if r := recover(); r != nil {
+		if !p.comparing {
+			panic(r)
+		}
+		p.err = &ComparePanicError{
+			Value:   r,
+			Left:    p.format(p.left),
+			Right:   p.format(p.right),
+			Sources: p.sources(),
+			Stack:   debug.Stack(),
+		}
+	}
+}
+
+// next calls nextIndexed on the tree of the Merger, recovering from panics of
+// the comparison functions when configured to. This covers the comparisons of
+// the tree, and of the sources validating the order of their values, which
+// are read by the tree.
+func (m *Merger[T]) next(values []T, sources []int) (n int, err error) {
+	if m.comparePanic != nil {
+		defer m.comparePanic.recover()
+	}
+	return m.tree.nextIndexed(values, sources, m.treeCmp)
+}
+
+// filterDuplicates applies the duplicate policy to the values, recovering from
+// panics of the comparison function when configured to.
+func (m *Merger[T]) filterDuplicates(values []T, sources []int) ([]T, []int) {
+	if m.comparePanic != nil {
+		defer m.comparePanic.recover()
+	}
+	return m.duplicates.filter(values, sources, m.creditDuplicates)
+}
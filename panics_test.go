@@ -0,0 +1,177 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestWithComparePanicRecovery(t *testing.T) {
+	errval := errors.New("invalid value")
+	compare := func(a, b int) int {
+		if a == 13 || b == 13 {
+			panic(errval)
+		}
+		return a - b
+	}
+
+	m := newMerger(t, compare,
+		[]iter.Seq2[int, error]{
+			seqOf(1, 4, 7),
+			seqOf(2, 13, 20),
+			seqOf(3, 6, 9),
+		},
+		WithComparePanicRecovery(strconv.Itoa),
+	)
+
+	var got []int
+	var err error
+	for v, e := range m.All() {
+		if e != nil {
+			err = e
+			break
+		}
+		got = append(got, v)
+	}
+
+	var panicErr *ComparePanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected *ComparePanicError, got %v", err)
+	}
+	if !errors.Is(err, errval) {
+		t.Errorf("expected error to wrap the panic value: %v", err)
+	}
+	if panicErr.Left != "13" && panicErr.Right != "13" {
+		t.Errorf("expected 13 in compared values: %q %q", panicErr.Left, panicErr.Right)
+	}
+	if !slices.Contains(panicErr.Sources, 1) {
+		t.Errorf("expected source 1 in %v", panicErr.Sources)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Error("missing stack trace")
+	}
+	if !slices.IsSorted(got) || slices.ContainsFunc(got, func(v int) bool { return v > 4 }) {
+		t.Errorf("unexpected values yielded before the panic: %v", got)
+	}
+}
+
+func TestWithComparePanicRecoveryDisabled(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected the comparison panic to propagate")
+		}
+	}()
+	compare := func(a, b int) int { panic("boom") }
+	for range MergeFunc(compare, seqOf(1, 2), seqOf(3, 4)) {
+	}
+}
+
+func TestWithComparePanicRecoverySources(t *testing.T) {
+	compare := func(a, b int) int {
+		if a+b == 27 {
+			panic("boom")
+		}
+		return a - b
+	}
+	// The values of all sources are formatted the same, the sources must not
+	// be identified by matching the formatted values.
+	format := func(v int) string { return strconv.Itoa(v % 10) }
+
+	m := newMerger(t, compare,
+		[]iter.Seq2[int, error]{
+			seqOf(3, 13),
+			seqOf(4, 14),
+			seqOf(5, 23),
+		},
+		WithComparePanicRecovery(format),
+	)
+
+	var panicErr *ComparePanicError
+	for _, err := range m.All() {
+		if errors.As(err, &panicErr) {
+			break
+		}
+	}
+	if panicErr == nil {
+		t.Fatal("expected *ComparePanicError")
+	}
+	want := []int{0, 1}
+	if panicErr.Left == "4" {
+		want = []int{1, 0}
+	}
+	if !slices.Equal(panicErr.Sources, want) {
+		t.Errorf("expected sources %v comparing %s and %s, got %v", want, panicErr.Left, panicErr.Right, panicErr.Sources)
+	}
+}
+
+func TestWithComparePanicRecoveryPolicies(t *testing.T) {
+	tests := []struct {
+		scenario string
+		// The values are only compared in this order by the option.
+		left, right int
+		option      Option
+		sources     []int
+	}{
+		{scenario: "duplicates", left: 1, right: 2, option: Duplicates(KeepFirst), sources: []int{1, 1}},
+		{scenario: "unordered policy", left: 3, right: 2, option: WithUnorderedPolicy(UnorderedDrop), sources: []int{1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			compare := func(a, b int) int {
+				if a == test.left && b == test.right {
+					panic("boom")
+				}
+				return a - b
+			}
+			m := newMerger(t, compare,
+				[]iter.Seq2[int, error]{seqOf(10, 20), seqOf(1, 2, 3)},
+				WithComparePanicRecovery(strconv.Itoa),
+				test.option,
+			)
+
+			var panicErr *ComparePanicError
+			for _, err := range m.All() {
+				if errors.As(err, &panicErr) {
+					break
+				}
+			}
+			if panicErr == nil {
+				t.Fatal("expected *ComparePanicError")
+			}
+			if panicErr.Left != strconv.Itoa(test.left) || panicErr.Right != strconv.Itoa(test.right) {
+				t.Errorf("expected to compare %d and %d, got %s and %s", test.left, test.right, panicErr.Left, panicErr.Right)
+			}
+			if !slices.Equal(panicErr.Sources, test.sources) {
+				t.Errorf("expected sources %v, got %v", test.sources, panicErr.Sources)
+			}
+		})
+	}
+}
+
+func TestWithComparePanicRecoveryReset(t *testing.T) {
+	compare := func(a, b int) int {
+		if a == 13 || b == 13 {
+			panic("boom")
+		}
+		return a - b
+	}
+	m := newMerger(t, compare,
+		[]iter.Seq2[int, error]{seqOf(1, 13), seqOf(2, 4)},
+		WithComparePanicRecovery(strconv.Itoa),
+	)
+	if _, err := values(m.All()); err == nil {
+		t.Fatal("expected the comparison to panic")
+	}
+
+	m.Reset(seqOf(1, 3), seqOf(2, 4))
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,199 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"sort"
+	"sync"
+)
+
+// Partition splits the ordered sequence seq into len(boundaries)+1 sequences
+// covering contiguous ranges of values: the partition at index i yields the
+// values greater or equal to boundaries[i-1] and less than boundaries[i]. The
+// boundaries must be ordered.
+//
+// A typical use case is re-sharding sorted data, by merging many inputs and
+// writing range-partitioned outputs:
+//
+//	parts := kway.Partition(boundaries, kway.Merge(inputs...))
+//	for i, part := range parts {
+//		go write(i, part)
+//	}
+//
+// The sequence is read from a separate goroutine, which buffers a bounded
+// number of values ahead of the partition being consumed, so the consumer of
+// a partition can start while the previous one is still processing its last
+// values. Because the ranges are contiguous, the partitions must be consumed
+// concurrently or in order; each partition must be ranged over, even if only
+// to stop right away, for the goroutine to exit. Partitions can only be ranged
+// over once.
+//
+// Errors produced by seq are yielded by the partition that the merge was
+// positioned on when they occurred. Values ordered before the previous value
+// of seq are dropped and reported as ErrUnordered.
+//
+// See PartitionFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func Partition[T cmp.Ordered](boundaries []T, seq iter.Seq2[T, error]) []iter.Seq2[T, error] {
+	return PartitionFunc(cmp.Compare[T], boundaries, seq)
+}
+
+// PartitionFunc is like Partition but uses the given comparison function to
+// determine the order of values.
+//
+// See Partition for more details.
+func PartitionFunc[T any](cmp func(T, T) int, boundaries []T, seq iter.Seq2[T, error]) []iter.Seq2[T, error] {
+	p := &partitioner[T]{
+		cmp:        cmp,
+		boundaries: boundaries,
+		seq:        seq,
+		outputs:    make([]partitionOutput[T], len(boundaries)+1),
+	}
+	for i := range p.outputs {
+		p.outputs[i] = partitionOutput[T]{
+			batches: make(chan partitionBatch[T], 1),
+			done:    make(chan struct{}),
+		}
+	}
+
+	parts := make([]iter.Seq2[T, error], len(p.outputs))
+	for i := range parts {
+		parts[i] = p.partition(i)
+	}
+	return parts
+}
+
+type partitioner[T any] struct {
+	cmp        func(T, T) int
+	boundaries []T
+	seq        iter.Seq2[T, error]
+	outputs    []partitionOutput[T]
+	once       sync.Once
+}
+
+type partitionOutput[T any] struct {
+	batches chan partitionBatch[T]
+	// Closed by the consumer of the partition when it stops ranging over it.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+type partitionBatch[T any] struct {
+	values []T
+	err    error
+}
+
+func (p *partitioner[T]) partition(i int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		out := &p.outputs[i]
+		defer out.stop()
+		p.once.Do(func() { go p.run() })
+		out.values(yield)
+	}
+}
+
+// values yields the values and errors sent to the output.
+func (out *partitionOutput[T]) values(yield func(T, error) bool) {
+	var zero T
+	for batch := range out.batches {
+		for _, value := range batch.values {
+			if !yield(value, nil) {
+				return
+			}
+		}
+		if batch.err != nil && !yield(zero, batch.err) {
+			return
+		}
+	}
+}
+
+// stop signals that the consumer of the output stopped receiving values.
+func (out *partitionOutput[T]) stop() {
+	out.doneOnce.Do(func() { close(out.done) })
+}
+
+// indexOf returns the index of the partition that value belongs to.
+func (p *partitioner[T]) indexOf(value T) int {
+	return sort.Search(len(p.boundaries), func(i int) bool {
+		return p.cmp(p.boundaries[i], value) > 0
+	})
+}
+
+// run reads values from the sequence and routes them to the partitions.
+func (p *partitioner[T]) run() {
+	current := 0
+	defer func() {
+		for i := current; i < len(p.outputs); i++ {
+			close(p.outputs[i].batches)
+		}
+	}()
+
+	buf := make([]T, 0, bufferSize)
+	// send delivers the buffered values and the error to the current
+	// partition, discarding them if its consumer stopped. It returns false if
+	// all the partitions stopped.
+	send := func(err error) bool {
+		out := &p.outputs[current]
+		select {
+		case out.batches <- partitionBatch[T]{values: buf, err: err}:
+			buf = make([]T, 0, bufferSize)
+		case <-out.done:
+			buf = buf[:0]
+			if p.stopped(current) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var prev T
+	var hasPrev bool
+	for value, err := range p.seq {
+		if err != nil {
+			if !send(err) {
+				return
+			}
+			continue
+		}
+		if hasPrev && p.cmp(value, prev) < 0 {
+			if !send(ErrUnordered) {
+				return
+			}
+			continue
+		}
+		prev, hasPrev = value, true
+		i := p.indexOf(value)
+		if i > current {
+			if len(buf) > 0 && !send(nil) {
+				return
+			}
+			for ; current < i; current++ {
+				close(p.outputs[current].batches)
+			}
+		}
+		if buf = append(buf, value); len(buf) == cap(buf) && !send(nil) {
+			return
+		}
+	}
+
+	if len(buf) > 0 {
+		send(nil)
+	}
+}
+
+// stopped returns true if the consumers of all partitions starting at index i
+// stopped, in which case no more values need to be read.
+func (p *partitioner[T]) stopped(i int) bool {
+	return allStopped(p.outputs[i:])
+}
+
+func allStopped[T any](outputs []partitionOutput[T]) bool {
+	for i := range outputs {
+		select {
+		case <-outputs[i].done:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package kway
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+	parts := Partition([]int{100, 250, 250, 900}, count(1000))
+	if len(parts) != 5 {
+		t.Fatalf("expected 5 partitions, got %d", len(parts))
+	}
+
+	ranges := [][2]int{{0, 100}, {100, 250}, {250, 250}, {250, 900}, {900, 1000}}
+	for i, part := range parts {
+		got, err := values(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := values(sequence(ranges[i][0], ranges[i][1], 1))
+		if !slices.Equal(got, want) {
+			t.Errorf("partition %d: expected %d values, got %d", i, len(want), len(got))
+		}
+	}
+}
+
+func TestPartitionConcurrent(t *testing.T) {
+	parts := Partition([]int{300, 600}, count(1000))
+	results := make([][]int, len(parts))
+
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		wg.Add(1)
+		go func(i int, part iter.Seq2[int, error]) {
+			defer wg.Done()
+			results[i], _ = values(part)
+		}(i, part)
+	}
+	wg.Wait()
+
+	got := slices.Concat(results...)
+	if want, _ := values(count(1000)); !slices.Equal(got, want) {
+		t.Errorf("partitions do not cover the sequence: %d values", len(got))
+	}
+	if len(results[0]) != 300 || len(results[1]) != 300 || len(results[2]) != 400 {
+		t.Errorf("unexpected partition sizes: %d, %d, %d", len(results[0]), len(results[1]), len(results[2]))
+	}
+}
+
+func TestPartitionStopEarly(t *testing.T) {
+	parts := Partition([]int{500}, count(1000))
+
+	for v, err := range parts[0] {
+		if err != nil || v != 0 {
+			t.Fatalf("unexpected value: %d, %v", v, err)
+		}
+		break
+	}
+
+	got, err := values(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(sequence(500, 1000, 1)); !slices.Equal(got, want) {
+		t.Errorf("expected %d values, got %d", len(want), len(got))
+	}
+}
+
+func TestPartitionErrors(t *testing.T) {
+	errval := errors.New("")
+
+	seq := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(5, nil) && yield(2, nil) && yield(6, nil)
+	}
+	parts := Partition([]int{3}, seq)
+
+	var errs [2][]error
+	var got [2][]int
+	for i, part := range parts {
+		for v, err := range part {
+			if err != nil {
+				errs[i] = append(errs[i], err)
+			} else {
+				got[i] = append(got[i], v)
+			}
+		}
+	}
+
+	if !slices.Equal(got[0], []int{1}) || !slices.Equal(got[1], []int{5, 6}) {
+		t.Errorf("unexpected values: %v", got)
+	}
+	if !slices.Equal(errs[0], []error{errval}) || !slices.Equal(errs[1], []error{ErrUnordered}) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestPartitionUnorderedWithinPartition(t *testing.T) {
+	seq := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(4, nil) && yield(2, nil) && yield(5, nil)
+	}
+	parts := Partition([]int{10}, seq)
+
+	var errs []error
+	var got []int
+	for _, part := range parts {
+		for v, err := range part {
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				got = append(got, v)
+			}
+		}
+	}
+
+	if !slices.Equal(got, []int{1, 4, 5}) {
+		t.Errorf("unexpected values: %v", got)
+	}
+	if !slices.Equal(errs, []error{ErrUnordered}) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
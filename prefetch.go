@@ -0,0 +1,90 @@
+package kway
+
+import (
+	"context"
+	"iter"
+)
+
+// Prefetch returns a sequence yielding the batches of seq, which are read ahead
+// by a separate goroutine, up to n batches. This is the recommended way to
+// merge sequences read from remote sources, so the merge does not wait on the
+// sum of the latencies of its sources:
+//
+//	merged := kway.MergeSlice(
+//		kway.Prefetch(4, remote0),
+//		kway.Prefetch(4, remote1),
+//	)
+//
+// The batches are copied into a ring of n+1 buffers which are reused once the
+// merge is done with them, so seq may reuse its batches like the sequences of
+// this package do, and the application must not retain the batches beyond the
+// body of the loop ranging over the returned sequence. Errors produced by seq
+// are yielded in order with its batches.
+//
+// Prefetch supports the WithContext option: when the context is canceled, the
+// sequence yields the context error and stops, even if the goroutine reading
+// from seq is blocked. Other options are ignored. When the application stops
+// consuming the sequence early, the goroutine exits the next time seq produces
+// a batch.
+func Prefetch[T any](n int, seq iter.Seq2[[]T, error], opts ...Option) iter.Seq2[[]T, error] {
+	if n <= 0 {
+		panic("kway: prefetch depth must be positive")
+	}
+	o := makeOptions(opts)
+	ctx := o.context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	type prefetched struct {
+		slot int
+		err  error
+	}
+
+	return func(yield func([]T, error) bool) {
+		ring := make([][]T, n+1)
+		free := make(chan int, len(ring))
+		for i := range ring {
+			free <- i
+		}
+		full := make(chan prefetched, len(ring))
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			defer close(full)
+			for values, err := range seq {
+				var slot int
+				select {
+				case slot = <-free:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+				ring[slot] = append(ring[slot][:0], values...)
+				full <- prefetched{slot: slot, err: err}
+			}
+		}()
+
+		for {
+			select {
+			case p, ok := <-full:
+				if !ok {
+					// The goroutine also exits when the context is canceled.
+					if err := ctx.Err(); err != nil {
+						yield(nil, err)
+					}
+					return
+				}
+				if (len(ring[p.slot]) > 0 || p.err != nil) && !yield(ring[p.slot], p.err) {
+					return
+				}
+				free <- p.slot
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			}
+		}
+	}
+}
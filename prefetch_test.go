@@ -0,0 +1,107 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrefetch(t *testing.T) {
+	got, err := concatValues(Prefetch(2, countSlice(1000, 7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(7000)); !slices.Equal(got, want) {
+		t.Errorf("expected %d values, got %d", len(want), len(got))
+	}
+
+	merged, err := concatValues(MergeSlice(Prefetch(3, countSlice(500, 10)), Prefetch(3, countSlice(500, 3))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 6500 || !slices.IsSorted(merged) {
+		t.Errorf("prefetched sequences were not merged correctly")
+	}
+}
+
+func TestPrefetchReadsAhead(t *testing.T) {
+	var read atomic.Int64
+	seq := func(yield func([]int, error) bool) {
+		buf := make([]int, 1)
+		for i := range 100 {
+			read.Add(1)
+			buf[0] = i
+			if !yield(buf, nil) {
+				return
+			}
+		}
+	}
+
+	for values, err := range Prefetch(4, seq) {
+		if err != nil || values[0] != 0 {
+			t.Fatalf("unexpected batch: %v, %v", values, err)
+		}
+		// The goroutine fills the ring while the first batch is held.
+		deadline := time.Now().Add(5 * time.Second)
+		for read.Load() < 5 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		break
+	}
+	// The batch held by the consumer and the 4 prefetched batches.
+	if n := read.Load(); n < 5 || n > 6 {
+		t.Errorf("expected 5 or 6 batches to be read, got %d", n)
+	}
+}
+
+func TestPrefetchErrors(t *testing.T) {
+	errval := errors.New("")
+	seq := func(yield func([]int, error) bool) {
+		_ = yield([]int{1}, nil) && yield([]int{2}, errval) && yield([]int{3}, nil)
+	}
+
+	var got []int
+	var errs []error
+	for values, err := range Prefetch(1, seq) {
+		got = append(got, values...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
+
+func TestPrefetchContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	seq := func(yield func([]int, error) bool) {
+		if yield([]int{1}, nil) {
+			<-blocked
+		}
+	}
+
+	var errs []error
+	for values, err := range Prefetch(2, seq, WithContext(ctx)) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(values) > 0 {
+			cancel()
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", errs)
+	}
+}
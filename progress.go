@@ -0,0 +1,81 @@
+package kway
+
+import (
+	"iter"
+	"sync"
+)
+
+// Progress is a snapshot of the progress of a Merger.
+type Progress struct {
+	// Number of values read from each source, including values which are
+	// buffered by the Merger and were not yielded yet.
+	Read []int64
+	// Number of values from each source which were yielded to the application.
+	Yielded []int64
+	// Total number of values yielded to the application.
+	Total int64
+}
+
+// Lag returns the number of values read from the source at index i which were
+// not yielded to the application yet.
+func (p Progress) Lag(i int) int64 {
+	return p.Read[i] - p.Yielded[i]
+}
+
+// progress holds the progress of a Merger published at batch boundaries.
+type progress struct {
+	mutex   sync.Mutex
+	read    []int64
+	yielded []int64
+	total   int64
+}
+
+// Progress returns a snapshot of the progress of the merge, which applications
+// use to display progress or measure the lag between merged sources.
+//
+// The snapshot is updated each time a batch of values has been yielded to the
+// application (or when the application stopped consuming the batch), so the
+// counts of all sources are consistent with each other: they reflect the state
+// of the merge at the same batch boundary. The method is safe to call from
+// other goroutines than the one consuming the merge.
+func (m *Merger[T]) Progress() Progress {
+	m.progress.mutex.Lock()
+	defer m.progress.mutex.Unlock()
+	p := Progress{
+		Read:    make([]int64, len(m.seqs)),
+		Yielded: make([]int64, len(m.seqs)),
+		Total:   m.progress.total,
+	}
+	copy(p.Read, m.progress.read)
+	copy(p.Yielded, m.progress.yielded)
+	return p
+}
+
+// publishProgress updates the snapshot returned by Progress.
+func (m *Merger[T]) publishProgress() {
+	m.progress.mutex.Lock()
+	defer m.progress.mutex.Unlock()
+	if m.progress.yielded == nil {
+		m.progress.yielded = make([]int64, len(m.seqs))
+		m.progress.read = make([]int64, len(m.seqs))
+	}
+	m.progress.total = 0
+	for i := range m.positions {
+		m.progress.yielded[i] = m.positions[i].Count
+		m.progress.total += m.positions[i].Count
+	}
+	copy(m.progress.read, m.reads)
+}
+
+// countReads returns a sequence yielding the batches of seq, and counting the
+// values read from the source at the given index.
+func (m *Merger[T]) countReads(source int, seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for values, err := range seq {
+			m.reads[source] += int64(len(values))
+			if !yield(values, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerProgress(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{
+		sequence(0, 1000, 2),
+		sequence(1, 501, 2),
+	})
+
+	if p := m.Progress(); p.Total != 0 || !slices.Equal(p.Yielded, []int64{0, 0}) {
+		t.Errorf("unexpected progress before the merge: %+v", p)
+	}
+
+	for _, err := range m.Batches() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := m.Progress()
+		for i := range p.Read {
+			if p.Lag(i) < 0 {
+				t.Errorf("source %d yielded more values than were read: %+v", i, p)
+			}
+		}
+		if p.Total != p.Yielded[0]+p.Yielded[1] {
+			t.Errorf("inconsistent total: %+v", p)
+		}
+	}
+
+	p := m.Progress()
+	if want := []int64{500, 250}; !slices.Equal(p.Yielded, want) || !slices.Equal(p.Read, want) || p.Total != 750 {
+		t.Errorf("unexpected progress after the merge: %+v", p)
+	}
+}
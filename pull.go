@@ -0,0 +1,52 @@
+package kway
+
+import "iter"
+
+// PullBatched converts the push-style sequence seq into a pull-style iterator,
+// like iter.Pull2, but reads values from seq in batches of up to n values.
+//
+// iter.Pull2 switches between the goroutines of the caller and the sequence on
+// each call to next, which dominates the cost of consuming sequences of small
+// values. PullBatched amortizes this cost by only switching when a batch was
+// exhausted, most calls to next return a value from the current batch.
+//
+// Errors produced by seq are returned by next after the values that preceded
+// them, in the order in which seq produced them. The stop function must be
+// called when the application stops calling next, to release the resources
+// held by the iterator.
+func PullBatched[T any](n int, seq iter.Seq2[T, error]) (next func() (T, error, bool), stop func()) {
+	if n <= 0 {
+		panic("kway: batch size must be positive")
+	}
+
+	nextBatch, stopBatches := iter.Pull2(bufferFunc(n, nil, seq))
+	var values []T
+	var err error
+
+	next = func() (T, error, bool) {
+		var zero T
+		for {
+			if len(values) > 0 {
+				value := values[0]
+				values = values[1:]
+				return value, nil, true
+			}
+			if err != nil {
+				e := err
+				err = nil
+				return zero, e, true
+			}
+			var ok bool
+			if values, err, ok = nextBatch(); !ok {
+				return zero, nil, false
+			}
+		}
+	}
+
+	stop = func() {
+		values, err = nil, nil
+		stopBatches()
+	}
+
+	return next, stop
+}
@@ -0,0 +1,56 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPullBatched(t *testing.T) {
+	errval := errors.New("")
+
+	failing := func(yield func(int, error) bool) {
+		_ = yield(0, nil) && yield(1, nil) && yield(0, errval) && yield(2, nil)
+	}
+
+	next, stop := PullBatched(4, failing)
+	defer stop()
+
+	var got []int
+	var errs []error
+	for {
+		v, err, ok := next()
+		if !ok {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			got = append(got, -1)
+		} else {
+			got = append(got, v)
+		}
+	}
+
+	if want := []int{0, 1, -1, 2}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
+
+func TestPullBatchedStop(t *testing.T) {
+	next, stop := PullBatched(2, count(10))
+
+	for i := range 3 {
+		v, err, ok := next()
+		if !ok || err != nil || v != i {
+			t.Fatalf("next: %d, %v, %t", v, err, ok)
+		}
+	}
+
+	stop()
+	if _, _, ok := next(); ok {
+		t.Error("next returned a value after stop")
+	}
+}
@@ -0,0 +1,92 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Counted is a value paired with the number of sequences that contained it.
+type Counted[T any] struct {
+	Value T
+	Count int
+}
+
+// Quorum merges multiple sequences and yields the values that were present in
+// at least n of them. Each value is yielded once, regardless of how many times
+// it appeared in the sequences.
+//
+// A value repeated within a single sequence only counts once toward the
+// quorum, which makes the function suitable for reconciling replicated logs
+// where each replica may contain duplicates.
+//
+// See QuorumFunc for a version of this function that allows the caller to pass
+// a custom comparison function, and QuorumCount for a version that also reports
+// the number of sequences that each value was present in.
+func Quorum[T cmp.Ordered](n int, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return QuorumFunc(n, cmp.Compare[T], seqs...)
+}
+
+// QuorumFunc is like Quorum but uses the given comparison function to determine
+// the order and equality of values.
+//
+// See Quorum for more details.
+func QuorumFunc[T any](n int, cmp func(T, T) int, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for c, err := range QuorumCountFunc(n, cmp, seqs...) {
+			if !yield(c.Value, err) {
+				return
+			}
+		}
+	}
+}
+
+// QuorumCount is like Quorum but yields the values paired with the number of
+// sequences that they were present in.
+//
+// See Quorum for more details.
+func QuorumCount[T cmp.Ordered](n int, seqs ...iter.Seq2[T, error]) iter.Seq2[Counted[T], error] {
+	return QuorumCountFunc(n, cmp.Compare[T], seqs...)
+}
+
+// QuorumCountFunc is like QuorumCount but uses the given comparison function to
+// determine the order and equality of values.
+//
+// See Quorum for more details.
+func QuorumCountFunc[T any](n int, cmp func(T, T) int, seqs ...iter.Seq2[T, error]) iter.Seq2[Counted[T], error] {
+	return func(yield func(Counted[T], error) bool) {
+		// seen records the group number where each sequence was last counted,
+		// so values repeated within a sequence only count once per group.
+		seen := make([]int, len(seqs))
+		group := 0
+
+		var current Counted[T]
+		var hasCurrent bool
+
+		for v, err := range MergeIndexedFunc(cmp, seqs...) {
+			if err != nil {
+				if !yield(Counted[T]{}, err) {
+					return
+				}
+				continue
+			}
+
+			if !hasCurrent || cmp(current.Value, v.Value) != 0 {
+				if hasCurrent && current.Count >= n && !yield(current, nil) {
+					return
+				}
+				group++
+				current = Counted[T]{Value: v.Value}
+				hasCurrent = true
+			}
+
+			if seen[v.Source] != group {
+				seen[v.Source] = group
+				current.Count++
+			}
+		}
+
+		if hasCurrent && current.Count >= n {
+			yield(current, nil)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestQuorum(t *testing.T) {
+	got, err := values(Quorum(2,
+		seqOf(1, 2, 3, 5, 8),
+		seqOf(2, 2, 3, 4),
+		seqOf(1, 3, 4, 7),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestQuorumCount(t *testing.T) {
+	var got []Counted[int]
+	for c, err := range QuorumCount(1,
+		seqOf(1, 1, 2),
+		seqOf(1, 3),
+		seqOf(1, 2, 3),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c)
+	}
+	want := []Counted[int]{{1, 3}, {2, 2}, {3, 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestQuorumError(t *testing.T) {
+	errval := errors.New("")
+
+	seq0 := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(2, nil)
+	}
+
+	var got []int
+	var errCount int
+	for v, err := range Quorum(2, seq0, seqOf(1, 2)) {
+		if err != nil {
+			if err != errval {
+				t.Fatal(err)
+			}
+			errCount++
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if errCount != 1 {
+		t.Errorf("expected 1 error, got %d", errCount)
+	}
+}
@@ -0,0 +1,49 @@
+package kway
+
+import "context"
+
+// Limiter is the interface used by Merger to throttle its output.
+//
+// The interface is satisfied by *rate.Limiter from golang.org/x/time/rate.
+type Limiter interface {
+	WaitN(ctx context.Context, n int) error
+}
+
+// WithRateLimit configures a Merger to throttle the rate of values that it
+// produces. Each value consumes one token from the limiter.
+//
+// The Merger waits for tokens once per batch of merged values, which preserves
+// batching when consuming the merge with Merger.Batches. If the limiter has a
+// Burst method (like *rate.Limiter), the Merger never waits for more than the
+// burst size at once.
+func WithRateLimit(limiter Limiter) Option {
+	return func(o *options) { o.valueLimiter = limiter }
+}
+
+// WithBatchRateLimit is like WithRateLimit but each batch of values produced
+// by the Merger consumes a single token from the limiter.
+func WithBatchRateLimit(limiter Limiter) Option {
+	return func(o *options) { o.batchLimiter = limiter }
+}
+
+// WithContext configures the context of a Merger. When the context is
+// canceled, the Merger stops reading from its sources, yields the context
+// error and stops.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) { o.context = ctx }
+}
+
+func waitN(ctx context.Context, l Limiter, n int) error {
+	burst := n
+	if b, ok := l.(interface{ Burst() int }); ok && b.Burst() > 0 {
+		burst = b.Burst()
+	}
+	for n > 0 {
+		k := min(n, burst)
+		if err := l.WaitN(ctx, k); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
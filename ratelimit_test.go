@@ -0,0 +1,76 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+type countingLimiter struct {
+	burst int
+	waits []int
+}
+
+func (l *countingLimiter) Burst() int { return l.burst }
+
+func (l *countingLimiter) WaitN(ctx context.Context, n int) error {
+	if n > l.burst {
+		return errors.New("burst exceeded")
+	}
+	l.waits = append(l.waits, n)
+	return ctx.Err()
+}
+
+func TestMergerRateLimit(t *testing.T) {
+	valueLimiter := &countingLimiter{burst: 100}
+	batchLimiter := &countingLimiter{burst: 1}
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{count(150), count(150)},
+		WithRateLimit(valueLimiter),
+		WithBatchRateLimit(batchLimiter),
+	)
+
+	n := 0
+	for values, err := range m.Batches() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += len(values)
+	}
+	if n != 300 {
+		t.Errorf("expected 300 values, got %d", n)
+	}
+
+	total := 0
+	for _, w := range valueLimiter.waits {
+		total += w
+	}
+	if total != 300 {
+		t.Errorf("expected the value limiter to be waited on for 300 tokens, got %d", total)
+	}
+	if want := slices.Repeat([]int{1}, 3); !slices.Equal(batchLimiter.waits, want) {
+		t.Errorf("expected the batch limiter to be waited on %v, got %v", want, batchLimiter.waits)
+	}
+}
+
+func TestMergerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{count(10), count(10)},
+		WithContext(ctx),
+	)
+
+	got, err := values(m.All())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no values, got %v", got)
+	}
+}
@@ -0,0 +1,100 @@
+package kway
+
+import (
+	"io"
+	"iter"
+)
+
+// Encoder is the interface implemented by types that encode values to bytes,
+// used to convert sequences to byte streams with NewReader.
+type Encoder[T any] interface {
+	// Encode appends the encoding of values to dst and returns the extended
+	// buffer.
+	Encode(dst []byte, values []T) ([]byte, error)
+}
+
+// EncoderFunc is an adapter to allow the use of ordinary functions as encoders.
+type EncoderFunc[T any] func(dst []byte, values []T) ([]byte, error)
+
+// Encode calls f(dst, values).
+func (f EncoderFunc[T]) Encode(dst []byte, values []T) ([]byte, error) {
+	return f(dst, values)
+}
+
+// NewReader returns a reader producing the encoding of values yielded by seq,
+// which allows streaming merged values to APIs expecting an io.Reader (e.g.
+// HTTP request bodies or object store uploads).
+//
+// The values are encoded in batches, see NewSliceReader for details.
+func NewReader[T any](seq iter.Seq2[T, error], enc Encoder[T]) io.ReadCloser {
+	return NewSliceReader(buffer(bufferSize, seq), enc)
+}
+
+// NewSliceReader returns a reader producing the encoding of the batches of
+// values yielded by seq, typically the result of MergeSlice. Each batch is
+// passed to the encoder at once, which amortizes the cost of encoding values.
+//
+// Errors yielded by the sequence or returned by the encoder are returned by
+// the reader after the bytes encoded before the error were read.
+//
+// The sequence is consumed as the reader is read, and stopped when the reader
+// reaches the end of the sequence or when an error occurs. Applications that
+// do not read until the end must call Close to stop the sequence.
+func NewSliceReader[T any](seq iter.Seq2[[]T, error], enc Encoder[T]) io.ReadCloser {
+	next, stop := iter.Pull2(seq)
+	return &reader[T]{next: next, stop: stop, enc: enc}
+}
+
+type reader[T any] struct {
+	buf  []byte
+	off  int
+	err  error
+	enc  Encoder[T]
+	next func() ([]T, error, bool)
+	stop func()
+}
+
+func (r *reader[T]) Read(b []byte) (int, error) {
+	for r.off == len(r.buf) {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(b, r.buf[r.off:])
+	r.off += n
+	return n, nil
+}
+
+// fill encodes the next batch of values in the buffer of the reader, or sets
+// the error to return once the buffer was read.
+func (r *reader[T]) fill() {
+	r.buf, r.off = r.buf[:0], 0
+
+	values, err, ok := r.next()
+	if !ok {
+		r.err = io.EOF
+		return
+	}
+	if len(values) > 0 {
+		var encErr error
+		if r.buf, encErr = r.enc.Encode(r.buf, values); encErr != nil && err == nil {
+			err = encErr
+		}
+	}
+	if err != nil {
+		r.err = err
+		r.stop()
+	}
+}
+
+// Close stops the sequence that the reader was reading from, subsequent reads
+// return io.ErrClosedPipe.
+func (r *reader[T]) Close() error {
+	r.stop()
+	r.buf, r.off = nil, 0
+	if r.err == nil || r.err == io.EOF {
+		r.err = io.ErrClosedPipe
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package kway
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+var lineEncoder = EncoderFunc[int](func(dst []byte, values []int) ([]byte, error) {
+	for _, v := range values {
+		dst = strconv.AppendInt(dst, int64(v), 10)
+		dst = append(dst, '\n')
+	}
+	return dst, nil
+})
+
+func TestReader(t *testing.T) {
+	r := NewReader(Merge(seqOf(1, 4), seqOf(2, 5), seqOf(3, 6)), lineEncoder)
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "1\n2\n3\n4\n5\n6\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	errval := errors.New("")
+
+	seq := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(2, nil) && yield(0, errval)
+	}
+
+	r := NewReader(seq, lineEncoder)
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != errval {
+		t.Errorf("expected %v, got %v", errval, err)
+	}
+	if got, want := string(b), "1\n2\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	r := NewReader(count(1000), lineEncoder)
+
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Errorf("expected %v, got %v", io.ErrClosedPipe, err)
+	}
+}
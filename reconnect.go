@@ -0,0 +1,159 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+)
+
+// Retry configures how Reconnect reopens the sources that failed.
+type Retry struct {
+	// Maximum number of consecutive attempts to reopen a source which did not
+	// produce any values in between, zero means no limit.
+	MaxAttempts int
+	// Delay before the first attempt to reopen a source, doubled after each
+	// consecutive attempt up to MaxDelay. The delays default to 100ms and 10s.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// Retryable reports whether an error is transient and the source should be
+	// reopened. When nil, all errors are retryable except context errors.
+	Retryable func(error) bool
+}
+
+func (r *Retry) retryable(err error) bool {
+	if r.Retryable != nil {
+		return r.Retryable(err)
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func (r *Retry) delay(attempt int) time.Duration {
+	minDelay, maxDelay := r.MinDelay, r.MaxDelay
+	if minDelay <= 0 {
+		minDelay = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+	delay := minDelay
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// Reconnect returns a sequence of the values read from a source which may fail
+// transiently, typically a network connection. The open function is called to
+// open the source, then to reopen it each time it yields or returns an error.
+// The resumeFrom argument is the key of the last value that was yielded, and
+// the reopened source must resume from it, including the values with this key.
+// The first call to open receives the start key, which is usually the zero
+// value of K, or a key persisted by the application to resume a previous merge.
+//
+//	remote := kway.Reconnect(func(from int64) (iter.Seq2[Event, error], error) {
+//		return client.Events(ctx, from)
+//	}, func(e Event) int64 { return e.Seq }, 0, kway.Retry{MaxAttempts: 5})
+//
+// The sequence keeps track of the last key that it yielded, and of the number
+// of values it yielded with this key, so reopened sources may restart from any
+// position up to the first value with the key: the values which were already
+// yielded are skipped, and the merge receives each value exactly once. This
+// allows sources to resume inclusively from the key, which is required when
+// multiple values share a key and the source failed in the middle of them.
+//
+// Errors are retried according to the Retry configuration, and only surface in
+// the returned sequence when they are not retryable or the source could not be
+// reopened within the maximum number of attempts; the sequence then ends after
+// yielding the error. This allows the merge functions of this package to treat
+// the errors of the sequence as unrecoverable failures of the source.
+//
+// Reconnect supports the WithContext option: when the context is canceled while
+// waiting to reopen the source, the sequence yields the context error and
+// stops. Other options are ignored.
+//
+// See ReconnectFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func Reconnect[T any, K cmp.Ordered](open func(resumeFrom K) (iter.Seq2[T, error], error), key func(T) K, start K, retry Retry, opts ...Option) iter.Seq2[T, error] {
+	return ReconnectFunc(open, key, cmp.Compare[K], start, retry, opts...)
+}
+
+// ReconnectFunc is like Reconnect but uses the given comparison function to
+// determine the order of keys.
+//
+// See Reconnect for more details.
+func ReconnectFunc[T, K any](open func(resumeFrom K) (iter.Seq2[T, error], error), key func(T) K, cmp func(K, K) int, start K, retry Retry, opts ...Option) iter.Seq2[T, error] {
+	o := makeOptions(opts)
+	ctx := o.context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		last := start
+		attempts := 0
+		// Number of values yielded with the last key, which is zero until the
+		// first value was yielded.
+		seen := 0
+
+		for {
+			seq, err := open(last)
+			if err == nil {
+				// Number of values of the reopened source to skip if they
+				// have the last key.
+				skip := seen
+				for v, e := range seq {
+					if e != nil {
+						err = e
+						break
+					}
+					k := key(v)
+					if seen > 0 {
+						c := cmp(k, last)
+						switch {
+						case c < 0:
+							continue
+						case c == 0 && skip > 0:
+							skip--
+							continue
+						case c == 0:
+							seen++
+						default:
+							last, seen, skip = k, 1, 0
+						}
+					} else {
+						last, seen = k, 1
+					}
+					attempts = 0
+					if !yield(v, nil) {
+						return
+					}
+				}
+				if err == nil {
+					return
+				}
+			}
+
+			if !retry.retryable(err) {
+				yield(zero, err)
+				return
+			}
+			if attempts++; retry.MaxAttempts > 0 && attempts > retry.MaxAttempts {
+				yield(zero, fmt.Errorf("giving up after %d attempts to reopen the source: %w", retry.MaxAttempts, err))
+				return
+			}
+
+			timer := time.NewTimer(retry.delay(attempts))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				yield(zero, ctx.Err())
+				return
+			}
+		}
+	}
+}
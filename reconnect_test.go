@@ -0,0 +1,193 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+// flakySource returns an open function for Reconnect serving the values from 0
+// to n-1, which fails after every `every` values.
+func flakySource(n, every int, resumes *[]int) func(int) (iter.Seq2[int, error], error) {
+	return func(after int) (iter.Seq2[int, error], error) {
+		*resumes = append(*resumes, after)
+		return func(yield func(int, error) bool) {
+			for i, v := 0, after+1; v < n; i, v = i+1, v+1 {
+				if i == every {
+					yield(0, errTransient)
+					return
+				}
+				if !yield(v, nil) {
+					return
+				}
+			}
+		}, nil
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	var resumes []int
+	seq := Reconnect(flakySource(10, 3, &resumes), identity[int], -1, Retry{MinDelay: time.Microsecond})
+
+	got, err := values(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(10)); !slices.Equal(got, want) {
+		t.Errorf("wrong values: %v", got)
+	}
+	if want := []int{-1, 2, 5, 8}; !slices.Equal(resumes, want) {
+		t.Errorf("wrong resume keys: got %v, want %v", resumes, want)
+	}
+}
+
+func TestReconnectMerge(t *testing.T) {
+	var resumes0, resumes1 []int
+	retry := Retry{MinDelay: time.Microsecond}
+	merged := Merge(
+		Reconnect(flakySource(20, 4, &resumes0), identity[int], -1, retry),
+		Reconnect(flakySource(10, 1, &resumes1), identity[int], -1, retry),
+	)
+	got, err := values(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 30 || !slices.IsSorted(got) {
+		t.Errorf("wrong merged values: %v", got)
+	}
+}
+
+func TestReconnectOpenError(t *testing.T) {
+	attempts := 0
+	open := func(after int) (iter.Seq2[int, error], error) {
+		if attempts++; attempts < 3 {
+			return nil, errTransient
+		}
+		return seqOf(1, 2, 3), nil
+	}
+	got, err := values(Reconnect(open, identity[int], 0, Retry{MinDelay: time.Microsecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []int{1, 2, 3}) || attempts != 3 {
+		t.Errorf("wrong values after %d attempts: %v", attempts, got)
+	}
+}
+
+func TestReconnectMaxAttempts(t *testing.T) {
+	attempts := 0
+	open := func(after int) (iter.Seq2[int, error], error) {
+		attempts++
+		return nil, errTransient
+	}
+	_, err := values(Reconnect(open, identity[int], 0, Retry{MaxAttempts: 2, MinDelay: time.Microsecond}))
+	if !errors.Is(err, errTransient) {
+		t.Errorf("expected the transient error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestReconnectNotRetryable(t *testing.T) {
+	errFatal := errors.New("fatal")
+	attempts := 0
+	open := func(after int) (iter.Seq2[int, error], error) {
+		attempts++
+		return func(yield func(int, error) bool) {
+			if yield(1, nil) {
+				yield(0, errFatal)
+			}
+		}, nil
+	}
+	retry := Retry{
+		MinDelay:  time.Microsecond,
+		Retryable: func(err error) bool { return err == errTransient },
+	}
+	var got []int
+	var errs []error
+	for v, err := range Reconnect(open, identity[int], 0, retry) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errFatal) {
+		t.Errorf("expected the fatal error, got %v", errs)
+	}
+	if !slices.Equal(got, []int{1}) || attempts != 1 {
+		t.Errorf("wrong values after %d attempts: %v", attempts, got)
+	}
+}
+
+func TestReconnectContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	open := func(after int) (iter.Seq2[int, error], error) {
+		cancel()
+		return nil, errTransient
+	}
+	_, err := values(Reconnect(open, identity[int], 0, Retry{MinDelay: time.Hour}, WithContext(ctx)))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	r := Retry{MinDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range []time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 60: 5 * time.Second} {
+		if want == 0 {
+			continue
+		}
+		if got := r.delay(attempt); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestReconnectInclusiveResume(t *testing.T) {
+	type record struct{ key, n int }
+	var records []record
+	for key := range 5 {
+		for n := range key + 1 {
+			records = append(records, record{key, n})
+		}
+	}
+
+	// The source restarts from the first record of the key it is given, which
+	// the sequence must skip when they were already yielded, and fails after
+	// every 6 values.
+	var resumes []int
+	open := func(from int) (iter.Seq2[record, error], error) {
+		resumes = append(resumes, from)
+		i, _ := slices.BinarySearchFunc(records, from, func(r record, key int) int { return r.key - key })
+		return func(yield func(record, error) bool) {
+			for j, r := range records[i:] {
+				if j == 6 {
+					yield(record{}, errTransient)
+					return
+				}
+				if !yield(r, nil) {
+					return
+				}
+			}
+		}, nil
+	}
+
+	key := func(r record) int { return r.key }
+	got, err := values(Reconnect(open, key, 0, Retry{MaxAttempts: 3, MinDelay: time.Microsecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, records) {
+		t.Errorf("expected %v, got %v", records, got)
+	}
+	if want := []int{0, 2, 3, 4}; !slices.Equal(resumes, want) {
+		t.Errorf("wrong resume keys: got %v, want %v", resumes, want)
+	}
+}
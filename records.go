@@ -0,0 +1,397 @@
+package kway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+)
+
+// recordBufferSize is the default size of buffers that records are read into.
+const recordBufferSize = 64 * 1024
+
+// RecordFormat describes how binary records are framed in the files merged by
+// MergeRecordFiles.
+type RecordFormat struct {
+	size      int
+	keyOffset int
+	keyLength int
+}
+
+// FixedSizeRecords returns the format of files made of records of size bytes.
+func FixedSizeRecords(size int) RecordFormat {
+	if size <= 0 || size > MaxRecordSize {
+		panic("kway: record size must be positive and at most MaxRecordSize")
+	}
+	return RecordFormat{size: size}
+}
+
+// LengthPrefixedRecords returns the format of files made of records prefixed
+// with their length encoded as an unsigned varint, which is the format of
+// files written with LengthPrefixedCodec.
+func LengthPrefixedRecords() RecordFormat {
+	return RecordFormat{}
+}
+
+// Key returns a copy of the format where records are ordered by the key held
+// in length bytes at the given offset of each record, instead of their full
+// contents. The format must be of fixed-size records, like the records of
+// classic external sort workloads (e.g. gensort), which are made of a 10 bytes
+// key and a 90 bytes payload:
+//
+//	format := kway.FixedSizeRecords(100).Key(0, 10)
+//
+// Keys are compared in place with bytes.Compare, without decoding records.
+func (f RecordFormat) Key(offset, length int) RecordFormat {
+	if f.size == 0 {
+		panic("kway: record keys require fixed-size records")
+	}
+	if offset < 0 || length <= 0 || offset+length > f.size {
+		panic("kway: record key out of range of the record size")
+	}
+	f.keyOffset, f.keyLength = offset, length
+	return f
+}
+
+// compare orders records by their keys, or their full contents if the format
+// has no key.
+func (f RecordFormat) compare(a, b []byte) int {
+	if f.keyLength == 0 {
+		return bytes.Compare(a, b)
+	}
+	i, j := f.keyOffset, f.keyOffset+f.keyLength
+	return bytes.Compare(a[i:j], b[i:j])
+}
+
+// appendKey appends the key of the record to b, which is the full record if
+// the format has no key.
+func (f RecordFormat) appendKey(b, record []byte) []byte {
+	if f.keyLength == 0 {
+		return append(b, record...)
+	}
+	return append(b, record[f.keyOffset:f.keyOffset+f.keyLength]...)
+}
+
+// appendRecord appends the record to b, framed like in the files of the format.
+func (f RecordFormat) appendRecord(b, record []byte) []byte {
+	if f.size == 0 {
+		b = binary.AppendUvarint(b, uint64(len(record)))
+	}
+	return append(b, record...)
+}
+
+// split appends the views of the records held entirely in data to views, and
+// returns the number of bytes that they span. When data ends with a partial
+// record, need is the minimum length that the data must have to hold it.
+func (f RecordFormat) split(views [][]byte, data []byte) (_ [][]byte, n, need int, err error) {
+	if f.size > 0 {
+		// Fast path for fixed-size records, which are sliced from the data
+		// without framing each record.
+		for ; n+f.size <= len(data); n += f.size {
+			views = append(views, data[n:n+f.size:n+f.size])
+		}
+		return views, n, f.size, nil
+	}
+	for n < len(data) {
+		start, end, err := frame(data[n:])
+		if err != nil {
+			return views, n, 0, err
+		}
+		if end > len(data)-n {
+			return views, n, end, nil
+		}
+		views = append(views, data[n+start:n+end:n+end])
+		n += end
+	}
+	return views, n, 0, nil
+}
+
+// frame returns the bounds of the length-prefixed record at the beginning of b.
+// If the record is not entirely held in b, end is greater than len(b), and is
+// the minimum length that b must have to hold it.
+func frame(b []byte) (start, end int, err error) {
+	length, n := binary.Uvarint(b)
+	switch {
+	case n == 0:
+		return 0, len(b) + 1, nil
+	case n < 0:
+		return 0, 0, fmt.Errorf("%w: record length overflows", ErrRecordTooLarge)
+	case length > MaxRecordSize:
+		return 0, 0, recordTooLarge(length)
+	}
+	return n, n + int(length), nil
+}
+
+// MergeRecordFiles merges the binary records of files in the given format,
+// ordered by bytes.Compare of the records, or of their keys if the format has
+// one (see RecordFormat.Key). This is the fast path for large compactions of
+// files of binary records, which yields views of the records in the buffers
+// that the files were read into, instead of copying each record through
+// decoders and the buffers of the merge.
+//
+// The files are read with positioned reads (pread) into buffers pooled across
+// the files. The merge yields batches of records, and both the batches and the
+// records that they contain are only valid until the next iteration of the
+// loop: the buffers are recycled when the loop body returns. Applications must
+// copy the records that they retain:
+//
+//	for records, err := range kway.MergeRecordFiles(paths, kway.FixedSizeRecords(32)) {
+//		if err != nil {
+//			return err
+//		}
+//		for _, record := range records {
+//			// record is only valid until the end of this iteration
+//		}
+//	}
+//
+// Compressed files are not supported, since their records do not exist in the
+// files before being decompressed. Errors opening or reading a file are
+// yielded in place of its records, and a file ending with a partial record
+// produces io.ErrUnexpectedEOF. Length-prefixed records larger than
+// MaxRecordSize produce an error wrapping ErrRecordTooLarge.
+//
+// See MergeRecordFilesFunc for a version of this function that allows the
+// caller to pass a custom comparison function.
+func MergeRecordFiles(paths []string, format RecordFormat) iter.Seq2[[][]byte, error] {
+	return MergeRecordFilesFunc(format.compare, paths, format)
+}
+
+// MergeRecordFilesFunc is like MergeRecordFiles but uses the given comparison
+// function to determine the order of records, the key of the format is
+// ignored.
+//
+// See MergeRecordFiles for more details.
+func MergeRecordFilesFunc(cmp func([]byte, []byte) int, paths []string, format RecordFormat) iter.Seq2[[][]byte, error] {
+	return func(yield func([][]byte, error) bool) {
+		pool := new(recordPool)
+		seqs := make([]iter.Seq2[[][]byte, error], len(paths))
+		for i, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				seqs[i] = func(yield func([][]byte, error) bool) {
+					yield(nil, err)
+				}
+				continue
+			}
+			defer f.Close()
+			seqs[i] = pool.records(f, format)
+		}
+
+		for records, err := range MergeSliceFunc(cmp, seqs...) {
+			if !yield(records, err) {
+				return
+			}
+			pool.release()
+		}
+	}
+}
+
+// recordChunk is a buffer that records were read into, and the views of the
+// records in the buffer.
+type recordChunk struct {
+	buf   []byte
+	views [][]byte
+}
+
+// recordPool recycles the chunks of records read from files. A chunk is retired
+// when the merge moves on to the next chunk of its file, but the views of its
+// records may still be held in the batch that the merge is building; retired
+// chunks are only released for reuse after the batch has been yielded.
+type recordPool struct {
+	free    []*recordChunk
+	retired []*recordChunk
+}
+
+func (p *recordPool) get(size int) *recordChunk {
+	for i, c := range p.free {
+		if cap(c.buf) >= size {
+			last := len(p.free) - 1
+			p.free[i], p.free[last] = p.free[last], nil
+			p.free = p.free[:last]
+			c.views = c.views[:0]
+			return c
+		}
+	}
+	return &recordChunk{buf: make([]byte, size)}
+}
+
+func (p *recordPool) put(c *recordChunk) {
+	p.free = append(p.free, c)
+}
+
+func (p *recordPool) retire(c *recordChunk) {
+	p.retired = append(p.retired, c)
+}
+
+func (p *recordPool) release() {
+	p.free = append(p.free, p.retired...)
+	clear(p.retired)
+	p.retired = p.retired[:0]
+}
+
+// records returns a sequence of the chunks of records read from f.
+func (p *recordPool) records(f io.ReaderAt, format RecordFormat) iter.Seq2[[][]byte, error] {
+	return func(yield func([][]byte, error) bool) {
+		size := max(recordBufferSize, format.size)
+		offset := int64(0)
+
+		for {
+			c := p.get(size)
+			n, err := f.ReadAt(c.buf[:cap(c.buf)], offset)
+			eof := err == io.EOF
+			if err != nil && !eof {
+				p.put(c)
+				yield(nil, err)
+				return
+			}
+
+			var i, need int
+			c.views, i, need, err = format.split(c.views, c.buf[:n])
+			if err != nil {
+				p.put(c)
+				yield(nil, err)
+				return
+			}
+			offset += int64(i)
+
+			if len(c.views) == 0 {
+				p.put(c)
+				if eof {
+					if i < n {
+						yield(nil, io.ErrUnexpectedEOF)
+					}
+					return
+				}
+				// The next record is larger than the buffer.
+				size = max(2*size, need)
+				continue
+			}
+
+			more := yield(c.views, nil)
+			p.retire(c)
+			if !more {
+				return
+			}
+			if eof {
+				if i < n {
+					yield(nil, io.ErrUnexpectedEOF)
+				}
+				return
+			}
+		}
+	}
+}
+
+// WriteRecordFile writes the records yielded by seq to the named file in the
+// given format, creating it with permissions perm if it does not exist. The
+// file is replaced atomically, see WriteFile.
+//
+// Records are copied to the file as-is, which combined with MergeRecordFiles
+// implements compactions of record files without decoding or encoding any of
+// the records:
+//
+//	format := kway.FixedSizeRecords(100).Key(0, 10)
+//	err := kway.WriteRecordFile("merged", kway.MergeRecordFiles(paths, format), format, 0644)
+//
+// Records of files with a fixed-size format must all have the size of the
+// format, and length-prefixed records must not exceed MaxRecordSize, otherwise
+// an error is returned.
+func WriteRecordFile(name string, seq iter.Seq2[[][]byte, error], format RecordFormat, perm fs.FileMode) error {
+	_, err := writeRecordFile(name, seq, format, perm, false)
+	return err
+}
+
+// WriteRecordFileCertified is like WriteRecordFile, but also validates that the
+// records of seq are ordered by their keys in the format, and returns a
+// certificate of the file (see WriteFileCertified).
+//
+// The keys hashed in the certificate are the keys of the records in the format,
+// or their full contents if the format has no key, and the bounds are the first
+// and last records framed like in the file. If a record is ordered before the
+// previous record, the file is not written and the function returns an
+// *OrderError.
+func WriteRecordFileCertified(name string, seq iter.Seq2[[][]byte, error], format RecordFormat, perm fs.FileMode) (Certificate, error) {
+	return writeRecordFile(name, seq, format, perm, true)
+}
+
+func writeRecordFile(name string, seq iter.Seq2[[][]byte, error], format RecordFormat, perm fs.FileMode, certify bool) (cert Certificate, err error) {
+	f, err := createAtomic(name)
+	if err != nil {
+		return cert, err
+	}
+	defer func() {
+		if err != nil {
+			f.abort()
+		}
+	}()
+
+	var out io.Writer = f
+	var c *certifier[[]byte]
+	var checksum hash.Hash32
+	var counter *countWriter
+	var last []byte
+	if certify {
+		c = newCertifier(format.compare, format.appendKey)
+		checksum = crc32.New(castagnoli)
+		counter = &countWriter{w: checksum}
+		out = io.MultiWriter(f, counter)
+	}
+
+	w := bufio.NewWriterSize(out, recordBufferSize)
+	var prefix [binary.MaxVarintLen64]byte
+	for records, err := range seq {
+		if err != nil {
+			return cert, err
+		}
+		for _, record := range records {
+			if format.size == 0 {
+				if len(record) > MaxRecordSize {
+					return cert, recordTooLarge(uint64(len(record)))
+				}
+				w.Write(binary.AppendUvarint(prefix[:0], uint64(len(record))))
+			} else if len(record) != format.size {
+				return cert, fmt.Errorf("record of %d bytes written to a file of %d bytes records", len(record), format.size)
+			}
+			if c != nil {
+				if err := c.add(record); err != nil {
+					return cert, err
+				}
+				if c.count == 1 {
+					cert.Min = format.appendRecord(nil, record)
+				}
+			}
+			w.Write(record)
+		}
+		if c != nil && len(records) > 0 {
+			// The records are views which may be invalidated by the next
+			// iteration, the last one is retained to compare it with the
+			// records of the next batch.
+			last = append(last[:0], records[len(records)-1]...)
+			c.last = last
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return cert, err
+	}
+	if err := f.commit(perm); err != nil {
+		return cert, err
+	}
+	if c != nil {
+		cert.Name = filepath.Base(name)
+		cert.Values = c.count
+		cert.Size = counter.n
+		cert.Checksum = checksum.Sum32()
+		cert.KeyHash = c.sum()
+		if c.count > 0 {
+			cert.Max = format.appendRecord(nil, last)
+		}
+	}
+	return cert, nil
+}
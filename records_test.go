@@ -0,0 +1,229 @@
+package kway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// makeRecord returns a record of the given size, starting with the key encoded
+// as a big-endian integer and padded with the low byte of the key.
+func makeRecord(key uint64, size int) []byte {
+	record := bytes.Repeat([]byte{byte(key)}, size)
+	binary.BigEndian.PutUint64(record, key)
+	return record
+}
+
+// checkRecord returns the key of a record made by makeRecord, and whether the
+// record is intact.
+func checkRecord(record []byte) (uint64, bool) {
+	key := binary.BigEndian.Uint64(record)
+	for _, b := range record[8:] {
+		if b != byte(key) {
+			return key, false
+		}
+	}
+	return key, true
+}
+
+func writeRecordFiles(t *testing.T, files ...[][]byte) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(files))
+	for i, records := range files {
+		paths[i] = filepath.Join(dir, "records"+string(rune('0'+i)))
+		if err := os.WriteFile(paths[i], bytes.Join(records, nil), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestMergeRecordFilesFixedSize(t *testing.T) {
+	const size = 1000
+	const count = 300
+
+	files := make([][][]byte, 3)
+	for i := range count * len(files) {
+		files[i%len(files)] = append(files[i%len(files)], makeRecord(uint64(i), size))
+	}
+
+	var keys []uint64
+	for records, err := range MergeRecordFiles(writeRecordFiles(t, files...), FixedSizeRecords(size)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range records {
+			key, ok := checkRecord(record)
+			if !ok || len(record) != size {
+				t.Fatalf("record %d was overwritten or truncated", key)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) != count*len(files) {
+		t.Fatalf("expected %d records, got %d", count*len(files), len(keys))
+	}
+	for i, key := range keys {
+		if key != uint64(i) {
+			t.Fatalf("record %d has key %d", i, key)
+		}
+	}
+}
+
+func TestMergeRecordFilesLengthPrefixed(t *testing.T) {
+	// Records of growing sizes, some larger than the read buffers.
+	sizes := []int{8, 100, 5000, 70000, 200000, 16, 300000}
+
+	files := make([][][]byte, 2)
+	for i := range 20 {
+		record := makeRecord(uint64(i), sizes[i%len(sizes)])
+		framed := binary.AppendUvarint(nil, uint64(len(record)))
+		files[i%2] = append(files[i%2], append(framed, record...))
+	}
+
+	var keys []uint64
+	for records, err := range MergeRecordFiles(writeRecordFiles(t, files...), LengthPrefixedRecords()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range records {
+			key, ok := checkRecord(record)
+			if !ok || len(record) != sizes[int(key)%len(sizes)] {
+				t.Fatalf("record %d was overwritten or truncated", key)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if !slices.Equal(keys, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestMergeRecordFilesCodec(t *testing.T) {
+	codec := LengthPrefixedCodec(
+		func(b []byte, v string) ([]byte, error) { return append(b, v...), nil },
+		func(b []byte) (string, error) { return string(b), nil },
+	)
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	for i, values := range [][]string{{"apple", "cherry"}, {"banana", "date"}} {
+		if err := WriteFile(paths[i], seqOf(values...), codec.Encoder(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for records, err := range MergeRecordFiles(paths, LengthPrefixedRecords()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range records {
+			got = append(got, string(record))
+		}
+	}
+	if !slices.Equal(got, []string{"apple", "banana", "cherry", "date"}) {
+		t.Errorf("unexpected records: %q", got)
+	}
+}
+
+func TestMergeRecordFilesErrors(t *testing.T) {
+	truncated := writeRecordFiles(t, [][]byte{makeRecord(1, 16), makeRecord(2, 16)[:10]})
+	missing := filepath.Join(t.TempDir(), "missing")
+	// The length of the second record would grow the buffers indefinitely.
+	corrupted := writeRecordFiles(t, [][]byte{
+		append(binary.AppendUvarint(nil, 16), makeRecord(1, 16)...),
+		binary.AppendUvarint(nil, 1<<62),
+	})
+
+	tests := []struct {
+		scenario string
+		paths    []string
+		format   RecordFormat
+		err      error
+	}{
+		{"partial record", truncated, FixedSizeRecords(16), io.ErrUnexpectedEOF},
+		{"missing file", []string{missing}, FixedSizeRecords(16), os.ErrNotExist},
+		{"record too large", corrupted, LengthPrefixedRecords(), ErrRecordTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			var err error
+			for _, err = range MergeRecordFiles(test.paths, test.format) {
+				if err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestMergeRecordFilesKey(t *testing.T) {
+	// Records of 100 bytes with a 10 bytes key at offset 10, preceded by bytes
+	// which would order the records differently.
+	record := func(key, header byte) []byte {
+		r := bytes.Repeat([]byte{'.'}, 100)
+		copy(r, bytes.Repeat([]byte{header}, 10))
+		copy(r[10:], bytes.Repeat([]byte{key}, 10))
+		return r
+	}
+	files := [][][]byte{
+		{record('a', 'z'), record('c', 'y'), record('e', 'x')},
+		{record('b', 'c'), record('d', 'b'), record('f', 'a')},
+	}
+	format := FixedSizeRecords(100).Key(10, 10)
+
+	output := filepath.Join(t.TempDir(), "merged")
+	if err := WriteRecordFile(output, MergeRecordFiles(writeRecordFiles(t, files...), format), format, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bytes.Join([][]byte{
+		files[0][0], files[1][0], files[0][1], files[1][1], files[0][2], files[1][2],
+	}, nil)
+	if !bytes.Equal(b, want) {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+}
+
+func TestWriteRecordFileLengthPrefixed(t *testing.T) {
+	files := [][][]byte{{[]byte("\x01a"), []byte("\x03ccc")}, {[]byte("\x02bb")}}
+	output := filepath.Join(t.TempDir(), "merged")
+	format := LengthPrefixedRecords()
+	if err := WriteRecordFile(output, MergeRecordFiles(writeRecordFiles(t, files...), format), format, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "\x01a\x02bb\x03ccc" {
+		t.Errorf("unexpected output: %q", b)
+	}
+}
+
+func TestWriteRecordFileSizeMismatch(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "merged")
+	seq := seqOf([][]byte{[]byte("abcd"), []byte("abc")})
+	if err := WriteRecordFile(output, seq, FixedSizeRecords(4), 0o644); err == nil {
+		t.Error("expected an error writing a record of the wrong size")
+	}
+	if _, err := os.Stat(output); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("output file was created: %v", err)
+	}
+}
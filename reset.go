@@ -0,0 +1,85 @@
+package kway
+
+import (
+	"iter"
+	"sync"
+)
+
+// Reset prepares the Merger to merge a new set of sequences with the same
+// options, reusing the tree, buffers, and bookkeeping allocated for the
+// previous merge. Services performing many small merges of the same number of
+// sources use it to avoid allocating a new Merger for each of them.
+//
+// If the previous merge was not consumed to completion, Reset stops it first.
+// The state observed through the methods of the Merger (Progress, Stats, Err,
+// etc...) is cleared. Closers configured with WithClosers are tied to the
+// original sources and are not closed again by Stop after a reset, and so is
+// the token configured with WithResumeToken: the new sequences are merged from
+// their beginning, and the tokens captured after a reset hold offsets from the
+// beginning of the new sequences.
+//
+// Reset must not be called while the merge is being consumed, and panics if
+// the number of sequences differs from the number of sequences the Merger was
+// constructed with.
+func (m *Merger[T]) Reset(seqs ...iter.Seq2[T, error]) {
+	if len(seqs) != len(m.seqs) {
+		panic("kway: Reset called with a different number of sequences")
+	}
+
+	if p := m.pull; p != nil && p.stop != nil {
+		p.stop()
+	}
+	m.finish()
+	clear(m.cancels)
+	m.cancels = m.cancels[:0]
+
+	var zero T
+	m.seqs = seqs
+	m.opts.resume = nil
+	for i := range m.positions {
+		m.positions[i].Count, m.positions[i].Last = 0, zero
+	}
+	m.sinceCheckpoint = 0
+	m.lastWatermark, m.hasWatermark = zero, false
+	m.lastDistinct, m.hasLastDistinct = zero, false
+	m.stopMonitor = nil
+	m.pull = nil
+	m.errs = nil
+	m.failed = nil
+	if m.duplicates != nil {
+		m.duplicates.reset()
+	}
+	if m.comparePanic != nil {
+		m.comparePanic.reset()
+	}
+	m.comparisons = 0
+	clear(m.adaptation.wins)
+	m.adaptation.values, m.adaptation.comparisons = 0, 0
+	clear(m.reads)
+
+	for i := range m.activity {
+		m.activity[i].done.Store(false)
+	}
+	if m.memory != nil {
+		m.memory.used = 0
+	}
+	if m.stats != nil {
+		m.stats.Total = Summary[T]{}
+		m.stats.Comparisons, m.stats.Rebalances = 0, 0
+		for i := range m.stats.Sources {
+			s := &m.stats.Sources[i]
+			s.Summary, s.Blocked, s.Err = Summary[T]{}, 0, nil
+		}
+	}
+
+	m.progress.mutex.Lock()
+	clear(m.progress.read)
+	clear(m.progress.yielded)
+	m.progress.total = 0
+	m.progress.mutex.Unlock()
+
+	m.started.Store(false)
+	m.stopping.Store(false)
+	m.done = make(chan struct{})
+	m.doneOnce = sync.Once{}
+}
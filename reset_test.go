@@ -0,0 +1,95 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerReset(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(0, 2, 4), seqOf(1, 3)},
+		WithStats(),
+	)
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Reset in the middle of a merge consumed with Next.
+	m.Reset(seqOf(10, 30), seqOf(20, 40))
+	if v, err, ok := m.Next(); !ok || err != nil || v != 10 {
+		t.Fatalf("next: %d, %v, %t", v, err, ok)
+	}
+
+	m.Reset(seqOf(5, 7), seqOf(6))
+	got, err = values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{5, 6, 7}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if p := m.Progress(); p.Total != 3 || !slices.Equal(p.Yielded, []int64{2, 1}) {
+		t.Errorf("unexpected progress after reset: %+v", p)
+	}
+	if s := m.Stats(); s.Total.Count != 3 {
+		t.Errorf("expected 3 values in stats, got %d", s.Total.Count)
+	}
+}
+
+func TestMergerResetResumeToken(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(0, 2, 4), seqOf(1, 3)},
+		WithResumeToken(ResumeToken{Offsets: []int64{2, 1}}),
+	)
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// The offsets of the token only apply to the original sequences.
+	m.Reset(seqOf(5, 7), seqOf(6))
+	got, err = values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{5, 6, 7}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if token := m.ResumeToken(); !slices.Equal(token.Offsets, []int64{2, 1}) {
+		t.Errorf("expected offsets [2 1], got %v", token.Offsets)
+	}
+}
+
+func TestMergerResetMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Reset to panic")
+		}
+	}()
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{seqOf(0)})
+	m.Reset(seqOf(0), seqOf(1))
+}
+
+func BenchmarkMergerReset(b *testing.B) {
+	m := newMerger(b, cmp.Compare[int], []iter.Seq2[int, error]{count(10), count(10), count(10)})
+	b.ReportAllocs()
+	for range b.N {
+		m.Reset(count(10), count(10), count(10))
+		for _, err := range m.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package kway
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ResumeToken is a serializable position of a Merger in its sources, which
+// allows a merge to be restarted after the values that were already consumed.
+//
+// Offsets holds the number of values consumed from each source, indexed by
+// source. Resuming from offsets requires the sources to produce the same
+// values when they are restarted, which is the case of files or of logs read
+// from fixed positions.
+type ResumeToken struct {
+	Offsets []int64
+}
+
+// resumeTokenVersion is the first byte of the binary encoding of resume tokens.
+const resumeTokenVersion = 1
+
+// MarshalBinary satisfies the encoding.BinaryMarshaler interface.
+func (t ResumeToken) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 1+binary.MaxVarintLen64*(len(t.Offsets)+1))
+	b = append(b, resumeTokenVersion)
+	b = binary.AppendUvarint(b, uint64(len(t.Offsets)))
+	for _, offset := range t.Offsets {
+		if offset < 0 {
+			return nil, fmt.Errorf("invalid resume token: negative offset %d", offset)
+		}
+		b = binary.AppendUvarint(b, uint64(offset))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary satisfies the encoding.BinaryUnmarshaler interface.
+func (t *ResumeToken) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 || b[0] != resumeTokenVersion {
+		return errors.New("invalid resume token: unsupported version")
+	}
+	b = b[1:]
+	n, size := binary.Uvarint(b)
+	if size <= 0 || n > uint64(len(b)) {
+		return errors.New("invalid resume token: malformed number of offsets")
+	}
+	b = b[size:]
+	offsets := make([]int64, n)
+	for i := range offsets {
+		v, size := binary.Uvarint(b)
+		if size <= 0 || int64(v) < 0 {
+			return errors.New("invalid resume token: malformed offset")
+		}
+		offsets[i], b = int64(v), b[size:]
+	}
+	if len(b) != 0 {
+		return errors.New("invalid resume token: trailing bytes")
+	}
+	t.Offsets = offsets
+	return nil
+}
+
+// WithResumeToken configures a Merger to resume a merge from the given token,
+// skipping the values that were consumed from each source before the token was
+// captured. The tokens captured by the resumed Merger hold the offsets from
+// the beginning of the sources, including the values that were skipped.
+//
+// NewMerger panics if the token does not have one offset per source.
+func WithResumeToken(token ResumeToken) Option {
+	return func(o *options) { o.resume = token.Offsets }
+}
+
+// ResumeToken returns the token capturing the current position of the Merger
+// in its sources, which only accounts for values that were yielded to the
+// application.
+//
+// The method is typically called in the function passed to WithCheckpoint, to
+// persist the token alongside the values written by the application, or the
+// tokens can be received in the merged output with Frames.
+func (m *Merger[T]) ResumeToken() ResumeToken {
+	offsets := make([]int64, len(m.positions))
+	for i := range offsets {
+		offsets[i] = m.positions[i].Count
+		if m.opts.resume != nil {
+			offsets[i] += m.opts.resume[i]
+		}
+	}
+	return ResumeToken{Offsets: offsets}
+}
+
+// Frame is an element of the sequence returned by Merger.Frames, holding either
+// a batch of merged values, or a token marking the position of the merge after
+// the values of all the frames that preceded it.
+type Frame[T any] struct {
+	Values []T
+	Token  *ResumeToken
+}
+
+// Frames returns a sequence yielding the merged values in batches, interleaved
+// with resume tokens at least every given number of values, and at the end of
+// the merge.
+//
+// Embedding the tokens in the output stream allows a downstream writer to
+// persist them in the same transaction or file as the values: after a crash,
+// the writer resumes the merge with WithResumeToken from the last token that
+// it persisted, instead of restarting from scratch.
+//
+// Like with Batches, the batches are reused across iterations, the application
+// must not retain them beyond the body of the loop ranging over the sequence.
+func (m *Merger[T]) Frames(every int) iter.Seq2[Frame[T], error] {
+	if every <= 0 {
+		panic("kway: resume token interval must be positive")
+	}
+	return func(yield func(Frame[T], error) bool) {
+		since := 0
+		stopped := false
+		marker := func() bool {
+			since = 0
+			token := m.ResumeToken()
+			if !yield(Frame[T]{Token: &token}, nil) {
+				stopped = true
+			}
+			return !stopped
+		}
+		fail := func(err error) bool {
+			if !yield(Frame[T]{}, err) {
+				stopped = true
+			}
+			return !stopped
+		}
+		m.run(func(values []T, sources []int) bool {
+			if !yield(Frame[T]{Values: values}, nil) {
+				stopped = true
+				return false
+			}
+			if !m.observe(values, sources, fail) {
+				return false
+			}
+			if since += len(values); since >= every {
+				return marker()
+			}
+			return true
+		}, fail)
+		if !stopped && since > 0 {
+			marker()
+		}
+	}
+}
+
+// skipValues returns a sequence skipping the first n values of seq, errors are
+// not counted as values and are yielded.
+func skipValues[T any](n int64, seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if n == 0 {
+		return seq
+	}
+	return func(yield func(T, error) bool) {
+		skipped := int64(0)
+		for v, err := range seq {
+			if err == nil && skipped < n {
+				skipped++
+				continue
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
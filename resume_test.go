@@ -0,0 +1,98 @@
+package kway
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestResumeTokenBinary(t *testing.T) {
+	for _, token := range []ResumeToken{
+		{Offsets: []int64{}},
+		{Offsets: []int64{0, 1, 1 << 40}},
+	} {
+		b, err := token.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded ResumeToken
+		if err := decoded.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(decoded.Offsets, token.Offsets) {
+			t.Errorf("expected %v, got %v", token.Offsets, decoded.Offsets)
+		}
+	}
+
+	for _, b := range [][]byte{nil, {0}, {1}, {1, 2, 0}, {1, 1, 0, 0}} {
+		var token ResumeToken
+		if err := token.UnmarshalBinary(b); err == nil {
+			t.Errorf("%v: expected an error", b)
+		}
+	}
+}
+
+func TestMergerFrames(t *testing.T) {
+	compare := func(a, b int) int { return a - b }
+	sources := func() []iter.Seq2[int, error] {
+		return []iter.Seq2[int, error]{sequence(0, 1000, 2), sequence(1, 1000, 2), sequence(500, 600, 1)}
+	}
+	want, err := values(newMerger(t, compare, sources()).All())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Consume the merge until the third token, then simulate a crash: the
+	// values received after the last token are lost.
+	var got []int
+	var pending []int
+	var last []byte
+	tokens := 0
+	for frame, err := range newMerger(t, compare, sources()).Frames(100) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.Token == nil {
+			pending = append(pending, frame.Values...)
+			continue
+		}
+		if last, err = frame.Token.MarshalBinary(); err != nil {
+			t.Fatal(err)
+		}
+		got, pending = append(got, pending...), nil
+		if tokens++; tokens == 3 {
+			break
+		}
+	}
+
+	var token ResumeToken
+	if err := token.UnmarshalBinary(last); err != nil {
+		t.Fatal(err)
+	}
+	var final *ResumeToken
+	for frame, err := range newMerger(t, compare, sources(), WithResumeToken(token)).Frames(100) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, frame.Values...)
+		if frame.Token != nil {
+			final = frame.Token
+		}
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("resumed merge produced different values: %d values, expected %d", len(got), len(want))
+	}
+	if final == nil || !slices.Equal(final.Offsets, []int64{500, 500, 100}) {
+		t.Errorf("unexpected final token: %+v", final)
+	}
+}
+
+func TestWithResumeTokenMismatch(t *testing.T) {
+	_, err := NewMerger(func(a, b int) int { return a - b }, []iter.Seq2[int, error]{count(1), count(2)},
+		WithResumeToken(ResumeToken{Offsets: []int64{1}}),
+	)
+	if err == nil {
+		t.Error("expected NewMerger to return an error")
+	}
+}
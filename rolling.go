@@ -0,0 +1,205 @@
+package kway
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io/fs"
+	"iter"
+	"path/filepath"
+	"strings"
+)
+
+// Rotation configures how WriteFiles splits its output into a series of files.
+//
+// A file is rotated when it reached either of the limits, the limits are
+// checked before writing each value so a file exceeds MaxBytes by at most the
+// size of one encoded value. The zero value writes all values to a single file.
+type Rotation struct {
+	// Maximum number of bytes written to a file.
+	MaxBytes int64
+	// Maximum number of values written to a file.
+	MaxValues int64
+	// Template of the file names, formatted with the zero-based index of each
+	// file, for example "part-%05d.gob". The default is "%06d".
+	Template string
+	// Name of the manifest file written to the directory after all files were
+	// written. No manifest is written if the name is empty.
+	Manifest string
+}
+
+func (r *Rotation) template() string {
+	if r.Template == "" {
+		return "%06d"
+	}
+	return r.Template
+}
+
+func (r *Rotation) full(values, size int64) bool {
+	return (r.MaxValues > 0 && values >= r.MaxValues) || (r.MaxBytes > 0 && size >= r.MaxBytes)
+}
+
+// WriteFiles writes the values yielded by seq to a series of files in dir,
+// encoded with the codec and rotated according to the rotation configuration.
+// Each file starts a new stream of the codec, so it can be read independently
+// of the others, for example with MergeFiles.
+//
+// Because seq is typically the output of a merge, the files partition the
+// ordered values in contiguous ranges, which is how compactions and sorted
+// exports produce outputs of bounded sizes.
+//
+// Like WriteFile, each file is written atomically: files are written to
+// temporary names and synced to stable storage, then renamed to their names
+// once all values were written, and the manifest is renamed last. Readers that
+// discover the files by reading the manifest never observe partial outputs.
+//
+// The manifest is written to a temporary file and synced along with the other
+// files, before any of them is renamed. If an error occurs while writing the
+// values or the manifest, the temporary files are removed, the existing files
+// of the directory are left untouched, and the error is returned with an
+// empty manifest. If renaming the files fails, the files renamed before the
+// error are not removed, since they may have replaced files that a previous
+// manifest references; the error is then returned with the manifest of the
+// files that were renamed, so the application can record or repair them.
+//
+// The function returns the manifest describing the files that were written,
+// which MergeManifest uses to merge the files back.
+// When seq yields no values, no files are written and the manifest is empty.
+func WriteFiles[T any](dir string, seq iter.Seq2[T, error], codec Codec[T], rotation Rotation, perm fs.FileMode) (Manifest, error) {
+	manifest, _, err := writeFiles(dir, seq, codec, rotation, perm, nil)
+	return manifest, err
+}
+
+// WriteFilesCertified is like WriteFiles, but also validates that the values of
+// seq are ordered according to cmp, and returns a certificate of each file, in
+// the order of the files of the manifest. The certificates are the entries of
+// the manifest extended with the hash of the keys of their values, see
+// WriteFileCertified for the requirements of the key function.
+//
+// The order is validated across files, so the certificates attest that the
+// files partition an ordered sequence. If a value is ordered before the
+// previous value, the function returns an *OrderError, with the same effect on
+// the files as any other error.
+func WriteFilesCertified[T any](dir string, seq iter.Seq2[T, error], codec Codec[T], rotation Rotation, cmp func(T, T) int, key func([]byte, T) []byte, perm fs.FileMode) (Manifest, []Certificate, error) {
+	return writeFiles(dir, seq, codec, rotation, perm, newCertifier(cmp, key))
+}
+
+func writeFiles[T any](dir string, seq iter.Seq2[T, error], codec Codec[T], rotation Rotation, perm fs.FileMode, c *certifier[T]) (manifest Manifest, certs []Certificate, err error) {
+	template := rotation.template()
+	if name := fmt.Sprintf(template, 0); strings.Contains(name, "%!") || name == fmt.Sprintf(template, 1) {
+		return manifest, certs, fmt.Errorf("file name template %q does not contain the file index", template)
+	}
+
+	var f *atomicFile
+	var pending []*atomicFile
+	var enc Encoder[T]
+	var buf []byte
+	var file ManifestFile
+	var first, last T
+	var checksum hash.Hash32
+	values := make([]T, 1)
+	published := 0
+	manifest.Files = []ManifestFile{}
+
+	defer func() {
+		if err != nil {
+			if f != nil {
+				f.abort()
+			}
+			for _, p := range pending {
+				p.abort()
+			}
+			manifest.Files = manifest.Files[:published]
+			if certs != nil {
+				certs = certs[:published]
+			}
+		}
+	}()
+
+	closeFile := func() error {
+		file.Checksum = checksum.Sum32()
+		file.Min, file.Max = encodeBound(codec, first), encodeBound(codec, last)
+		if err := f.sync(perm); err != nil {
+			return err
+		}
+		pending, f = append(pending, f), nil
+		manifest.Files = append(manifest.Files, file)
+		if c != nil {
+			certs = append(certs, Certificate{ManifestFile: file, KeyHash: c.sum()})
+		}
+		return nil
+	}
+
+	for v, err := range seq {
+		if err != nil {
+			return manifest, certs, err
+		}
+		if f != nil && rotation.full(file.Values, file.Size) {
+			if err := closeFile(); err != nil {
+				return manifest, certs, err
+			}
+		}
+		if f == nil {
+			file = ManifestFile{Name: fmt.Sprintf(template, len(manifest.Files))}
+			if f, err = createAtomic(filepath.Join(dir, file.Name)); err != nil {
+				return manifest, certs, err
+			}
+			enc = codec.Encoder()
+			checksum = crc32.New(castagnoli)
+			first = v
+		}
+		if c != nil {
+			if err := c.add(v); err != nil {
+				return manifest, certs, err
+			}
+		}
+		values[0] = v
+		if buf, err = enc.Encode(buf[:0], values); err != nil {
+			return manifest, certs, err
+		}
+		if _, err := f.Write(buf); err != nil {
+			return manifest, certs, err
+		}
+		checksum.Write(buf)
+		last = v
+		file.Values++
+		file.Size += int64(len(buf))
+	}
+
+	if f != nil {
+		if err := closeFile(); err != nil {
+			return manifest, certs, err
+		}
+	}
+	files := len(pending)
+	if rotation.Manifest != "" {
+		b, err := json.MarshalIndent(&manifest, "", "  ")
+		if err != nil {
+			return manifest, certs, err
+		}
+		if f, err = createAtomic(filepath.Join(dir, rotation.Manifest)); err != nil {
+			return manifest, certs, err
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			return manifest, certs, err
+		}
+		if err := f.sync(perm); err != nil {
+			return manifest, certs, err
+		}
+		pending, f = append(pending, f), nil
+	}
+	for len(pending) > 0 {
+		if err := pending[0].rename(); err != nil {
+			return manifest, certs, err
+		}
+		pending = pending[1:]
+		published = min(published+1, files)
+	}
+	if len(manifest.Files) > 0 || rotation.Manifest != "" {
+		if err := syncDir(dir); err != nil {
+			return manifest, certs, err
+		}
+	}
+	return manifest, certs, nil
+}
@@ -0,0 +1,228 @@
+package kway
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestWriteFiles(t *testing.T) {
+	codec := varintCodec()
+
+	for _, test := range []struct {
+		scenario string
+		rotation Rotation
+		values   []int64
+	}{
+		{
+			scenario: "single file",
+			rotation: Rotation{},
+			values:   []int64{100},
+		},
+		{
+			scenario: "rotate by count",
+			rotation: Rotation{MaxValues: 30},
+			values:   []int64{30, 30, 30, 10},
+		},
+		{
+			scenario: "rotate by size",
+			// Values 0 to 63 are encoded on 2 bytes (including the length
+			// prefix), and on 3 bytes afterwards.
+			rotation: Rotation{MaxBytes: 60},
+			values:   []int64{30, 30, 22, 18},
+		},
+		{
+			scenario: "rotate by count or size",
+			rotation: Rotation{MaxValues: 28, MaxBytes: 60},
+			values:   []int64{28, 28, 23, 20, 1},
+		},
+	} {
+		t.Run(test.scenario, func(t *testing.T) {
+			dir := t.TempDir()
+			test.rotation.Template = "part-%03d"
+			test.rotation.Manifest = "manifest.json"
+
+			manifest, err := WriteFiles(dir, count(100), codec, test.rotation, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var counts []int64
+			var paths []string
+			for i, file := range manifest.Files {
+				if file.Name != fmt.Sprintf("part-%03d", i) {
+					t.Errorf("unexpected file name: %q", file.Name)
+				}
+				info, err := os.Stat(filepath.Join(dir, file.Name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Size() != file.Size {
+					t.Errorf("%s: manifest size %d, file size %d", file.Name, file.Size, info.Size())
+				}
+				counts = append(counts, file.Values)
+				paths = append(paths, filepath.Join(dir, file.Name))
+			}
+			if !slices.Equal(counts, test.values) {
+				t.Errorf("expected files with %v values, got %v", test.values, counts)
+			}
+
+			read, err := ReadManifest(filepath.Join(dir, "manifest.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(read.Files, manifest.Files) {
+				t.Errorf("manifest mismatch:\nwant: %+v\ngot:  %+v", manifest.Files, read.Files)
+			}
+
+			got, err := values(MergeFiles(paths, codec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, _ := values(count(100)); !slices.Equal(got, want) {
+				t.Errorf("files do not contain the input values: %v", got)
+			}
+		})
+	}
+}
+
+func TestWriteFilesEmpty(t *testing.T) {
+	dir := t.TempDir()
+	manifest, err := WriteFiles(dir, count(0), varintCodec(), Rotation{Manifest: "manifest"}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 0 {
+		t.Errorf("unexpected files: %+v", manifest.Files)
+	}
+	read, err := ReadManifest(filepath.Join(dir, "manifest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Files) != 0 {
+		t.Errorf("unexpected files: %+v", read.Files)
+	}
+}
+
+func TestWriteFilesError(t *testing.T) {
+	dir := t.TempDir()
+	errval := errors.New("")
+
+	failing := func(yield func(int, error) bool) {
+		for i := range 10 {
+			if !yield(i, nil) {
+				return
+			}
+		}
+		yield(0, errval)
+	}
+	_, err := WriteFiles(dir, failing, varintCodec(), Rotation{MaxValues: 3, Manifest: "manifest"}, 0644)
+	if err != errval {
+		t.Fatalf("expected %v, got %v", errval, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("file left behind: %s", entry.Name())
+	}
+}
+
+func TestWriteFilesErrorKeepsPublishedFiles(t *testing.T) {
+	dir := t.TempDir()
+	rotation := Rotation{MaxValues: 3, Manifest: "manifest"}
+	if _, err := WriteFiles(dir, count(10), varintCodec(), rotation, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errval := errors.New("")
+	failing := func(yield func(int, error) bool) {
+		for i := range 10 {
+			if !yield(i+100, nil) {
+				return
+			}
+		}
+		yield(0, errval)
+	}
+	if _, err := WriteFiles(dir, failing, varintCodec(), rotation, 0644); err != errval {
+		t.Fatalf("expected %v, got %v", errval, err)
+	}
+
+	// The files of the first call must still match their manifest, the failed
+	// call must not have replaced them with the values it wrote before failing.
+	got, err := values(MergeManifest(filepath.Join(dir, "manifest"), varintCodec()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := values(count(10))
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWriteFilesRenameError(t *testing.T) {
+	for _, test := range []struct {
+		blocked   string
+		published int
+	}{
+		{blocked: "000001", published: 1},
+		{blocked: "manifest", published: 4},
+	} {
+		t.Run(test.blocked, func(t *testing.T) {
+			dir := t.TempDir()
+			// A directory at the name of a file makes renaming the file fail.
+			if err := os.Mkdir(filepath.Join(dir, test.blocked), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := WriteFiles(dir, count(10), varintCodec(), Rotation{MaxValues: 3, Manifest: "manifest"}, 0644)
+			if err == nil {
+				t.Fatal("expected an error renaming the files")
+			}
+
+			// The files renamed before the error are recorded in the returned
+			// manifest, and match their entries.
+			if len(manifest.Files) != test.published {
+				t.Fatalf("expected %d files in the manifest, got %+v", test.published, manifest.Files)
+			}
+			var got []int
+			names := []string{test.blocked}
+			for _, file := range manifest.Files {
+				values, err := values(readManifestFile(filepath.Join(dir, file.Name), file, varintCodec()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, values...)
+				names = append(names, file.Name)
+			}
+			want, _ := values(count(10))
+			if want = want[:min(3*test.published, 10)]; !slices.Equal(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+
+			// No temporary files are left in the directory.
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if !slices.Contains(names, entry.Name()) {
+					t.Errorf("file left behind: %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFilesTemplate(t *testing.T) {
+	_, err := WriteFiles(t.TempDir(), count(1), varintCodec(), Rotation{Template: "output"}, 0644)
+	if err == nil {
+		t.Error("expected an error for a template without the file index")
+	}
+}
@@ -0,0 +1,78 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"math/rand/v2"
+	"slices"
+)
+
+// Sample returns a sequence yielding a uniform random sample of n values of
+// seq, or all its values if it produced fewer than n values.
+//
+// The sample is built in one pass with reservoir sampling, retaining at most n
+// values in memory, and is yielded once seq is exhausted. The sampled values
+// are yielded in the order that seq produced them, so a sample of a merge is
+// ordered, which makes it suitable to compute the split points of partitioned
+// merges (see Partition).
+//
+// Errors produced by seq are yielded as they occur, before the sample.
+func Sample[T any](n int, seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if n < 0 {
+		panic("kway: sample size must not be negative")
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		reservoir := make([]spilled[T], 0, min(n, bufferSize))
+		count := int64(0)
+
+		for value, err := range seq {
+			if err != nil {
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+			if len(reservoir) < n {
+				reservoir = append(reservoir, spilled[T]{pos: count, value: value})
+			} else if i := rand.Int64N(count + 1); i < int64(n) {
+				reservoir[i] = spilled[T]{pos: count, value: value}
+			}
+			count++
+		}
+
+		slices.SortFunc(reservoir, func(a, b spilled[T]) int {
+			return cmp.Compare(a.pos, b.pos)
+		})
+		for _, e := range reservoir {
+			if !yield(e.value, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SampleEvery returns a sequence yielding every k-th value of seq, starting with
+// the first one. Unlike Sample, the values are yielded as seq produces them,
+// and the size of the sample is proportional to the length of seq.
+//
+// Errors produced by seq are always yielded.
+func SampleEvery[T any](k int, seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if k <= 0 {
+		panic("kway: sample stride must be positive")
+	}
+	return func(yield func(T, error) bool) {
+		i := 0
+		for value, err := range seq {
+			if err == nil {
+				skip := i%k != 0
+				if i++; skip {
+					continue
+				}
+			}
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	got, err := values(Sample(100, count(10000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 100 {
+		t.Fatalf("expected 100 values, got %d", len(got))
+	}
+	if !slices.IsSorted(got) {
+		t.Error("sampled values are not in order")
+	}
+	if len(slices.Compact(slices.Clone(got))) != 100 {
+		t.Error("sampled values contain duplicates")
+	}
+
+	got, err = values(Sample(100, count(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := values(count(10)); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSampleIsUniform(t *testing.T) {
+	// Each value of the sequence should be sampled with probability 1/10,
+	// count how many samples fall in each half of the sequence.
+	var low, high int
+	for range 100 {
+		for v, err := range Sample(100, count(1000)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v < 500 {
+				low++
+			} else {
+				high++
+			}
+		}
+	}
+	if low < 4500 || high < 4500 {
+		t.Errorf("sample is not uniform: %d values in the first half, %d in the second", low, high)
+	}
+}
+
+func TestSampleEvery(t *testing.T) {
+	errval := errors.New("")
+	seq := func(yield func(int, error) bool) {
+		_ = yield(0, nil) && yield(1, nil) && yield(0, errval) && yield(2, nil) && yield(3, nil) && yield(4, nil)
+	}
+
+	var got []int
+	var errs []error
+	for v, err := range SampleEvery(2, seq) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{0, 2, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
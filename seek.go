@@ -0,0 +1,49 @@
+package kway
+
+import "iter"
+
+// seeker advances an ordered sequence to the elements matching the keys of a
+// merge, in lockstep with the merge.
+type seeker[E, K any] struct {
+	next func() (E, error, bool)
+	stop func()
+	key  func(E) K
+	cmp  func(K, K) int
+	// The element that the sequence is positioned on, valid unless done is
+	// true.
+	current E
+	started bool
+	done    bool
+}
+
+func newSeeker[E, K any](key func(E) K, cmp func(K, K) int, seq iter.Seq2[E, error]) *seeker[E, K] {
+	s := &seeker[E, K]{key: key, cmp: cmp}
+	s.next, s.stop = PullBatched(bufferSize, seq)
+	return s
+}
+
+// seek moves the sequence to the first element with a key greater or equal to
+// k. Errors produced by the sequence are passed to fail, seek returns false if
+// fail returned false.
+func (s *seeker[E, K]) seek(k K, fail func(error) bool) bool {
+	for !s.done && (!s.started || s.cmp(s.key(s.current), k) < 0) {
+		elem, err, ok := s.next()
+		if !ok {
+			s.done = true
+			break
+		}
+		if err != nil {
+			if !fail(err) {
+				return false
+			}
+			continue
+		}
+		s.current, s.started = elem, true
+	}
+	return true
+}
+
+// match returns true if the sequence is positioned on an element with key k.
+func (s *seeker[E, K]) match(k K) bool {
+	return !s.done && s.started && s.cmp(s.key(s.current), k) == 0
+}
@@ -0,0 +1,59 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// SelectKeys merges sequences of values ordered by the keys returned by the key
+// function, and only yields the values whose key is present in the ordered
+// sequence of keys.
+//
+// The keys are matched against the merged values by advancing both sequences
+// in lockstep, like a merge, instead of building a hash set of the keys. This
+// keeps the selection streaming and bounded in memory regardless of the number
+// of keys, which makes it suitable to extract a large sorted subset of data
+// spread across many shards. The merge ends as soon as the sequence of keys is
+// exhausted, since no more values can match.
+//
+// All the values matching a key are yielded, including duplicates. Errors
+// produced by the sequence of keys or the sequences of values are yielded
+// inline.
+//
+// See SelectKeysFunc for a version of this function that allows the caller to
+// pass a custom comparison function for keys.
+func SelectKeys[T any, K cmp.Ordered](key func(T) K, keys iter.Seq2[K, error], seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return SelectKeysFunc(key, cmp.Compare[K], keys, seqs...)
+}
+
+// SelectKeysFunc is like SelectKeys but uses the given comparison function to
+// determine the order and equality of keys.
+//
+// See SelectKeys for more details.
+func SelectKeysFunc[T, K any](key func(T) K, cmp func(K, K) int, keys iter.Seq2[K, error], seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	merged := MergeByFunc(key, cmp, seqs...)
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		fail := func(err error) bool { return yield(zero, err) }
+
+		keys := newSeeker(func(k K) K { return k }, cmp, keys)
+		defer keys.stop()
+
+		for value, err := range merged {
+			if err != nil {
+				if !fail(err) {
+					return
+				}
+				continue
+			}
+			k := key(value)
+			if !keys.seek(k, fail) || keys.done {
+				return
+			}
+			if keys.match(k) && !yield(value, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSelectKeys(t *testing.T) {
+	identity := func(v int) int { return v }
+
+	got, err := values(SelectKeys(identity,
+		seqOf(1, 4, 5, 8, 20),
+		sequence(0, 10, 2),
+		sequence(1, 10, 3),
+		seqOf(4, 5, 30),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 4, 4, 4, 5, 8}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSelectKeysStopsWhenKeysAreExhausted(t *testing.T) {
+	identity := func(v int) int { return v }
+
+	read := 0
+	counting := func(yield func(int, error) bool) {
+		for i := 0; ; i++ {
+			read++
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+
+	got, err := values(SelectKeys(identity, seqOf(2, 3), counting))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if read > bufferSize+1 {
+		t.Errorf("too many values read: %d", read)
+	}
+}
+
+func TestSelectKeysError(t *testing.T) {
+	identity := func(v int) int { return v }
+	errval := errors.New("")
+
+	keys := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(3, nil)
+	}
+
+	var got []int
+	var errs []error
+	for v, err := range SelectKeys(identity, keys, seqOf(1, 2, 3)) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{1, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 1 || errs[0] != errval {
+		t.Errorf("expected one error, got %v", errs)
+	}
+}
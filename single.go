@@ -0,0 +1,68 @@
+package kway
+
+import "iter"
+
+// SingleSourceMode defines how a Merger handles merges of a single source, see
+// WithSingleSource.
+type SingleSourceMode int
+
+const (
+	// SingleSourceMerge processes a single source like any number of sources:
+	// values are buffered, and all the options of the Merger apply (e.g.
+	// statistics, checkpoints, transforms, or ordering policies). This is the
+	// default, which makes instrumented pipelines behave uniformly regardless
+	// of the number of sources.
+	SingleSourceMerge SingleSourceMode = iota
+	// SingleSourceValidate is like SingleSourceMerge, and also validates that
+	// the source produces ordered values, as if the Merger was configured with
+	// WithUnorderedPolicy(UnorderedError) when no policy was set.
+	SingleSourceValidate
+	// SingleSourcePassthrough yields the values of a single source as-is,
+	// bypassing the merge pipeline and the options of the Merger. Errors are
+	// still wrapped in *SourceError values, and collected when the Merger is
+	// configured with WithCollectErrors. This is the behavior of MergeFunc and
+	// the other Merge functions when given a single sequence.
+	SingleSourcePassthrough
+)
+
+// WithSingleSource configures how a Merger processes a merge of a single
+// source, the option has no effect when the Merger has more sources.
+func WithSingleSource(mode SingleSourceMode) Option {
+	return func(o *options) { o.singleSource = mode }
+}
+
+// passthrough returns the sequence of the single source of the Merger with its
+// errors wrapped, or nil if the source must go through the merge pipeline.
+func (m *Merger[T]) passthrough() iter.Seq2[T, error] {
+	if len(m.seqs) != 1 || m.opts.singleSource != SingleSourcePassthrough || m.duplicates != nil || m.opts.dropFailed || !m.opts.deadline.IsZero() {
+		return nil
+	}
+	seq := m.seqs[0]
+	if m.opts.resume != nil {
+		seq = skipValues(m.opts.resume[0], seq)
+	}
+	return func(yield func(T, error) bool) {
+		m.started.Store(true)
+		defer m.doneOnce.Do(func() { close(m.done) })
+		// See Merger.start for the ordering of started and stopping.
+		if m.stopping.Load() {
+			return
+		}
+
+		for v, err := range seq {
+			if m.stopping.Load() {
+				return
+			}
+			if err != nil {
+				err = m.sourceError(0, err)
+				if m.opts.collectErrors {
+					m.errs = append(m.errs, err)
+					continue
+				}
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
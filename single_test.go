@@ -0,0 +1,136 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerSingleSource(t *testing.T) {
+	errval := errors.New("")
+
+	source := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(3, nil) && yield(2, nil) && yield(0, errval) && yield(4, nil)
+	}
+
+	tests := []struct {
+		mode  SingleSourceMode
+		want  []int
+		count int64
+		errs  int
+	}{
+		{mode: SingleSourceMerge, want: []int{1, 3, 2, 4}, count: 4, errs: 1},
+		{mode: SingleSourceValidate, want: []int{1, 3, 4}, count: 3, errs: 2},
+		{mode: SingleSourcePassthrough, want: []int{1, 3, 2, 4}, count: 0, errs: 1},
+	}
+
+	for _, test := range tests {
+		for _, consume := range []string{"all", "next"} {
+			m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{source},
+				WithSingleSource(test.mode),
+				WithStats(),
+			)
+
+			var got []int
+			var errs int
+			collect := func(v int, err error) {
+				if err != nil {
+					var sourceErr *SourceError
+					if !errors.As(err, &sourceErr) {
+						t.Errorf("mode %d: expected *SourceError, got %v", test.mode, err)
+					}
+					errs++
+				} else {
+					got = append(got, v)
+				}
+			}
+
+			switch consume {
+			case "all":
+				for v, err := range m.All() {
+					collect(v, err)
+				}
+			case "next":
+				for {
+					v, err, ok := m.Next()
+					if !ok {
+						break
+					}
+					collect(v, err)
+				}
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("mode %d (%s): expected %v, got %v", test.mode, consume, test.want, got)
+			}
+			if errs != test.errs {
+				t.Errorf("mode %d (%s): expected %d errors, got %d", test.mode, consume, test.errs, errs)
+			}
+			if count := m.Stats().Total.Count; count != test.count {
+				t.Errorf("mode %d (%s): expected stats to count %d values, got %d", test.mode, consume, test.count, count)
+			}
+		}
+	}
+}
+
+func TestMergerSingleSourceCollectErrors(t *testing.T) {
+	errval := errors.New("")
+
+	source := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errval) && yield(2, nil) && yield(0, errval) && yield(3, nil)
+	}
+
+	tests := []struct {
+		scenario string
+		seqs     []iter.Seq2[int, error]
+		mode     SingleSourceMode
+	}{
+		{scenario: "merge", seqs: []iter.Seq2[int, error]{source}, mode: SingleSourceMerge},
+		{scenario: "passthrough", seqs: []iter.Seq2[int, error]{source}, mode: SingleSourcePassthrough},
+		{scenario: "one remaining source", seqs: []iter.Seq2[int, error]{seqOf[int](), source}, mode: SingleSourcePassthrough},
+	}
+
+	for _, test := range tests {
+		for _, consume := range []string{"all", "next"} {
+			m := newMerger(t, cmp.Compare[int], test.seqs,
+				WithSingleSource(test.mode),
+				WithCollectErrors(),
+			)
+
+			var got []int
+			switch consume {
+			case "all":
+				for v, err := range m.All() {
+					if err != nil {
+						t.Errorf("%s (%s): unexpected error yielded inline: %v", test.scenario, consume, err)
+					}
+					got = append(got, v)
+				}
+			case "next":
+				for {
+					v, err, ok := m.Next()
+					if !ok {
+						break
+					}
+					if err != nil {
+						t.Errorf("%s (%s): unexpected error yielded inline: %v", test.scenario, consume, err)
+					}
+					got = append(got, v)
+				}
+			}
+
+			if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+				t.Errorf("%s (%s): expected %v, got %v", test.scenario, consume, want, got)
+			}
+			err := m.Err()
+			if !errors.Is(err, errval) {
+				t.Fatalf("%s (%s): expected %v, got %v", test.scenario, consume, errval, err)
+			}
+			if errs := err.(interface{ Unwrap() []error }).Unwrap(); len(errs) != 2 {
+				t.Errorf("%s (%s): expected 2 errors, got %d", test.scenario, consume, len(errs))
+			}
+		}
+	}
+}
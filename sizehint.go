@@ -0,0 +1,40 @@
+package kway
+
+import (
+	"cmp"
+	"slices"
+)
+
+// WithSizeHints provides a Merger with the expected number of values that each
+// of its sources will produce, the hints are matched to sources by index.
+//
+// The Merger uses the hints to place the largest sources on the shortest paths
+// of its loser tree, which reduces the number of comparisons needed to merge
+// skewed inputs where a few sources produce most of the values. The paths only
+// differ in length when the number of sources is not a power of two.
+//
+// Hints only affect performance, the merged values are the same regardless of
+// their accuracy, including the order of equal values read from different
+// sources.
+func WithSizeHints(sizes ...int) Option {
+	return func(o *options) { o.sizeHints = sizes }
+}
+
+// sizeOrder returns the indexes of n sources sorted by decreasing size hints,
+// sources without a hint are considered empty.
+func sizeOrder(n int, sizes []int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	size := func(i int) int {
+		if i < len(sizes) {
+			return sizes[i]
+		}
+		return 0
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return cmp.Compare(size(j), size(i))
+	})
+	return order
+}
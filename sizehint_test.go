@@ -0,0 +1,62 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerSizeHints(t *testing.T) {
+	const n = 10000
+
+	merge := func(opts ...Option) (values []int, comparisons int) {
+		// The source at index 2 produces two out of every three values, it is
+		// on one of the deepest leaves of the tree without hints. The other
+		// sources take turns producing the remaining values.
+		seqs := []iter.Seq2[int, error]{
+			sequence(0, n, 12),
+			sequence(3, n, 12),
+			func(yield func(int, error) bool) {
+				for i := range n {
+					if i%3 != 0 && !yield(i, nil) {
+						return
+					}
+				}
+			},
+			sequence(6, n, 12),
+			sequence(9, n, 12),
+		}
+
+		compare := func(a, b int) int {
+			comparisons++
+			return cmp.Compare(a, b)
+		}
+		m := newMerger(t, compare, seqs, opts...)
+		for v, err := range m.All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+		return values, comparisons
+	}
+
+	want, withoutHints := merge()
+	got, withHints := merge(WithSizeHints(n/12, n/12, 2*n/3, n/12, n/12))
+
+	if !slices.Equal(got, want) {
+		t.Error("merged values differ with size hints")
+	}
+	if withHints >= withoutHints {
+		t.Errorf("expected fewer comparisons with size hints: %d >= %d", withHints, withoutHints)
+	}
+	t.Logf("comparisons: %d without hints, %d with hints", withoutHints, withHints)
+}
+
+func TestSizeOrder(t *testing.T) {
+	got := sizeOrder(5, []int{10, 30, 20})
+	if want := []int{1, 2, 0, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
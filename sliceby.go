@@ -0,0 +1,63 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// MergeSliceBy merges sequences producing slices of values, ordering values by
+// the keys returned by the key function.
+//
+// In addition to caching the keys of values like MergeBy, the function compares
+// the last key of each incoming batch with the heads of the other sequences.
+// When a batch does not need to be interleaved with values of other sequences,
+// it is yielded as-is, without copying it into an output buffer. This makes
+// the function particularly efficient on inputs made of large sorted runs, like
+// columnar data files partitioned by key ranges.
+//
+// See MergeSlice for more details.
+func MergeSliceBy[T any, K cmp.Ordered](key func(T) K, seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return MergeSliceByFunc(key, cmp.Compare[K], seqs...)
+}
+
+// MergeSliceByFunc is like MergeSliceBy but uses the given comparison function
+// to determine the order of keys.
+//
+// See MergeSliceBy for more details.
+func MergeSliceByFunc[T, K any](key func(T) K, cmp func(K, K) int, seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	if len(seqs) == 1 {
+		return seqs[0]
+	}
+	return mergeSliceTree(func() tree[T, K] { return makeKeyTree(key, seqs...) }, cmp)
+}
+
+// mergeSliceTree is like mergeTree but passes batches through when they do not
+// need to be merged with values of other sequences.
+func mergeSliceTree[T, K any](newTree func() tree[T, K], cmp func(K, K) int) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		tree := newTree()
+		tree.passthrough = true
+		tree.drainable = true
+		defer tree.stop()
+
+		buffer := make([]T, bufferSize)
+		for !tree.drain(yield) {
+			if batch := tree.take(cmp); len(batch) > 0 {
+				if !yield(batch, nil) {
+					return
+				}
+				continue
+			}
+			n, err := tree.next(buffer, cmp)
+			if err == nil && n == 0 {
+				if tree.count == 0 {
+					return
+				}
+				continue
+			}
+			if !yield(buffer[:n], err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package kway
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergeSliceBy(t *testing.T) {
+	type row struct {
+		key   int
+		value string
+	}
+
+	batches := func(batches ...[]int) iter.Seq2[[]row, error] {
+		return func(yield func([]row, error) bool) {
+			for _, batch := range batches {
+				rows := make([]row, len(batch))
+				for i, k := range batch {
+					rows[i] = row{key: k}
+				}
+				if !yield(rows, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	var got []int
+	var passthrough int
+	for rows, err := range MergeSliceBy(func(r row) int { return r.key },
+		batches([]int{0, 1, 2}, []int{10, 11}, []int{20, 21, 22}),
+		batches([]int{3, 4, 5}, []int{9, 12}),
+		batches([]int{6, 7}, []int{30}),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cap(rows) != bufferSize {
+			passthrough++
+		}
+		for _, r := range rows {
+			got = append(got, r.key)
+		}
+	}
+
+	want := []int{0, 1, 2, 3, 4, 5, 6, 7, 9, 10, 11, 12, 20, 21, 22, 30}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	// All batches except [9,12] can be passed through.
+	if passthrough != 6 {
+		t.Errorf("expected 6 batches to be passed through, got %d", passthrough)
+	}
+}
@@ -0,0 +1,54 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"os"
+)
+
+// Sort yields the values of seq in order. When seq produces more values than
+// fit in the limits of the spill configuration, sorted runs of values are
+// written to temporary files and merged, which supports sorting datasets much
+// larger than memory.
+//
+// Values which compare equal are yielded in the order of seq. The values are
+// only yielded once seq is exhausted, and the temporary files are removed when
+// the iteration ends. Errors produced by seq, or while accessing the temporary
+// files, are yielded inline. The codec of the spill configuration is required,
+// an error is yielded if it is nil.
+//
+// See SortFunc for a version of this function that allows the caller to pass a
+// custom comparison function.
+func Sort[T cmp.Ordered](spill Spill[T], seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return SortFunc(cmp.Compare[T], spill, seq)
+}
+
+// SortFunc is like Sort but uses the given comparison function to determine
+// the order of values.
+//
+// See Sort for more details.
+func SortFunc[T any](cmpValue func(T, T) int, spill Spill[T], seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	byValue := func(a, b spilled[T]) int {
+		if c := cmpValue(a.value, b.value); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.pos, b.pos)
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		dir, err := os.MkdirTemp(spill.Dir, "kway-sort-*")
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		for e, err := range externalSort(dir, &spill, byValue, numbered(seq)) {
+			if !yield(e.value, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	input := []int{5, 3, 9, 1, 7, 3, 8, 2, 6, 0, 4}
+	want := slices.Sorted(slices.Values(input))
+
+	tests := []struct {
+		scenario string
+		spill    Spill[int]
+	}{
+		{"in memory", Spill[int]{}},
+		{"limit", Spill[int]{Limit: 3}},
+		{"memory", Spill[int]{Memory: 16, SizeOf: func(int) int { return 8 }}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			spill := test.spill
+			spill.Codec = varintCodec()
+			spill.Dir = t.TempDir()
+
+			got, err := values(Sort(spill, seqOf(input...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+			if entries, _ := os.ReadDir(spill.Dir); len(entries) != 0 {
+				t.Errorf("temporary files were not removed: %v", entries)
+			}
+		})
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	// The codec encodes the keys in the first byte and the positions in the
+	// second byte of the values, which are compared by key only.
+	codec := LengthPrefixedCodec(
+		func(b []byte, v tagged) ([]byte, error) { return append(b, byte(v.key), byte(v.source)), nil },
+		func(b []byte) (tagged, error) { return tagged{int(b[0]), int(b[1])}, nil },
+	)
+	compare := func(a, b tagged) int { return cmp.Compare(a.key, b.key) }
+
+	var input []tagged
+	for i := range 50 {
+		input = append(input, tagged{(i * 7) % 5, i})
+	}
+	want := slices.Clone(input)
+	slices.SortStableFunc(want, compare)
+
+	for _, limit := range []int{1, 4, 100} {
+		spill := Spill[tagged]{Codec: codec, Dir: t.TempDir(), Limit: limit}
+		got, err := values(SortFunc(compare, spill, seqOf(input...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("limit=%d: expected %v, got %v", limit, want, got)
+		}
+	}
+}
+
+func TestSortError(t *testing.T) {
+	errval := errors.New("")
+	seq := func(yield func(int, error) bool) {
+		_ = yield(3, nil) && yield(1, nil) && yield(0, errval) && yield(2, nil)
+	}
+	spill := Spill[int]{Codec: varintCodec(), Dir: t.TempDir(), Limit: 1}
+
+	var got []int
+	var errs []error
+	for v, err := range Sort(spill, seq) {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !slices.Equal(errs, []error{errval}) {
+		t.Errorf("expected %v, got %v", errval, errs)
+	}
+}
+
+func TestSortNoCodec(t *testing.T) {
+	_, err := values(Sort(Spill[int]{Dir: t.TempDir(), Limit: 1}, count(10)))
+	if err != errSpillCodec {
+		t.Errorf("expected %v, got %v", errSpillCodec, err)
+	}
+}
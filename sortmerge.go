@@ -0,0 +1,66 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"sync"
+)
+
+// SortMerge sorts the given slices and yields their values merged in order.
+//
+// The slices are sorted in place, concurrently, when iteration begins, which
+// makes the function a faster alternative to concatenating and sorting the
+// slices when they are already held in memory (e.g. rows buffered by multiple
+// workers).
+//
+// See SortMergeFunc for a version of this function that allows the caller to
+// pass a custom comparison function.
+func SortMerge[T cmp.Ordered](inputs ...[]T) iter.Seq[T] {
+	return SortMergeFunc(cmp.Compare[T], inputs...)
+}
+
+// SortMergeFunc is like SortMerge but uses the given comparison function to
+// sort and merge the values.
+//
+// See SortMerge for more details.
+func SortMergeFunc[T any](cmp func(T, T) int, inputs ...[]T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var wg sync.WaitGroup
+		for _, s := range inputs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slices.SortFunc(s, cmp)
+			}()
+		}
+		wg.Wait()
+
+		seqs := make([]iter.Seq2[[]T, error], len(inputs))
+		for i, s := range inputs {
+			seqs[i] = chunks(s)
+		}
+
+		for batch := range MergeSliceFunc(cmp, seqs...) {
+			for _, v := range batch {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// chunks returns a sequence yielding the values of s in slices of up to
+// bufferSize values.
+func chunks[T any](s []T) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for len(s) > 0 {
+			n := min(len(s), bufferSize)
+			if !yield(s[:n:n], nil) {
+				return
+			}
+			s = s[n:]
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package kway
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSortMerge(t *testing.T) {
+	for k := range 5 {
+		inputs := make([][]int, k)
+		var want []int
+		for i := range inputs {
+			inputs[i] = rand.Perm(1000)
+			want = append(want, inputs[i]...)
+		}
+		slices.Sort(want)
+
+		got := slices.Collect(SortMerge(inputs...))
+		if !slices.Equal(got, want) {
+			t.Errorf("k=%d: merged values are not sorted", k)
+		}
+	}
+}
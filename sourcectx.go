@@ -0,0 +1,79 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+)
+
+// WithSourceContexts associates contexts with the sources of a Merger, the
+// contexts are matched to sources by index.
+//
+// When the context of a source is canceled, the Merger stops reading from the
+// source, yields the context error as a *SourceError, and carries on merging
+// the remaining sources. The contexts of the sources are combined with the
+// merge-level context configured with WithContext: when the latter is
+// canceled, all sources stop, and the Merger yields its error and stops.
+//
+// The Merger checks the context of a source between values. When sources are
+// read concurrently (see WithFlushInterval), the merge does not wait for a
+// source blocked on a read after its context was canceled, the goroutine
+// reading from the source exits when the read completes. Sources which may
+// block indefinitely should also use their context to abort their reads.
+func WithSourceContexts(ctxs ...context.Context) Option {
+	return func(o *options) { o.sourceContexts = ctxs }
+}
+
+// sourceContext returns the context of the source at index i, which is
+// canceled with the cause of the merge context when the merge context is
+// canceled.
+func (m *Merger[T]) sourceContext(i int) context.Context {
+	ctx := m.context()
+	if i >= len(m.opts.sourceContexts) || m.opts.sourceContexts[i] == nil {
+		return ctx
+	}
+	sourceCtx := m.opts.sourceContexts[i]
+	if ctx.Done() == nil {
+		return sourceCtx
+	}
+	sourceCtx, cancel := context.WithCancelCause(sourceCtx)
+	stop := context.AfterFunc(ctx, func() { cancel(context.Cause(ctx)) })
+	m.cancels = append(m.cancels, func() {
+		stop()
+		cancel(nil)
+	})
+	return sourceCtx
+}
+
+// canceled reports whether err is the error of the merge context, which was
+// canceled.
+func (m *Merger[T]) canceled(err error) bool {
+	ctx := m.context()
+	return ctx.Err() != nil && errors.Is(err, context.Cause(ctx))
+}
+
+// withContext returns a sequence yielding the values of seq until one of the
+// contexts is canceled, at which point it yields the cause of the cancellation
+// and stops. The contexts are checked in order, so the cause of the first one
+// is yielded when several were canceled.
+func withContext[T any](seq iter.Seq2[T, error], ctxs ...context.Context) iter.Seq2[T, error] {
+	ctxs = slices.DeleteFunc(ctxs, func(ctx context.Context) bool { return ctx.Done() == nil })
+	if len(ctxs) == 0 {
+		return seq
+	}
+	return func(yield func(T, error) bool) {
+		for v, err := range seq {
+			for _, ctx := range ctxs {
+				if ctx.Err() != nil {
+					var zero T
+					yield(zero, context.Cause(ctx))
+					return
+				}
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergerSourceContexts(t *testing.T) {
+	var cancel context.CancelFunc
+
+	canceled := func(yield func(int, error) bool) {
+		for i := 1; ; i += 2 {
+			if i == 5 {
+				cancel()
+			}
+			if !yield(i, nil) {
+				return
+			}
+		}
+	}
+
+	for _, opts := range [][]Option{
+		{},
+		{WithFlushInterval(time.Millisecond)},
+	} {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+
+		m := newMerger(t, cmp.Compare[int],
+			[]iter.Seq2[int, error]{seqOf(0, 2, 4, 6, 8), canceled},
+			append(opts, WithLabels("a", "b"), WithSourceContexts(nil, ctx))...,
+		)
+
+		var got []int
+		var errs []error
+		for v, err := range m.All() {
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				got = append(got, v)
+			}
+		}
+
+		if want := []int{0, 1, 2, 3, 4, 6, 8}; !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		var sourceErr *SourceError
+		if len(errs) != 1 || !errors.As(errs[0], &sourceErr) || sourceErr.Label != "b" || !errors.Is(errs[0], context.Canceled) {
+			t.Errorf("unexpected errors: %v", errs)
+		}
+	}
+}
+
+func TestMergerSourceContextsMergeCanceled(t *testing.T) {
+	for _, test := range []struct {
+		opts       []Option
+		concurrent bool
+	}{
+		{nil, false},
+		{[]Option{WithCollectErrors()}, false},
+		{[]Option{WithFlushInterval(time.Millisecond)}, true},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The contexts of the sources are not derived from the merge context,
+		// the Merger must still stop reading the sources when it is canceled.
+		read := 0
+		canceled := func(yield func(int, error) bool) {
+			for read = 1; ; read++ {
+				if read == 5 {
+					cancel()
+				}
+				if !yield(read, nil) {
+					return
+				}
+			}
+		}
+
+		m := newMerger(t, cmp.Compare[int],
+			[]iter.Seq2[int, error]{count(1000), canceled},
+			append(test.opts, WithContext(ctx), WithSourceContexts(context.Background(), context.Background()))...,
+		)
+
+		var errs []error
+		for _, err := range m.All() {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		var sourceErr *SourceError
+		if len(errs) != 1 || errors.As(errs[0], &sourceErr) || !errors.Is(errs[0], context.Canceled) {
+			t.Errorf("expected the merge cancellation to be yielded once, got %v", errs)
+		}
+		// Sources read concurrently may be read ahead of the merge.
+		if !test.concurrent && read > 6 {
+			t.Errorf("the source was read after the merge was canceled: %d values", read)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// MergeBySource merges multiple sequences into one, ordering values by the keys
+// returned by the key function, which receives the index of the sequence that
+// produced each value.
+//
+// The function allows merging sources which encode the same logical key in
+// different ways, for example when some sources identify records by numeric
+// IDs and others by their string representation: the key function normalizes
+// the keys of each source, without transforming the sequences themselves.
+// Like MergeBy, keys are computed once per value and cached.
+//
+// See Merge for more details.
+func MergeBySource[T any, K cmp.Ordered](key func(source int, v T) K, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return MergeBySourceFunc(key, cmp.Compare[K], seqs...)
+}
+
+// MergeBySourceFunc is like MergeBySource but uses the given comparison
+// function to determine the order of keys.
+//
+// See MergeBySource for more details.
+func MergeBySourceFunc[T, K any](key func(source int, v T) K, cmp func(K, K) int, seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	if len(seqs) == 1 {
+		return seqs[0]
+	}
+	bufferedSeqs := make([]iter.Seq2[[]T, error], len(seqs))
+	for i, seq := range seqs {
+		bufferedSeqs[i] = buffer(bufferSize, seq)
+	}
+	return unbuffer(mergeTree(func() tree[T, K] { return makeSourceKeyTree(key, bufferedSeqs...) }, cmp))
+}
+
+// MergeSliceBySource is like MergeBySource but merges sequences producing
+// slices of values.
+//
+// Normalizing keys in the merge, instead of transforming the values of the
+// sequences, preserves the ability to pass batches through as-is when they do
+// not need to be interleaved with values of other sequences, see MergeSliceBy.
+func MergeSliceBySource[T any, K cmp.Ordered](key func(source int, v T) K, seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return MergeSliceBySourceFunc(key, cmp.Compare[K], seqs...)
+}
+
+// MergeSliceBySourceFunc is like MergeSliceBySource but uses the given
+// comparison function to determine the order of keys.
+//
+// See MergeSliceBySource for more details.
+func MergeSliceBySourceFunc[T, K any](key func(source int, v T) K, cmp func(K, K) int, seqs ...iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	if len(seqs) == 1 {
+		return seqs[0]
+	}
+	return mergeSliceTree(func() tree[T, K] { return makeSourceKeyTree(key, seqs...) }, cmp)
+}
@@ -0,0 +1,69 @@
+package kway
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// parseID normalizes the keys of sources encoding IDs in decimal (source 0) or
+// hexadecimal (source 1).
+func parseID(source int, v string) int64 {
+	base := 10
+	if source == 1 {
+		base = 16
+	}
+	id, _ := strconv.ParseInt(v, base, 64)
+	return id
+}
+
+func TestMergeBySource(t *testing.T) {
+	got, err := values(MergeBySource(parseID,
+		seqOf("1", "9", "12", "30"),
+		seqOf("a", "f", "1b"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", "9", "a", "12", "f", "1b", "30"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMergeSliceBySource(t *testing.T) {
+	batches := func(batches ...[]string) iter.Seq2[[]string, error] {
+		return func(yield func([]string, error) bool) {
+			for _, batch := range batches {
+				if !yield(batch, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	disjoint := []string{"20", "21", "22"}
+	var got []string
+	var passedThrough bool
+	for batch, err := range MergeSliceBySource(parseID,
+		batches([]string{"1", "16"}, disjoint),
+		batches([]string{"a", "11"}),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch) > 0 && &batch[0] == &disjoint[0] {
+			passedThrough = true
+		}
+		got = append(got, batch...)
+	}
+
+	want := []string{"1", "a", "16", "11", "20", "21", "22"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !passedThrough {
+		t.Error("the disjoint batch was not passed through")
+	}
+}
@@ -0,0 +1,83 @@
+package kway
+
+import (
+	"iter"
+	"sync"
+)
+
+// Split separates the values and errors of seq into two sequences, so
+// consumers routing errors to a different path (e.g. a dead-letter queue) do
+// not have to branch on errors in the loop processing values.
+//
+// The values sequence drives the iteration of seq, which can only happen once:
+// ranging over the values sequence a second time yields no values. The errors
+// encountered while ranging over the values are buffered for the errors
+// sequence, which ends after the values sequence ended, either because seq was
+// exhausted or because the loop ranging over the values was interrupted.
+//
+// Up to n errors are buffered. When the buffer is full, the values sequence
+// blocks until errors are received, which applies backpressure to sources
+// producing errors faster than they are handled. Applications consuming more
+// than n errors must range over the errors sequence in a separate goroutine:
+//
+//	values, errs := kway.Split(kway.Merge(seqs...), 16)
+//	go func() {
+//		for err := range errs {
+//			deadLetter(err)
+//		}
+//	}()
+//	for v := range values {
+//		...
+//	}
+//
+// If the loop ranging over the errors sequence is interrupted, the errors that
+// follow are discarded and no longer block the values sequence.
+func Split[T any](seq iter.Seq2[T, error], n int) (values iter.Seq[T], errs iter.Seq[error]) {
+	s := &split{
+		errs:    make(chan error, max(n, 0)),
+		stopped: make(chan struct{}),
+	}
+	var started sync.Once
+
+	values = func(yield func(T) bool) {
+		first := false
+		started.Do(func() { first = true })
+		if !first {
+			return
+		}
+		defer close(s.errs)
+
+		for v, err := range seq {
+			if err != nil {
+				select {
+				case s.errs <- err:
+				case <-s.stopped:
+				}
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+
+	errs = func(yield func(error) bool) {
+		for err := range s.errs {
+			if !yield(err) {
+				s.stop()
+				return
+			}
+		}
+	}
+	return values, errs
+}
+
+type split struct {
+	errs     chan error
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func (s *split) stop() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+}
@@ -0,0 +1,97 @@
+package kway
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	err1, err2 := errors.New("1"), errors.New("2")
+	seq := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, err1) && yield(2, nil) && yield(0, err2) && yield(3, nil)
+	}
+
+	values, errs := Split(seq, 2)
+	got := slices.Collect(values)
+	gotErrs := slices.Collect(errs)
+
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("expected values %v, got %v", want, got)
+	}
+	if want := []error{err1, err2}; !slices.Equal(gotErrs, want) {
+		t.Errorf("expected errors %v, got %v", want, gotErrs)
+	}
+	if again := slices.Collect(values); len(again) != 0 {
+		t.Errorf("values were yielded twice: %v", again)
+	}
+}
+
+func TestSplitConcurrent(t *testing.T) {
+	const n = 1000
+	seq := func(yield func(int, error) bool) {
+		for i := range n {
+			if !yield(i, nil) || !yield(0, errors.New("")) {
+				return
+			}
+		}
+	}
+
+	values, errs := Split(seq, 0)
+	count := make(chan int)
+	go func() {
+		c := 0
+		for range errs {
+			c++
+		}
+		count <- c
+	}()
+
+	if got := slices.Collect(values); len(got) != n {
+		t.Errorf("expected %d values, got %d", n, len(got))
+	}
+	if c := <-count; c != n {
+		t.Errorf("expected %d errors, got %d", n, c)
+	}
+}
+
+func TestSplitStopErrors(t *testing.T) {
+	seq := func(yield func(int, error) bool) {
+		for i := range 100 {
+			if !yield(i, nil) || !yield(0, errors.New("")) {
+				return
+			}
+		}
+	}
+
+	values, errs := Split(seq, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range errs {
+			break
+		}
+	}()
+
+	// Once the errors loop is interrupted, errors no longer block the values.
+	if got := slices.Collect(values); len(got) != 100 {
+		t.Errorf("expected 100 values, got %d", len(got))
+	}
+	<-done
+}
+
+func TestSplitStopValues(t *testing.T) {
+	seq := func(yield func(int, error) bool) {
+		_ = yield(1, nil) && yield(0, errors.New("")) && yield(2, nil) && yield(0, errors.New(""))
+	}
+
+	values, errs := Split(seq, 1)
+	for v := range values {
+		if v == 2 {
+			break
+		}
+	}
+	if got := len(slices.Collect(errs)); got != 1 {
+		t.Errorf("expected the errors sequence to end after 1 error, got %d", got)
+	}
+}
@@ -0,0 +1,118 @@
+package kway
+
+import (
+	"iter"
+	"time"
+)
+
+// Summary is a summary of values produced by a merge.
+//
+// Since merged values are ordered, Min and Max are the first and last values
+// that were yielded. They are only valid when Count is greater than zero.
+type Summary[T any] struct {
+	Count int64
+	Min   T
+	Max   T
+	// Times at which the first and last values were yielded.
+	First time.Time
+	Last  time.Time
+}
+
+func (s *Summary[T]) add(values []T, now time.Time) {
+	if len(values) == 0 {
+		return
+	}
+	if s.Count == 0 {
+		s.Min = values[0]
+		s.First = now
+	}
+	s.Count += int64(len(values))
+	s.Max = values[len(values)-1]
+	s.Last = now
+}
+
+// SourceStats is the summary of values that a Merger yielded from one of its
+// sources.
+type SourceStats[T any] struct {
+	Index int
+	Label string
+	Summary[T]
+	// Time that the merge spent blocked waiting on the source to produce its
+	// batches of values. When the merge is slow, the source with the longest
+	// blocking time is usually the one to blame.
+	Blocked time.Duration
+	// Error which removed the source from a merge configured with
+	// WithDropFailedSources, or nil if the source did not fail.
+	Err error
+}
+
+// Stats contains statistics collected by a Merger configured with WithStats.
+type Stats[T any] struct {
+	Total   Summary[T]
+	Sources []SourceStats[T]
+	// Number of comparisons performed by the loser tree to order the values,
+	// which is the main cost of the merge.
+	Comparisons int64
+	// Number of times the Merger rebuilt its tree to adapt to the shape of
+	// the inputs, see WithAdaptiveLayout.
+	Rebalances int
+}
+
+// WithStats configures a Merger to collect statistics about the values that it
+// yields, globally and for each source. The statistics are available through
+// the Merger.Stats method during and after the merge, which saves applications
+// from making an extra pass over the data to compute them (e.g. to produce
+// metadata of compacted files).
+//
+// The statistics also report the time that the merge spent waiting on each
+// source, see SourceStats.
+func WithStats() Option {
+	return func(o *options) { o.stats = true }
+}
+
+// Stats returns the statistics collected by the Merger. The returned value is
+// a copy that the application may retain.
+//
+// Statistics are only collected when the Merger was configured with WithStats,
+// otherwise the method returns the zero value.
+func (m *Merger[T]) Stats() Stats[T] {
+	if m.stats == nil {
+		return Stats[T]{}
+	}
+	stats := *m.stats
+	stats.Sources = append([]SourceStats[T](nil), m.stats.Sources...)
+	return stats
+}
+
+// measureBlocking returns a sequence yielding the batches of seq, and adding
+// the time spent waiting on seq to the blocking time of the source.
+func (m *Merger[T]) measureBlocking(source int, seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		stats := &m.stats.Sources[source]
+		start := time.Now()
+		for values, err := range seq {
+			stats.Blocked += time.Since(start)
+			if !yield(values, err) {
+				return
+			}
+			start = time.Now()
+		}
+		stats.Blocked += time.Since(start)
+	}
+}
+
+func (m *Merger[T]) collectStats(values []T, sources []int) {
+	now := time.Now()
+	m.stats.Total.add(values, now)
+
+	for i := 0; i < len(sources); {
+		// Values from the same source are often adjacent, summarize runs of
+		// values at once.
+		j := i + 1
+		for j < len(sources) && sources[j] == sources[i] {
+			j++
+		}
+		m.stats.Sources[sources[i]].add(values[i:j], now)
+		i = j
+	}
+}
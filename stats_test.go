@@ -0,0 +1,74 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"testing"
+	"time"
+)
+
+func TestMergerStats(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			seqOf(1, 5, 9),
+			seqOf(2, 3),
+			seqOf[int](),
+		},
+		WithLabels("a", "b", "c"),
+		WithStats(),
+	)
+
+	if _, err := values(m.All()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := m.Stats()
+	if total := stats.Total; total.Count != 5 || total.Min != 1 || total.Max != 9 {
+		t.Errorf("unexpected total summary: %+v", total)
+	}
+	if stats.Total.First.After(stats.Total.Last) {
+		t.Errorf("first value yielded after the last: %v > %v", stats.Total.First, stats.Total.Last)
+	}
+
+	want := []struct {
+		label          string
+		count          int64
+		minVal, maxVal int
+	}{
+		{"a", 3, 1, 9},
+		{"b", 2, 2, 3},
+		{"c", 0, 0, 0},
+	}
+	for i, w := range want {
+		s := stats.Sources[i]
+		if s.Index != i || s.Label != w.label || s.Count != w.count || s.Min != w.minVal || s.Max != w.maxVal {
+			t.Errorf("unexpected summary of source %d: %+v", i, s)
+		}
+	}
+}
+
+func TestMergerStatsBlocked(t *testing.T) {
+	const delay = 10 * time.Millisecond
+
+	slow := func(yield func(int, error) bool) {
+		for i := range 3 {
+			time.Sleep(delay)
+			if !yield(2*i, nil) {
+				return
+			}
+		}
+	}
+
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{slow, sequence(1, 6, 2)}, WithStats())
+	if _, err := values(m.All()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := m.Stats()
+	if blocked := stats.Sources[0].Blocked; blocked < 3*delay {
+		t.Errorf("expected the slow source to block the merge for at least %v, got %v", 3*delay, blocked)
+	}
+	if blocked := stats.Sources[1].Blocked; blocked >= delay {
+		t.Errorf("expected the fast source not to block the merge, got %v", blocked)
+	}
+}
@@ -0,0 +1,83 @@
+package kway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+)
+
+// WithClosers associates closers with the sources of a Merger, the closers are
+// matched to sources by index. The closers are invoked by Merger.Stop, which
+// allows the Merger to release resources held by sources (e.g. open files or
+// network connections) when the merge is interrupted.
+func WithClosers(closers ...io.Closer) Option {
+	return func(o *options) { o.closers = closers }
+}
+
+// Stop gracefully stops the merge: the Merger stops reading new values from
+// its sources, yields the values that were already buffered, then ends the
+// sequences returned by All or Batches, which releases the goroutines reading
+// from the sources.
+//
+// Stop waits for the application to consume the buffered values, so it must be
+// called from a different goroutine than the one ranging over the merge. If ctx
+// is canceled before the merge ends, Stop returns the context error without
+// closing the sources.
+//
+// When the Merger is consumed with Next, Stop must be called from the goroutine
+// calling Next. The buffered values are discarded, and subsequent calls to Next
+// report the end of the merge.
+//
+// Once the merge has ended, Stop invokes the closers configured with
+// WithClosers, and returns their errors joined with errors.Join, each wrapped
+// in a *SourceError identifying the source. Calling Stop multiple times only
+// closes the sources once.
+func (m *Merger[T]) Stop(ctx context.Context) error {
+	m.stopping.Store(true)
+
+	if p := m.pull; p != nil {
+		p.events, p.done = nil, true
+		if p.stop != nil {
+			p.stop()
+		} else {
+			m.finish()
+		}
+	}
+
+	if m.started.Load() {
+		select {
+		case <-m.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var errs []error
+	m.closeOnce.Do(func() {
+		for i, c := range m.opts.closers {
+			if c == nil {
+				continue
+			}
+			if err := c.Close(); err != nil {
+				errs = append(errs, m.sourceError(i, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// stoppable returns a sequence yielding the batches of seq until the Merger is
+// stopped, which prevents the tree from reading more values.
+func (m *Merger[T]) stoppable(seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		if m.stopping.Load() {
+			return
+		}
+		for values, err := range seq {
+			if !yield(values, err) || m.stopping.Load() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package kway
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestMergerStop(t *testing.T) {
+	errval := errors.New("close failed")
+	closed := make([]bool, 2)
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{count(1e6), count(1e6)},
+		WithLabels("a", "b"),
+		WithClosers(
+			closerFunc(func() error { closed[0] = true; return nil }),
+			closerFunc(func() error { closed[1] = true; return errval }),
+		),
+	)
+
+	values := make(chan int)
+	go func() {
+		defer close(values)
+		for v, err := range m.All() {
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			values <- v
+		}
+	}()
+
+	<-values
+	stopped := make(chan error)
+	go func() { stopped <- m.Stop(context.Background()) }()
+
+	n := 1
+	for range values {
+		n++
+	}
+	if n >= 2e6 {
+		t.Errorf("the merge did not stop: %d values", n)
+	}
+
+	err := <-stopped
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Label != "b" || !errors.Is(err, errval) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !closed[0] || !closed[1] {
+		t.Errorf("sources were not closed: %v", closed)
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Errorf("sources closed twice: %v", err)
+	}
+}
+
+func TestMergerStopTimeout(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int], []iter.Seq2[int, error]{count(1e6), count(1e6)})
+
+	for range m.All() {
+		// The application is blocked in the loop, Stop cannot wait for the
+		// merge to end.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		if err := m.Stop(ctx); err != context.DeadlineExceeded {
+			t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+		break
+	}
+}
+
+func TestMergerStopBeforeStart(t *testing.T) {
+	for _, test := range []struct {
+		scenario string
+		sources  int
+		opts     []Option
+	}{
+		{"merge", 2, nil},
+		{"passthrough", 1, []Option{WithSingleSource(SingleSourcePassthrough)}},
+	} {
+		t.Run(test.scenario, func(t *testing.T) {
+			for range 100 {
+				var closed atomic.Bool
+				source := func(yield func(int, error) bool) {
+					for i := 0; ; i++ {
+						if closed.Load() {
+							t.Error("source read after it was closed")
+							return
+						}
+						if !yield(i, nil) {
+							return
+						}
+					}
+				}
+
+				seqs := make([]iter.Seq2[int, error], test.sources)
+				closers := make([]io.Closer, test.sources)
+				for i := range seqs {
+					seqs[i] = source
+					closers[i] = closerFunc(func() error { closed.Store(true); return nil })
+				}
+				m := newMerger(t, cmp.Compare[int], seqs, append(test.opts, WithClosers(closers...))...)
+
+				// Stop races with the start of the merge, it either waits for
+				// the merge to end or prevents it from reading the sources.
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					for range m.All() {
+					}
+				}()
+				if err := m.Stop(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				<-done
+			}
+		})
+	}
+}
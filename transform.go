@@ -0,0 +1,55 @@
+package kway
+
+import "iter"
+
+// WithSourceTransform configures a Merger to apply fn to the values read from
+// its sources before merging them. The function receives the index of the
+// source that the value was read from, which allows normalizing sources that
+// differ in representation (e.g. time zones or key prefixes) so they can be
+// merged with a single comparison function.
+//
+// The transform is applied to batches of values as they are read from each
+// source, it is cheaper than wrapping each source in a sequence transforming
+// values one at a time. Values produced by fn must preserve the order of the
+// source.
+//
+// If fn returns an error, the value is dropped and the error is yielded to the
+// application as a *SourceError identifying the source, then the merge carries
+// on with the next values.
+func WithSourceTransform[T any](fn func(source int, value T) (T, error)) Option {
+	return func(o *options) { o.transform = fn }
+}
+
+// transformBatches applies fn to the values of batches produced by seq, in
+// place. Values for which fn returns an error are removed from the batches,
+// the batch is split and the error is yielded where the value was.
+//
+// The memory budget, when not nil, is updated to account for the size of the
+// transformed values instead of the original ones.
+func transformBatches[T any](source int, fn func(int, T) (T, error), memory *memoryBudget[T], seq iter.Seq2[[]T, error]) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		for values, err := range seq {
+			i := 0
+			for j, v := range values {
+				if memory != nil {
+					memory.release(values[j : j+1])
+				}
+				v, fnErr := fn(source, v)
+				if fnErr != nil {
+					if !yield(values[i:j], fnErr) {
+						return
+					}
+					i = j + 1
+					continue
+				}
+				if memory != nil {
+					memory.acquire(v)
+				}
+				values[j] = v
+			}
+			if !yield(values[i:], err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerSourceTransform(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			seqOf(1, 3, 5),
+			seqOf(20, 40, 60), // scaled by 10 compared to the other source
+		},
+		WithSourceTransform(func(source int, v int) (int, error) {
+			if source == 1 {
+				v /= 10
+			}
+			return v, nil
+		}),
+	)
+
+	got, err := values(m.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMergerSourceTransformError(t *testing.T) {
+	errval := errors.New("odd")
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			seqOf(0, 1, 2, 3, 4),
+			seqOf(5, 6),
+		},
+		WithLabels("a", "b"),
+		WithSourceTransform(func(source int, v int) (int, error) {
+			if source == 0 && v%2 != 0 {
+				return v, errval
+			}
+			return v, nil
+		}),
+	)
+
+	var got []int
+	var errs []error
+	for v, err := range m.All() {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			got = append(got, v)
+		}
+	}
+	if want := []int{0, 2, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	for _, err := range errs {
+		var sourceErr *SourceError
+		if !errors.As(err, &sourceErr) || sourceErr.Label != "a" || !errors.Is(err, errval) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
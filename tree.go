@@ -1,57 +1,350 @@
 package kway
 
 import (
+	"cmp"
 	"iter"
+	"slices"
 )
 
-type tree[T any] struct {
-	cursors []cursor[T]
-	nodes   []node
+// tree is a loser-tree merging batches of values of type T, ordered by keys of
+// type K. When the key function is nil, K must be T and values are their own
+// keys.
+//
+// Keys are extracted once per value when the tree receives a new batch from a
+// cursor, and cached alongside the values, so the replays of the tree only
+// compare cached keys instead of re-running the key function O(log k) times.
+type tree[T, K any] struct {
+	cursors []cursor[T, K]
+	nodes   []node[K]
 	count   int
-	winner  node
+	winner  node[K]
+	key     func(T) K
+	// sourceKey, when not nil, is the key function of values which depends on
+	// the index of the cursor they were read from, see cursorKey.
+	sourceKey func(int, T) K
+	// errSource is the index of the cursor that produced the last error
+	// returned by next.
+	errSource int
+	// When eager is true, next returns the values it has produced instead of
+	// blocking to read the next batch of a cursor.
+	eager bool
+	// When passthrough is true, next stops before producing values from a new
+	// batch that can be passed through as a whole, see take.
+	passthrough bool
+	// When drainable is true, next stops when a single cursor is left, so its
+	// batches can be passed through, see drain.
+	drainable bool
+	// When fair is true, ties between equal heads are won by the cursor which
+	// produced a value least recently, see beats.
+	fair  bool
+	turns uint64
+	// compared holds the indexes of the cursors whose heads were passed to the
+	// last call of the comparison function, which identifies the sources of
+	// the values if the comparison panics.
+	compared [2]int
 }
 
-type node struct {
+// node is an entry of the tree, index is the position of the node in the tree
+// and value is the index of the cursor that it represents (or -1 if empty).
+//
+// The key of the cursor's head value is copied in the node, so replays of the
+// tree compare keys stored contiguously in the nodes array instead of chasing
+// pointers into the cursors' batches, which reduces cache misses for large k.
+// Only the winner's cursor advances during a replay, so the heads of the
+// losers stored in the tree never become stale.
+type node[K any] struct {
 	index int
 	value int
+	head  K
+	ok    bool // whether the cursor has a head value
 }
 
-type cursor[T any] struct {
-	values []T
-	err    error
-	next   func() ([]T, error, bool)
-	stop   func()
+func emptyNode[K any]() node[K] {
+	return node[K]{index: -1, value: -1}
 }
 
-func makeTree[T any](seqs ...iter.Seq2[[]T, error]) tree[T] {
-	t := tree[T]{
-		cursors: make([]cursor[T], len(seqs)),
-		winner:  node{index: -1, value: -1},
+type cursor[T, K any] struct {
+	key func(T) K
+	// presetKeys, when not nil, points to the keys of the last batch read from
+	// the cursor, which were computed by the source instead of the key function.
+	presetKeys *[]K
+	values     []T
+	keys       []K
+	buffer     []K
+	fresh      bool   // true if no values were consumed from the current batch
+	turn       uint64 // when the cursor last produced values, in fair trees
+	err        error
+	next       func() ([]T, error, bool)
+	stop       func()
+}
+
+// set assigns the batch of values and error to the cursor, extracting the keys
+// of values when the cursor has a key function.
+func (c *cursor[T, K]) set(values []T, err error) {
+	c.values, c.err = values, err
+	c.fresh = true
+	if c.presetKeys != nil {
+		c.keys = *c.presetKeys
+		return
+	}
+	key := c.key
+	if key == nil {
+		c.keys = any(values).([]K)
+		return
+	}
+	c.buffer = c.buffer[:0]
+	for _, v := range values {
+		c.buffer = append(c.buffer, key(v))
+	}
+	c.keys = c.buffer
+}
+
+// skip discards the n values at the head of the cursor.
+func (c *cursor[T, K]) skip(n int) {
+	c.values = c.values[n:]
+	c.keys = c.keys[n:]
+	c.fresh = false
+}
+
+// head returns the key of the head value of the cursor, and whether it exists.
+func (c *cursor[T, K]) head() (head K, ok bool) {
+	if len(c.keys) > 0 {
+		head, ok = c.keys[0], true
+	}
+	return head, ok
+}
+
+func makeTree[T any](seqs ...iter.Seq2[[]T, error]) tree[T, T] {
+	return makeKeyTree[T, T](nil, seqs...)
+}
+
+func makeKeyTree[T, K any](key func(T) K, seqs ...iter.Seq2[[]T, error]) tree[T, K] {
+	t := tree[T, K]{key: key}
+	t.reset(seqs...)
+	return t
+}
+
+func makeSourceKeyTree[T, K any](key func(int, T) K, seqs ...iter.Seq2[[]T, error]) tree[T, K] {
+	t := tree[T, K]{sourceKey: key}
+	t.reset(seqs...)
+	return t
+}
+
+// cursorKey returns the key function of values read from the cursor at index
+// i, which is nil when values are their own keys.
+func (t *tree[T, K]) cursorKey(i int) func(T) K {
+	if t.sourceKey == nil {
+		return t.key
 	}
+	key := t.sourceKey
+	return func(v T) K { return key(i, v) }
+}
 
+// reset prepares the tree to merge a new set of sequences, reusing the cursors,
+// nodes, and key buffers allocated for the previous merge. The cursors of the
+// previous merge must have been stopped.
+func (t *tree[T, K]) reset(seqs ...iter.Seq2[[]T, error]) {
+	t.cursors = slices.Grow(t.cursors[:0], len(seqs))[:len(seqs)]
 	for i, seq := range seqs {
+		// The tree reads from the cursor that won the last game, which
+		// requires pull semantics, unlike the two-way merge which drives one of
+		// its sequences with a range loop (see merge2Kernel). The cursors read
+		// batches of values, so each coroutine switch is amortized over a
+		// batch rather than paid for every value.
 		next, stop := iter.Pull2(seq)
-		t.cursors[i] = cursor[T]{next: next, stop: stop}
+		c := &t.cursors[i]
+		*c = cursor[T, K]{key: t.cursorKey(i), buffer: c.buffer[:0], next: next, stop: stop}
 	}
 
 	t.count = len(t.cursors)
-	t.nodes = make([]node, 2*len(t.cursors))
+	t.winner = emptyNode[K]()
+	t.errSource = 0
+	t.turns = 0
+	t.nodes = slices.Grow(t.nodes[:0], 2*len(t.cursors))[:2*len(t.cursors)]
 
 	head := t.nodes[:len(t.nodes)/2]
 	tail := t.nodes[len(t.nodes)/2:]
 
 	for i := range head {
-		head[i] = node{index: -1, value: -1}
+		head[i] = emptyNode[K]()
 	}
 	for i := range tail {
-		tail[i] = node{index: i + len(tail), value: i}
+		tail[i] = node[K]{index: i + len(tail), value: i}
 	}
-	return t
 }
 
-func (t *tree[T]) initialize(i int, cmp func(T, T) int) node {
+// reorder assigns the cursors to the leaves of the tree in the given order, the
+// cursor order[0] is placed on the leaf with the shortest path to the root of
+// the tree, order[1] on the next shortest, etc... It must be called before
+// values are read from the tree.
+//
+// When the number of cursors is not a power of two, some leaves play fewer
+// games than others, and replays of the tree after their cursors produced a
+// value cost fewer comparisons.
+func (t *tree[T, K]) reorder(order []int) {
+	tail := t.nodes[len(t.nodes)/2:]
+	leaves, _ := t.leaves()
+	for i, cursor := range order {
+		tail[leaves[i]].value = cursor
+	}
+}
+
+// leaves returns the indexes of the leaves in the tail of the nodes array
+// sorted by the number of games on their path to the root, and the number of
+// games of each leaf.
+func (t *tree[T, K]) leaves() (leaves, games []int) {
+	tail := t.nodes[len(t.nodes)/2:]
+	leaves = make([]int, len(tail))
+	games = make([]int, len(tail))
+	for i := range leaves {
+		leaves[i] = i
+		// A node is a game between two players only if both its subtrees
+		// have leaves, which is when its right child exists since indexes
+		// in the right subtree are greater than in the left.
+		for offset := parent(len(tail) + i); true; offset = parent(offset) {
+			if right(offset) < len(t.nodes) {
+				games[i]++
+			}
+			if offset == 0 {
+				break
+			}
+		}
+	}
+	slices.SortStableFunc(leaves, func(i, j int) int {
+		return cmp.Compare(games[i], games[j])
+	})
+	return leaves, games
+}
+
+// rebalance reassigns the cursors remaining in the tree to its leaves so the
+// cursors with the most wins are on the shortest paths, if the estimated number
+// of games played to produce the same wins decreases by more than 10%. Unlike
+// reorder, it is called after values were read from the tree, and rebuilds the
+// tree from the heads of the cursors. It returns true if the tree was rebuilt.
+func (t *tree[T, K]) rebalance(wins []int64, cmp func(K, K) int) bool {
+	if t.winner.index < 0 || t.count < 2 {
+		return false
+	}
+
+	tail := t.nodes[len(t.nodes)/2:]
+	leaves, games := t.leaves()
+
+	var order []int
+	var cost, balancedCost int64
+	for i, leaf := range tail {
+		if leaf.value < 0 {
+			continue
+		}
+		// The tree can only be rebuilt from the heads of the cursors, which
+		// are missing when the last read was interrupted before refilling a
+		// cursor.
+		if c := &t.cursors[leaf.value]; len(c.values) == 0 && c.err == nil {
+			return false
+		}
+		order = append(order, leaf.value)
+		cost += wins[leaf.value] * int64(games[i])
+	}
+
+	sortByWins(order, wins)
+	for i, cursor := range order {
+		balancedCost += wins[cursor] * int64(games[leaves[i]])
+	}
+	if 10*balancedCost >= 9*cost {
+		return false
+	}
+
+	for i := range tail {
+		tail[i] = emptyNode[K]()
+	}
+	for i, cursor := range order {
+		head, ok := t.cursors[cursor].head()
+		tail[leaves[i]] = node[K]{index: leaves[i] + len(tail), value: cursor, head: head, ok: ok}
+	}
+	for i := range t.nodes[:len(t.nodes)/2] {
+		t.nodes[i] = emptyNode[K]()
+	}
+	t.winner = t.initialize(0, cmp)
+	return true
+}
+
+// add adds cursors reading from the sequences to the tree. It can be called
+// between calls to next, in which case the first batches of the sequences are
+// read, and the tree is rebuilt from the heads of all its cursors.
+//
+// The indexes of the new cursors follow the indexes of the existing cursors,
+// including the cursors of sequences which were exhausted.
+func (t *tree[T, K]) add(cmp func(K, K) int, seqs ...iter.Seq2[[]T, error]) {
+	started := t.winner.index >= 0
+	tail := t.nodes[len(t.nodes)/2:]
+
+	var order []int
+	if started {
+		for _, leaf := range tail {
+			if leaf.value >= 0 {
+				order = append(order, leaf.value)
+			}
+		}
+	} else {
+		for i := range t.cursors {
+			order = append(order, i)
+		}
+	}
+
+	for _, seq := range seqs {
+		next, stop := iter.Pull2(seq)
+		order = append(order, len(t.cursors))
+		t.cursors = append(t.cursors, cursor[T, K]{key: t.cursorKey(len(t.cursors)), next: next, stop: stop})
+	}
+
+	if started {
+		// Cursors with no values left (e.g. after reporting an error) and
+		// the new cursors need their next batch to play games.
+		live := order[:0]
+		for _, i := range order {
+			c := &t.cursors[i]
+			if len(c.values) == 0 && c.err == nil {
+				values, err, ok := nextNonEmptyValues(c.next)
+				if !ok {
+					c.stop()
+					continue
+				}
+				c.set(values, err)
+			}
+			live = append(live, i)
+		}
+		order = live
+	}
+
+	t.count = len(order)
+	t.nodes = make([]node[K], 2*len(order))
+	head := t.nodes[:len(t.nodes)/2]
+	tail = t.nodes[len(t.nodes)/2:]
+	for i := range head {
+		head[i] = emptyNode[K]()
+	}
+	for i, cursor := range order {
+		tail[i] = node[K]{index: i + len(tail), value: cursor}
+		if started {
+			tail[i].head, tail[i].ok = t.cursors[cursor].head()
+		}
+	}
+
+	t.winner = emptyNode[K]()
+	if started && t.count > 0 {
+		t.winner = t.initialize(0, cmp)
+	}
+}
+
+// sortByWins sorts the cursor indexes by decreasing number of wins.
+func sortByWins(cursors []int, wins []int64) {
+	slices.SortStableFunc(cursors, func(i, j int) int {
+		return cmp.Compare(wins[j], wins[i])
+	})
+}
+
+func (t *tree[T, K]) initialize(i int, cmp func(K, K) int) node[K] {
 	if i >= len(t.nodes) {
-		return node{index: -1, value: -1}
+		return emptyNode[K]()
 	}
 	n1 := t.initialize(left(i), cmp)
 	n2 := t.initialize(right(i), cmp)
@@ -63,7 +356,7 @@ func (t *tree[T]) initialize(i int, cmp func(T, T) int) node {
 	return winner
 }
 
-func (t *tree[T]) playGame(n1, n2 node, cmp func(T, T) int) (loser, winner node) {
+func (t *tree[T, K]) playGame(n1, n2 node[K], cmp func(K, K) int) (loser, winner node[K]) {
 	if n1.value < 0 {
 		return n1, n2
 	}
@@ -72,34 +365,80 @@ func (t *tree[T]) playGame(n1, n2 node, cmp func(T, T) int) (loser, winner node)
 	}
 	c1 := &t.cursors[n1.value]
 	c2 := &t.cursors[n2.value]
-	if c1.err != nil {
+	// Cursors with an error and no values left win the game, so errors are
+	// reported as soon as possible.
+	if c1.err != nil && len(c1.values) == 0 {
 		return n2, n1
 	}
-	if c2.err != nil {
+	if c2.err != nil && len(c2.values) == 0 {
 		return n1, n2
 	}
-	if cmp(c1.values[0], c2.values[0]) < 0 {
+	if t.beats(n1, n2, cmp) {
 		return n2, n1
 	} else {
 		return n1, n2
 	}
 }
 
-func (t *tree[T]) next(buf []T, cmp func(T, T) int) (n int, err error) {
+// beats reports whether the head of n1 is ordered before the head of n2.
+//
+// When the heads are equal, the cursor with the lowest index wins, so equal
+// values are produced in the order of the cursors, and the output of the tree
+// does not depend on the placement of the cursors on the leaves.
+//
+// In fair trees, when the heads are equal, the cursor which produced a value
+// least recently wins instead. The winner of a replay is the cursor that just
+// produced a value, so it loses ties against the other cursors with equal
+// heads, which take turns in round-robin order instead of waiting for the
+// winner to exhaust its run of equal values.
+func (t *tree[T, K]) beats(n1, n2 node[K], cmp func(K, K) int) bool {
+	c := t.compare(&n1, &n2, cmp)
+	if c == 0 {
+		if t.fair {
+			return t.cursors[n1.value].turn < t.cursors[n2.value].turn
+		}
+		return n1.value < n2.value
+	}
+	return c < 0
+}
+
+// buffered reports whether the winner of the tree has values buffered in its
+// cursor, in which case next produces them without reading from the cursors.
+func (t *tree[T, K]) buffered() bool {
+	w := t.winner
+	return w.index >= 0 && w.value >= 0 && len(t.cursors[w.value].values) > 0
+}
+
+// compare compares the heads of n1 and n2, recording the indexes of their
+// cursors in t.compared first.
+func (t *tree[T, K]) compare(n1, n2 *node[K], cmp func(K, K) int) int {
+	t.compared = [2]int{n1.value, n2.value}
+	return cmp(n1.head, n2.head)
+}
+
+func (t *tree[T, K]) next(buf []T, cmp func(K, K) int) (n int, err error) {
+	return t.nextIndexed(buf, nil, cmp)
+}
+
+// nextIndexed is like next but when sources is not nil, it also writes the
+// index of the cursor that each value was read from.
+func (t *tree[T, K]) nextIndexed(buf []T, sources []int, cmp func(K, K) int) (n int, err error) {
 	if len(buf) == 0 || t.count == 0 {
 		return 0, nil
 	}
 
 	winner := t.winner
 	if winner.index < 0 {
-		for i := range t.cursors {
-			c := &t.cursors[i]
+		for i := len(t.nodes) / 2; i < len(t.nodes); i++ {
+			leaf := &t.nodes[i]
+			c := &t.cursors[leaf.value]
 			values, err, ok := nextNonEmptyValues(c.next)
 			if ok {
-				c.values, c.err = values, err
+				c.set(values, err)
+				leaf.head, leaf.ok = c.head()
 			} else {
 				c.stop()
-				t.nodes[i+len(t.cursors)] = node{index: -1, value: -1}
+				*leaf = emptyNode[K]()
 				t.count--
 				continue
 			}
@@ -108,29 +447,59 @@ func (t *tree[T]) next(buf []T, cmp func(T, T) int) (n int, err error) {
 			return 0, nil
 		}
 		winner = t.initialize(0, cmp)
+		if t.drainable && t.count == 1 {
+			t.winner = winner
+			return 0, nil
+		}
 	}
 
+	// streak counts the consecutive replays won by the same cursor, which hints
+	// that the cursor may be producing a run of values ordered before the heads
+	// of the other cursors.
+	streak := 0
+
 	for n < len(buf) {
 		c := &t.cursors[winner.value]
 
+		if t.passthrough && c.fresh && t.dominates(winner, cmp) {
+			break
+		}
+
 		if len(c.values) > 0 {
-			buf[n] = c.values[0]
-			n++
-			c.values = c.values[1:]
+			r := 1
+			if streak >= runThreshold {
+				r = t.run(winner, len(buf)-n, cmp)
+			}
+			copy(buf[n:], c.values[:r])
+			if sources != nil {
+				for i := range r {
+					sources[n+i] = winner.value
+				}
+			}
+			n += r
+			c.skip(r)
+			if t.fair {
+				t.turns++
+				c.turn = t.turns
+			}
 		}
 
 		if len(c.values) == 0 {
 			if err = c.err; err != nil {
 				c.err = nil
+				t.errSource = winner.value
+				break
+			}
+			if t.eager && n > 0 {
 				break
 			}
 			values, err, ok := nextNonEmptyValues(c.next)
 			if ok {
-				c.values, c.err = values, err
+				c.set(values, err)
 			} else {
 				c.stop()
 				winner.value = -1
-				t.nodes[winner.index] = node{index: -1, value: -1}
+				t.nodes[winner.index] = emptyNode[K]()
 				t.count--
 				if t.count == 0 {
 					break
@@ -138,18 +507,18 @@ func (t *tree[T]) next(buf []T, cmp func(T, T) int) (n int, err error) {
 			}
 		}
 
+		if winner.value >= 0 {
+			winner.head, winner.ok = c.head()
+		}
+
+		previous := winner.value
+
 		for offset := parent(winner.index); true; offset = parent(offset) {
-			player := t.nodes[offset]
+			player := &t.nodes[offset]
 
 			if player.value >= 0 {
-				if winner.value < 0 {
-					t.nodes[offset], winner = winner, player
-				} else {
-					c1 := &t.cursors[player.value]
-					c2 := &t.cursors[winner.value]
-					if len(c1.values) == 0 || (len(c2.values) != 0 && cmp(c1.values[0], c2.values[0]) < 0) {
-						t.nodes[offset], winner = winner, player
-					}
+				if winner.value < 0 || !player.ok || (winner.ok && t.beats(*player, winner, cmp)) {
+					*player, winner = winner, *player
 				}
 			}
 
@@ -157,13 +526,153 @@ func (t *tree[T]) next(buf []T, cmp func(T, T) int) (n int, err error) {
 				break
 			}
 		}
+
+		if winner.value == previous {
+			streak++
+		} else {
+			streak = 0
+		}
+
+		if t.drainable && t.count == 1 {
+			break
+		}
 	}
 
 	t.winner = winner
 	return n, err
 }
 
-func (t *tree[T]) stop() {
+// runThreshold is the number of consecutive replays won by the same cursor
+// after which the tree starts looking for runs of values, see run.
+const runThreshold = 2
+
+// run returns the length of the run of values at the head of the winner's
+// cursor which are ordered before the heads of all other cursors, up to limit.
+//
+// Emitting a run of values at once costs O(log k) comparisons to find the
+// second smallest head of the tree, and one comparison per value in the run,
+// instead of replaying the tree for each value. This greatly reduces the cost
+// of merging inputs with long runs of duplicate or clustered keys.
+func (t *tree[T, K]) run(winner node[K], limit int, cmp func(K, K) int) int {
+	c := &t.cursors[winner.value]
+	limit = min(limit, len(c.keys))
+
+	var next *node[K]
+	for offset := parent(winner.index); true; offset = parent(offset) {
+		if player := &t.nodes[offset]; player.value >= 0 {
+			if !player.ok {
+				return 1
+			}
+			if next == nil {
+				next = player
+			} else if c := t.compare(player, next, cmp); c < 0 || (c == 0 && player.value < next.value) {
+				next = player
+			}
+		}
+		if offset == 0 {
+			break
+		}
+	}
+
+	if next == nil {
+		return limit
+	}
+
+	// The values equal to the next head are not part of the run when the next
+	// cursor wins the ties, which is always the case in fair trees.
+	bound := 0
+	if t.fair || next.value < winner.value {
+		bound = -1
+	}
+	r := 1
+	t.compared = [2]int{winner.value, next.value}
+	for r < limit && cmp(c.keys[r], next.head) <= bound {
+		r++
+	}
+	return r
+}
+
+// dominates reports whether all the values in the batch of the winner's cursor
+// are ordered before the heads of all other cursors.
+//
+// The second smallest head of the tree must have lost its last game against the
+// winner, so it is one of the losers stored on the winner's path to the root,
+// which is why only those nodes need to be compared.
+func (t *tree[T, K]) dominates(winner node[K], cmp func(K, K) int) bool {
+	c := &t.cursors[winner.value]
+	if len(c.keys) == 0 {
+		return false
+	}
+	last := c.keys[len(c.keys)-1]
+
+	for offset := parent(winner.index); true; offset = parent(offset) {
+		if player := &t.nodes[offset]; player.value >= 0 {
+			if !player.ok {
+				return false
+			}
+			t.compared = [2]int{player.value, winner.value}
+			if c := cmp(player.head, last); c < 0 || (c == 0 && player.value < winner.value) {
+				return false
+			}
+		}
+		if offset == 0 {
+			break
+		}
+	}
+	return true
+}
+
+// take returns the batch of the winner's cursor if none of its values need to
+// be merged with values of other cursors, in which case the batch is consumed
+// and can be passed through as-is. The method returns nil otherwise.
+func (t *tree[T, K]) take(cmp func(K, K) int) []T {
+	winner := t.winner
+	if winner.index < 0 || winner.value < 0 {
+		return nil
+	}
+	c := &t.cursors[winner.value]
+	if !c.fresh || !t.dominates(winner, cmp) {
+		return nil
+	}
+	batch := c.values
+	c.values = c.values[len(c.values):]
+	c.keys = c.keys[len(c.keys):]
+	c.fresh = false
+	return batch
+}
+
+// peek returns the key of the next value that the tree will produce, and
+// whether it is known without reading more values from the cursors.
+func (t *tree[T, K]) peek() (head K, ok bool) {
+	if winner := t.winner; winner.index >= 0 && winner.value >= 0 {
+		head, ok = t.cursors[winner.value].head()
+	}
+	return head, ok
+}
+
+// drain yields the batches of the last cursor of the tree as-is, without
+// copying them. It returns false if the tree has more than one cursor left.
+func (t *tree[T, K]) drain(yield func([]T, error) bool) bool {
+	winner := t.winner
+	if t.count != 1 || winner.index < 0 || winner.value < 0 {
+		return false
+	}
+	c := &t.cursors[winner.value]
+	t.count = 0
+	defer c.stop()
+
+	if (len(c.values) > 0 || c.err != nil) && !yield(c.values, c.err) {
+		return true
+	}
+	for {
+		values, err, ok := c.next()
+		if !ok || ((len(values) > 0 || err != nil) && !yield(values, err)) {
+			return true
+		}
+	}
+}
+
+func (t *tree[T, K]) stop() {
 	for _, c := range t.cursors {
 		c.stop()
 	}
@@ -1,6 +1,7 @@
 package kway
 
 import (
+	"cmp"
 	"iter"
 	"slices"
 	"strings"
@@ -116,3 +117,39 @@ func TestParent(t *testing.T) {
 		t.Errorf("expected parent of 22 to be 10, got %d", p)
 	}
 }
+
+func TestTreeManySequences(t *testing.T) {
+	const k = 100
+
+	seqs := make([]iter.Seq2[[]int, error], k)
+	want := make([]int, 0, 10*k)
+	for i := range seqs {
+		values := make([]int, 10)
+		for j := range values {
+			values[j] = (j * k) + ((i * 37) % k)
+		}
+		want = append(want, values...)
+		seqs[i] = words(values...)
+	}
+	slices.Sort(want)
+
+	tree := makeTree(seqs...)
+	defer tree.stop()
+
+	var got []int
+	buffer := make([]int, 7)
+	for {
+		n, err := tree.next(buffer, cmp.Compare[int])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+		got = append(got, buffer[:n]...)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("expected values to be in order, got %v", got)
+	}
+}
@@ -0,0 +1,305 @@
+package kway
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+	"math"
+	"os"
+	"slices"
+)
+
+// Spill configures the temporary files that functions processing more values
+// than fit in memory write values to.
+type Spill[T any] struct {
+	// Codec used to encode values in the temporary files, it is required.
+	Codec Codec[T]
+	// Directory where the temporary files are created, the default temporary
+	// directory is used if empty.
+	Dir string
+	// Maximum number of values held in memory, values are spilled to disk
+	// when the limit is exceeded. Zero means a default of 1M values, unless
+	// Memory is set.
+	Limit int
+	// Maximum number of bytes held in memory, as reported by the SizeOf
+	// function, values are spilled to disk when the limit is exceeded. Memory
+	// is ignored if SizeOf is nil.
+	Memory int64
+	// Returns the size in bytes that a value occupies in memory.
+	SizeOf func(T) int
+}
+
+const defaultSpillLimit = 1 << 20
+
+var errSpillCodec = errors.New("spill configuration has no codec")
+
+func (s *Spill[T]) limit() int {
+	if s.Limit > 0 {
+		return s.Limit
+	}
+	if s.memory() {
+		return math.MaxInt
+	}
+	return defaultSpillLimit
+}
+
+func (s *Spill[T]) memory() bool {
+	return s.Memory > 0 && s.SizeOf != nil
+}
+
+// MergeUniqueBy merges sequences and yields only the first occurrence of each
+// key returned by the key function, in the order of the merge.
+//
+// Unlike deduplicating adjacent values of the merge, which only removes values
+// that compare equal, the keys may define an equality unrelated to the order
+// of values (e.g. merging records by time and deduplicating them by identity),
+// so duplicates can be arbitrarily far apart in the merged sequence. When the
+// number of merged values exceeds the limit of the spill configuration, the
+// function sorts them externally using temporary files, which supports
+// deduplicating datasets much larger than memory.
+//
+// The values are only yielded once all sequences have been merged, and the
+// temporary files are removed when the iteration ends. Errors produced by the
+// sequences, or while accessing the temporary files, are yielded inline. The
+// codec of the spill configuration is required, an error is yielded if it is
+// nil.
+//
+// See MergeUniqueByFunc for a version of this function that allows the caller
+// to pass custom comparison functions.
+func MergeUniqueBy[T cmp.Ordered, K cmp.Ordered](key func(T) K, spill Spill[T], seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return MergeUniqueByFunc(cmp.Compare[T], key, cmp.Compare[K], spill, seqs...)
+}
+
+// MergeUniqueByFunc is like MergeUniqueBy but uses the given comparison
+// functions to determine the order of values and the equality of keys.
+//
+// See MergeUniqueBy for more details.
+func MergeUniqueByFunc[T, K any](cmpValue func(T, T) int, key func(T) K, cmpKey func(K, K) int, spill Spill[T], seqs ...iter.Seq2[T, error]) iter.Seq2[T, error] {
+	byKey := func(a, b spilled[T]) int {
+		if c := cmpKey(key(a.value), key(b.value)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.pos, b.pos)
+	}
+	byPos := func(a, b spilled[T]) int {
+		return cmp.Compare(a.pos, b.pos)
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		dir, err := os.MkdirTemp(spill.Dir, "kway-unique-*")
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		merged := numbered(MergeFunc(cmpValue, seqs...))
+		sorted := externalSort(dir, &spill, byKey, merged)
+		unique := firstOfKeys(key, cmpKey, sorted)
+
+		for e, err := range externalSort(dir, &spill, byPos, unique) {
+			if !yield(e.value, err) {
+				return
+			}
+		}
+	}
+}
+
+// spilled is a value paired with its position in the sequence it was read from.
+type spilled[T any] struct {
+	pos   int64
+	value T
+}
+
+func numbered[T any](seq iter.Seq2[T, error]) iter.Seq2[spilled[T], error] {
+	return func(yield func(spilled[T], error) bool) {
+		pos := int64(0)
+		for value, err := range seq {
+			e := spilled[T]{pos: pos, value: value}
+			if err == nil {
+				pos++
+			}
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
+
+// firstOfKeys yields the first value of each run of values with equal keys.
+func firstOfKeys[T, K any](key func(T) K, cmp func(K, K) int, seq iter.Seq2[spilled[T], error]) iter.Seq2[spilled[T], error] {
+	return func(yield func(spilled[T], error) bool) {
+		var last K
+		var hasLast bool
+		for e, err := range seq {
+			if err != nil {
+				if !yield(e, err) {
+					return
+				}
+				continue
+			}
+			k := key(e.value)
+			if hasLast && cmp(last, k) == 0 {
+				continue
+			}
+			last, hasLast = k, true
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// externalSort yields the values of seq sorted by the comparison function. If
+// seq produces more values than the limit of the spill configuration, sorted
+// runs of values are written to temporary files in dir, then merged.
+//
+// Errors produced by seq are yielded when they are encountered, ahead of the
+// sorted values. If the spill configuration has no codec, the sequence only
+// yields an error, regardless of the number of values.
+func externalSort[T any](dir string, spill *Spill[T], cmp func(a, b spilled[T]) int, seq iter.Seq2[spilled[T], error]) iter.Seq2[spilled[T], error] {
+	return func(yield func(spilled[T], error) bool) {
+		var zero spilled[T]
+		if spill.Codec == nil {
+			yield(zero, errSpillCodec)
+			return
+		}
+		var runs []iter.Seq2[spilled[T], error]
+		limit := spill.limit()
+		chunk := make([]spilled[T], 0, min(limit, bufferSize))
+		size := int64(0)
+
+		for e, err := range seq {
+			if err != nil {
+				if !yield(zero, err) {
+					return
+				}
+				continue
+			}
+			n := int64(0)
+			if spill.memory() {
+				n = int64(spill.SizeOf(e.value))
+			}
+			if len(chunk) == limit || (len(chunk) > 0 && size+n > spill.Memory && spill.memory()) {
+				slices.SortFunc(chunk, cmp)
+				run, err := writeRun(dir, spill.Codec, chunk)
+				if err != nil {
+					yield(zero, err)
+					return
+				}
+				runs = append(runs, run)
+				clear(chunk)
+				chunk, size = chunk[:0], 0
+			}
+			chunk = append(chunk, e)
+			size += n
+		}
+
+		slices.SortFunc(chunk, cmp)
+		if len(runs) == 0 {
+			for _, e := range chunk {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			return
+		}
+
+		runs = append(runs, func(yield func(spilled[T], error) bool) {
+			for _, e := range chunk {
+				if !yield(e, nil) {
+					return
+				}
+			}
+		})
+		for e, err := range MergeFunc(cmp, runs...) {
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
+
+// writeRun writes the values to a temporary file in dir, and their positions
+// to a separate file, and returns a sequence reading them back.
+func writeRun[T any](dir string, codec Codec[T], run []spilled[T]) (iter.Seq2[spilled[T], error], error) {
+	values, err := os.CreateTemp(dir, "*.run")
+	if err != nil {
+		return nil, err
+	}
+	defer values.Close()
+	positions, err := os.CreateTemp(dir, "*.pos")
+	if err != nil {
+		return nil, err
+	}
+	defer positions.Close()
+
+	enc := codec.Encoder()
+	var buf, pos []byte
+	batch := make([]T, 0, bufferSize)
+
+	for chunk := range slices.Chunk(run, bufferSize) {
+		batch, pos = batch[:0], pos[:0]
+		for _, e := range chunk {
+			batch = append(batch, e.value)
+			pos = binary.AppendUvarint(pos, uint64(e.pos))
+		}
+		if buf, err = enc.Encode(buf[:0], batch); err != nil {
+			return nil, err
+		}
+		if _, err := values.Write(buf); err != nil {
+			return nil, err
+		}
+		if _, err := positions.Write(pos); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := errors.Join(values.Close(), positions.Close()); err != nil {
+		return nil, err
+	}
+	return readRun(values.Name(), positions.Name(), codec), nil
+}
+
+func readRun[T any](valuesPath, positionsPath string, codec Codec[T]) iter.Seq2[spilled[T], error] {
+	return func(yield func(spilled[T], error) bool) {
+		var zero spilled[T]
+
+		values, err := os.Open(valuesPath)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer values.Close()
+		positions, err := os.Open(positionsPath)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer positions.Close()
+
+		r := bufio.NewReader(positions)
+		for value, err := range codec.Decode(values) {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			pos, err := binary.ReadUvarint(r)
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				yield(zero, err)
+				return
+			}
+			if !yield(spilled[T]{pos: int64(pos), value: value}, nil) {
+				return
+			}
+		}
+	}
+}
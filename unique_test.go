@@ -0,0 +1,85 @@
+package kway
+
+import (
+	"bytes"
+	"cmp"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestMergeUniqueBy(t *testing.T) {
+	// Values are merged in order, and deduplicated by their last digit.
+	key := func(v int) int { return v % 10 }
+
+	for _, limit := range []int{1, 3, 100} {
+		spill := Spill[int]{
+			Codec: GobCodec[int](),
+			Dir:   t.TempDir(),
+			Limit: limit,
+		}
+
+		got, err := values(MergeUniqueBy(key, spill,
+			seqOf(3, 11, 21, 40),
+			seqOf(1, 13, 25, 30),
+			seqOf(5, 12, 50, 51, 99),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{1, 3, 5, 12, 30, 99}; !slices.Equal(got, want) {
+			t.Errorf("limit=%d: expected %v, got %v", limit, want, got)
+		}
+	}
+}
+
+func TestMergeUniqueByLarge(t *testing.T) {
+	key := func(v int) int { return v % 1000 }
+	spill := Spill[int]{
+		Codec: varintCodec(),
+		Dir:   t.TempDir(),
+		Limit: 500,
+	}
+
+	got, err := values(MergeUniqueBy(key, spill, count(10000), sequence(500, 20000, 3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := values(count(1000))
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %d unique values, got %d", len(want), len(got))
+	}
+	if entries, _ := os.ReadDir(spill.Dir); len(entries) != 0 {
+		t.Errorf("temporary files were not removed: %v", entries)
+	}
+}
+
+func TestMergeUniqueByRetainingCodec(t *testing.T) {
+	// Values decoded from the runs are held by the merge while the following
+	// records are read, which must not overwrite them.
+	spill := Spill[[]byte]{
+		Codec: retainingCodec(),
+		Dir:   t.TempDir(),
+		Limit: 2,
+	}
+	seq := seqOf([]byte("a1"), []byte("b2"), []byte("c1"), []byte("d3"), []byte("e2"))
+	key := func(v []byte) byte { return v[1] }
+
+	got, err := values(MergeUniqueByFunc(bytes.Compare, key, cmp.Compare[byte], spill, seq))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{[]byte("a1"), []byte("b2"), []byte("d3")}; !slices.EqualFunc(got, want, bytes.Equal) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestMergeUniqueByNoCodec(t *testing.T) {
+	for _, limit := range []int{1, 100} {
+		spill := Spill[int]{Dir: t.TempDir(), Limit: limit}
+		_, err := values(MergeUniqueBy(func(v int) int { return v }, spill, count(10)))
+		if err != errSpillCodec {
+			t.Errorf("limit=%d: expected %v, got %v", limit, errSpillCodec, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package kway
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+)
+
+// ErrUnordered is the error reported by a Merger configured with
+// WithUnorderedPolicy(UnorderedError) when a source produces a value ordered
+// before the previous value it produced, and by Monotonic sequences.
+var ErrUnordered = errors.New("value ordered before the previous value")
+
+// OrderError is the error reported in place of values ordered before the
+// previous value of their source, which locates the value in the source.
+// Matching the error with errors.Is(err, ErrUnordered) is true.
+type OrderError struct {
+	// Position of the value in its source, counting from zero.
+	Offset int64
+}
+
+// Error satisfies the error interface.
+func (e *OrderError) Error() string {
+	return fmt.Sprintf("value at offset %d ordered before the previous value", e.Offset)
+}
+
+// Unwrap returns ErrUnordered.
+func (e *OrderError) Unwrap() error { return ErrUnordered }
+
+// UnorderedPolicy defines how a Merger handles values produced out of order by
+// its sources, see WithUnorderedPolicy.
+type UnorderedPolicy int
+
+const (
+	// UnorderedError drops values ordered before the previous value of their
+	// source, and yields an *OrderError to the application in their place,
+	// wrapped in a *SourceError.
+	UnorderedError UnorderedPolicy = iota
+	// UnorderedDrop silently drops values ordered before the previous value
+	// of their source.
+	UnorderedDrop
+	// UnorderedSort sorts the batches of values read from each source before
+	// merging them, which repairs sources that are only locally unordered
+	// (e.g. events produced concurrently and written with small delays).
+	// Values that are still ordered before the previous batch of their source
+	// after sorting are dropped.
+	UnorderedSort
+)
+
+// WithUnorderedPolicy configures a Merger to validate that its sources produce
+// ordered values, and to handle regressions according to the given policy.
+//
+// Without this option, the Merger trusts that sources are ordered, and values
+// produced out of order are merged at unspecified positions of the output.
+func WithUnorderedPolicy(policy UnorderedPolicy) Option {
+	return func(o *options) {
+		o.validateOrder = true
+		o.unordered = policy
+	}
+}
+
+// ValidateSlices returns sequences yielding the batches of the given sequences,
+// after validating that each batch is ordered and that its values are not
+// ordered before the last value of the previous batch. This costs one
+// comparison per value, and protects merges from producers which break their
+// ordering contract, whose values would otherwise be merged at unspecified
+// positions of the output:
+//
+//	merged := kway.MergeSliceFunc(cmp, kway.ValidateSlices(cmp, seqs...)...)
+//
+// Values ordered before the previous value of their sequence are dropped, and
+// reported by an *OrderError wrapped in a *SourceError, which identify the
+// sequence by its index and the position of the value in the sequence.
+func ValidateSlices[T any](cmp func(T, T) int, seqs ...iter.Seq2[[]T, error]) []iter.Seq2[[]T, error] {
+	validated := make([]iter.Seq2[[]T, error], len(seqs))
+	for i, seq := range seqs {
+		validated[i] = orderBatches(cmp, UnorderedError, nil, seq, func(err error) error {
+			return &SourceError{Index: i, Err: err}
+		})
+	}
+	return validated
+}
+
+// orderBatches applies the unordered policy to the values of batches produced
+// by seq. Values removed from the batches are released from the memory budget
+// when it is not nil. The wrap function is applied to the errors reporting
+// values produced out of order.
+func orderBatches[T any](cmp func(T, T) int, policy UnorderedPolicy, memory *memoryBudget[T], seq iter.Seq2[[]T, error], wrap func(error) error) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		var last T
+		var hasLast bool
+		var offset int64
+
+		for values, err := range seq {
+			if policy == UnorderedSort {
+				slices.SortStableFunc(values, cmp)
+			}
+
+			// With UnorderedError, the values between regressions are yielded
+			// as subslices of the batch, which is not modified. Otherwise, the
+			// values which are retained are compacted at the front of the batch.
+			i, n := 0, 0
+			for j, v := range values {
+				if hasLast && cmp(v, last) < 0 {
+					if memory != nil {
+						memory.release(values[j : j+1])
+					}
+					if policy == UnorderedError {
+						if !yield(values[i:j], wrap(&OrderError{Offset: offset + int64(j)})) {
+							return
+						}
+						i = j + 1
+					}
+					continue
+				}
+				last, hasLast = v, true
+				if policy != UnorderedError {
+					values[n] = v
+				}
+				n++
+			}
+			if policy == UnorderedError {
+				n = len(values)
+			}
+			offset += int64(len(values))
+
+			if !yield(values[i:n], err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package kway
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestMergerUnorderedPolicy(t *testing.T) {
+	tests := []struct {
+		policy UnorderedPolicy
+		want   []int
+		errs   int
+	}{
+		{policy: UnorderedError, want: []int{1, 2, 3, 4, 5, 6}, errs: 1},
+		{policy: UnorderedDrop, want: []int{1, 2, 3, 4, 5, 6}},
+		{policy: UnorderedSort, want: []int{0, 1, 2, 3, 4, 5, 6}},
+	}
+
+	for _, test := range tests {
+		m := newMerger(t, cmp.Compare[int],
+			[]iter.Seq2[int, error]{
+				seqOf(1, 3, 0, 5),
+				seqOf(2, 4, 6),
+			},
+			WithUnorderedPolicy(test.policy),
+		)
+
+		var got []int
+		var errs int
+		for v, err := range m.All() {
+			if err != nil {
+				if !errors.Is(err, ErrUnordered) {
+					t.Fatal(err)
+				}
+				errs++
+			} else {
+				got = append(got, v)
+			}
+		}
+
+		if !slices.Equal(got, test.want) {
+			t.Errorf("policy %d: expected %v, got %v", test.policy, test.want, got)
+		}
+		if errs != test.errs {
+			t.Errorf("policy %d: expected %d errors, got %d", test.policy, test.errs, errs)
+		}
+	}
+}
+
+func TestMergerUnorderedPosition(t *testing.T) {
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{seqOf(1, 2), seqOf(1, 3, 0, 5)},
+		WithUnorderedPolicy(UnorderedError),
+	)
+	for _, err := range m.All() {
+		if err == nil {
+			continue
+		}
+		var sourceErr *SourceError
+		var orderErr *OrderError
+		if !errors.As(err, &sourceErr) || !errors.As(err, &orderErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sourceErr.Index != 1 || orderErr.Offset != 2 {
+			t.Errorf("expected source 1 at offset 2, got source %d at offset %d", sourceErr.Index, orderErr.Offset)
+		}
+	}
+}
+
+func TestValidateSlices(t *testing.T) {
+	slicesOf := func(batches ...[]int) iter.Seq2[[]int, error] {
+		return func(yield func([]int, error) bool) {
+			for _, batch := range batches {
+				if !yield(batch, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	unordered := []int{4, 3, 5}
+	seqs := ValidateSlices(cmp.Compare[int],
+		slicesOf([]int{1, 2}, []int{2, 6}),
+		slicesOf([]int{0, 3}, unordered, []int{1, 7}),
+	)
+
+	var got []int
+	var errs []error
+	for values, err := range MergeSlice(seqs...) {
+		got = append(got, values...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if want := []int{0, 1, 2, 2, 3, 4, 5, 6, 7}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !slices.Equal(unordered, []int{4, 3, 5}) {
+		t.Errorf("the batches of the sequences were modified: %v", unordered)
+	}
+
+	// One value is ordered before the previous value of its batch, the other
+	// before the last value of the previous batch.
+	offsets := []int64{3, 5}
+	if len(errs) != len(offsets) {
+		t.Fatalf("expected %d errors, got %v", len(offsets), errs)
+	}
+	for i, err := range errs {
+		var sourceErr *SourceError
+		var orderErr *OrderError
+		if !errors.As(err, &sourceErr) || !errors.As(err, &orderErr) || !errors.Is(err, ErrUnordered) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sourceErr.Index != 1 || orderErr.Offset != offsets[i] {
+			t.Errorf("expected source 1 at offset %d, got %v", offsets[i], err)
+		}
+	}
+}
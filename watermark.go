@@ -0,0 +1,30 @@
+package kway
+
+// WithWatermark configures a Merger to report watermarks to fn, which is the
+// guarantee that no values ordered before the watermark will be yielded.
+//
+// The watermark is the smallest head value across the sources which are still
+// open. Because only the Merger knows the heads of all sources, it can advance
+// the watermark ahead of the values yielded to the application, which allows
+// consumers aggregating values by event-time windows to close windows as soon
+// as possible.
+//
+// The function is called after batches of values were yielded to the
+// application, each time the watermark advances.
+func WithWatermark[T any](fn func(watermark T)) Option {
+	return func(o *options) { o.watermark = fn }
+}
+
+// advanceWatermark reports the next value that the tree will produce to the
+// watermark function if it is ordered after the last reported watermark.
+func (m *Merger[T]) advanceWatermark(t *tree[T, T]) {
+	next, ok := t.peek()
+	if !ok {
+		return
+	}
+	if m.hasWatermark && m.cmp(next, m.lastWatermark) <= 0 {
+		return
+	}
+	m.lastWatermark, m.hasWatermark = next, true
+	m.watermark(next)
+}
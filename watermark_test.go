@@ -0,0 +1,45 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+	"testing"
+)
+
+func TestMergerWatermark(t *testing.T) {
+	var watermarks []int
+	var last int
+
+	m := newMerger(t, cmp.Compare[int],
+		[]iter.Seq2[int, error]{
+			sequence(0, 1000, 2),
+			sequence(1, 1000, 2),
+		},
+		WithWatermark(func(watermark int) {
+			if watermark < last {
+				t.Errorf("watermark %d is behind the last value yielded %d", watermark, last)
+			}
+			watermarks = append(watermarks, watermark)
+		}),
+	)
+
+	for v, err := range m.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(watermarks) > 0 && v < watermarks[len(watermarks)-1] {
+			t.Errorf("value %d yielded after watermark %d", v, watermarks[len(watermarks)-1])
+		}
+		last = v
+	}
+
+	if len(watermarks) == 0 {
+		t.Fatal("no watermarks were reported")
+	}
+	for i := 1; i < len(watermarks); i++ {
+		if watermarks[i] <= watermarks[i-1] {
+			t.Errorf("watermarks did not advance: %v", watermarks)
+			break
+		}
+	}
+}
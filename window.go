@@ -0,0 +1,74 @@
+package kway
+
+import (
+	"cmp"
+	"iter"
+)
+
+// Window is a batch of merged values that belong to the same window.
+type Window[W, T any] struct {
+	Key    W
+	Values []T
+}
+
+// MergeWindows merges multiple sequences and groups the merged values into
+// windows determined by the windowOf function. Each window yielded by the
+// returned sequence contains all the values that belong to it, in order.
+//
+// The windowOf function must be monotonic with respect to the order of values:
+// if a <= b, then the window of a must not come after the window of b. Under
+// this condition, a window is closed as soon as the merge produces a value of
+// a different window, since all sequences have then moved past it.
+//
+// A typical use case is grouping event-time data into fixed-size time buckets:
+//
+//	windowOf := func(e Event) int64 { return e.Time.Unix() / 60 }
+//
+//	for w, err := range kway.MergeWindowsFunc(compareEvents, windowOf, seqs...) {
+//		...
+//	}
+//
+// The slices of values in the windows are not reused by the function, the
+// caller may retain them after the iteration moved to the next window.
+//
+// See MergeWindowsFunc for a version of this function that allows the caller
+// to pass a custom comparison function.
+func MergeWindows[T cmp.Ordered, W comparable](windowOf func(T) W, seqs ...iter.Seq2[T, error]) iter.Seq2[Window[W, T], error] {
+	return MergeWindowsFunc(cmp.Compare[T], windowOf, seqs...)
+}
+
+// MergeWindowsFunc is like MergeWindows but uses the given comparison function
+// to determine the order of values.
+//
+// See MergeWindows for more details.
+func MergeWindowsFunc[T any, W comparable](cmp func(T, T) int, windowOf func(T) W, seqs ...iter.Seq2[T, error]) iter.Seq2[Window[W, T], error] {
+	merged := MergeFunc(cmp, seqs...)
+
+	return func(yield func(Window[W, T], error) bool) {
+		var current Window[W, T]
+
+		for value, err := range merged {
+			if err != nil {
+				if !yield(Window[W, T]{}, err) {
+					return
+				}
+				continue
+			}
+
+			key := windowOf(value)
+			if len(current.Values) > 0 && key != current.Key {
+				if !yield(current, nil) {
+					return
+				}
+				current = Window[W, T]{}
+			}
+
+			current.Key = key
+			current.Values = append(current.Values, value)
+		}
+
+		if len(current.Values) > 0 {
+			yield(current, nil)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package kway
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeWindows(t *testing.T) {
+	windowOf := func(v int) int { return v / 10 }
+
+	var keys []int
+	var batches [][]int
+	for w, err := range MergeWindows(windowOf,
+		seqOf(1, 5, 12, 31),
+		seqOf(3, 17, 18, 35),
+		seqOf(11, 33),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, w.Key)
+		batches = append(batches, w.Values)
+	}
+
+	if want := []int{0, 1, 3}; !slices.Equal(keys, want) {
+		t.Errorf("expected window keys %v, got %v", want, keys)
+	}
+
+	want := [][]int{{1, 3, 5}, {11, 12, 17, 18}, {31, 33, 35}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("expected windows %v, got %v", want, batches)
+	}
+}